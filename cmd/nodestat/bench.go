@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/checker"
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/forward"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+	"gopkg.in/yaml.v2"
+)
+
+// runBench drives load against a node and prints the outcome
+func runBench(opts *globalOptions, nodeName string, params string, bench checker.BenchOptions) {
+	if bench.RPS <= 0 || bench.Duration <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --rps and --duration must be positive")
+		os.Exit(ExitConfigError)
+	}
+	if params != "" {
+		if err := json.Unmarshal([]byte(params), &bench.Params); err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing --params, expected a JSON array:", err)
+			os.Exit(ExitConfigError)
+		}
+	}
+
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading configuration:", err)
+		os.Exit(ExitConfigError)
+	}
+	node, ok := cfg.Nodes[nodeName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error selecting nodes: node %s not found in configuration\n", nodeName)
+		os.Exit(ExitConfigError)
+	}
+	// Stop the load on interrupt, the results so far are still reported
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Every request counts once, a retried request would hide the failures
+	ctx = rpc.WithOptions(ctx, rpc.Options{
+		Timeout: cfg.RPCTimeout,
+		Retry:   &config.Retry{},
+		Verbose: opts.checks.RPC.Verbose,
+		Log:     os.Stderr,
+	})
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	bench.ErrOut = os.Stderr
+	res, err := checker.Bench(ctx, forward.NewKubeClients(), nodeName, node, checker.DefaultPort, bench)
+	if res == nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(ExitCheckError)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Load stopped early: %v\n", err)
+	}
+	if err := writeBench(opts.output, nodeName, res); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing results:", err)
+		os.Exit(ExitCheckError)
+	}
+	if res.Errors > 0 {
+		os.Exit(ExitCheckError)
+	}
+}
+
+// writeBench writes the outcome of a load run to stdout in the requested format
+func writeBench(format string, nodeName string, res *checker.BenchResult) error {
+	switch format {
+	case checker.OutputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	case checker.OutputYAML:
+		data, err := yaml.Marshal(res)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Printf("---\n%s", data)
+		return err
+	default:
+		fmt.Printf("Node: %s\n", nodeName)
+		fmt.Printf("Requests: %d %s in %s, %.1f/s succeeded\n", res.Requests, res.Method, res.Duration.Round(time.Millisecond), res.Throughput)
+		fmt.Printf("Errors: %d (%.2f%%)\n", res.Errors, res.ErrorRate*100)
+		if res.FirstError != "" {
+			fmt.Printf("First error: %s\n", res.FirstError)
+		}
+		fmt.Printf("Latency: p50 %s, p90 %s, p99 %s, max %s\n", res.P50.Round(time.Microsecond),
+			res.P90.Round(time.Microsecond), res.P99.Round(time.Microsecond), res.Max.Round(time.Microsecond))
+		return nil
+	}
+}
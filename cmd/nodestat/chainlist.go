@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// runChainlistUpdate downloads the chainlist dataset at url and stores it as the refreshed one
+func runChainlistUpdate(url string) {
+	data, err := fetchChainlist(url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error downloading chainlist:", err)
+		os.Exit(ExitCheckError)
+	}
+	chains, err := config.SaveChainlist(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error saving chainlist:", err)
+		os.Exit(ExitCheckError)
+	}
+	path, _ := config.ChainlistPath()
+	fmt.Printf("Chainlist of %d chains written to %s\n", chains, path)
+}
+
+func fetchChainlist(url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rpc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
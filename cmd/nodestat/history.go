@@ -0,0 +1,187 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/checker"
+	"github.com/morzhanov/nodestat/pkg/config"
+	"gopkg.in/yaml.v2"
+	_ "modernc.org/sqlite"
+)
+
+// defaultHistoryPath is queried by the history command when --history is not set
+const defaultHistoryPath = "~/.nodestat/history.db"
+
+// historyLimit is the number of records printed by the history command
+const historyLimit = 50
+
+const historySchema = `CREATE TABLE IF NOT EXISTS results (
+	time         INTEGER NOT NULL,
+	node         TEXT    NOT NULL,
+	chain        TEXT    NOT NULL,
+	sync_status  TEXT    NOT NULL,
+	node_block   INTEGER NOT NULL,
+	latest_block INTEGER NOT NULL,
+	diff         INTEGER NOT NULL,
+	peers        INTEGER
+);
+CREATE INDEX IF NOT EXISTS results_node_time ON results (node, time);
+CREATE TABLE IF NOT EXISTS canary_runs (
+	time    INTEGER NOT NULL,
+	node    TEXT    NOT NULL,
+	canary  TEXT    NOT NULL,
+	latency INTEGER NOT NULL,
+	error   TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS canary_runs_node_canary_time ON canary_runs (node, canary, time);`
+
+// HistoryRecord represents a stored check result
+type HistoryRecord struct {
+	Time        time.Time `json:"time" yaml:"time"`
+	Node        string    `json:"node" yaml:"node"`
+	Chain       string    `json:"chain" yaml:"chain"`
+	SyncStatus  string    `json:"sync_status" yaml:"sync_status"`
+	NodeBlock   int64     `json:"node_block" yaml:"node_block"`
+	LatestBlock int64     `json:"latest_block" yaml:"latest_block"`
+	Diff        int64     `json:"diff" yaml:"diff"`
+	Peers       *int64    `json:"peers,omitempty" yaml:"peers,omitempty"`
+}
+
+// openHistory opens the SQLite history database at path, creating it if needed
+func openHistory(path string) (*sql.DB, error) {
+	path = config.ExpandHome(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// Canaries write concurrently with the checks, SQLite allows a single writer
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// recordHistory appends the results of a run to the history database
+func recordHistory(db *sql.DB, results map[string]checker.Result) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for nodeName, res := range results {
+		_, err := tx.Exec(`INSERT INTO results (time, node, chain, sync_status, node_block, latest_block, diff, peers)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			now, nodeName, res.Chain, res.SyncStatus, res.NodeBlockNum, res.LatestBlockNum, res.Diff, res.PeersCount)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// queryHistory returns the most recent records of a node, newest first
+func queryHistory(db *sql.DB, nodeName string, limit int) ([]HistoryRecord, error) {
+	rows, err := db.Query(`SELECT time, node, chain, sync_status, node_block, latest_block, diff, peers
+		FROM results WHERE node = ? ORDER BY time DESC LIMIT ?`, nodeName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []HistoryRecord{}
+	for rows.Next() {
+		var record HistoryRecord
+		var unix int64
+		var peers sql.NullInt64
+		if err := rows.Scan(&unix, &record.Node, &record.Chain, &record.SyncStatus,
+			&record.NodeBlock, &record.LatestBlock, &record.Diff, &peers); err != nil {
+			return nil, err
+		}
+		record.Time = time.Unix(unix, 0)
+		if peers.Valid {
+			record.Peers = &peers.Int64
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// historyCanaryStore persists the canary runs in the history database
+type historyCanaryStore struct {
+	db *sql.DB
+}
+
+func (s historyCanaryStore) AddCanaryRecord(nodeName string, canary string, record checker.CanaryRecord) error {
+	_, err := s.db.Exec(`INSERT INTO canary_runs (time, node, canary, latency, error) VALUES (?, ?, ?, ?, ?)`,
+		record.At.UnixMilli(), nodeName, canary, int64(record.Latency), record.Err)
+	return err
+}
+
+func (s historyCanaryStore) CanaryRecords(nodeName string, canary string, limit int) ([]checker.CanaryRecord, error) {
+	rows, err := s.db.Query(`SELECT time, latency, error FROM canary_runs
+		WHERE node = ? AND canary = ? ORDER BY time DESC LIMIT ?`, nodeName, canary, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []checker.CanaryRecord
+	for rows.Next() {
+		var record checker.CanaryRecord
+		var millis, latency int64
+		if err := rows.Scan(&millis, &latency, &record.Err); err != nil {
+			return nil, err
+		}
+		record.At = time.UnixMilli(millis)
+		record.Latency = time.Duration(latency)
+		records = append(records, record)
+	}
+	slices.Reverse(records)
+	return records, rows.Err()
+}
+
+// writeHistory writes history records to stdout in the requested format
+func writeHistory(format string, records []HistoryRecord) error {
+	switch format {
+	case checker.OutputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case checker.OutputYAML:
+		data, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Printf("---\n%s", data)
+		return err
+	default:
+		printHistory(records)
+		return nil
+	}
+}
+
+// printHistory prints history records as a table
+func printHistory(records []HistoryRecord) {
+	fmt.Printf("%-20s %-8s %12s %12s %8s %6s\n", "TIME", "STATUS", "NODE BLOCK", "REFERENCE", "DIFF", "PEERS")
+	for _, record := range records {
+		peers := "-"
+		if record.Peers != nil {
+			peers = fmt.Sprint(*record.Peers)
+		}
+		fmt.Printf("%-20s %-8s %12d %12d %8d %6s\n", record.Time.Format("2006-01-02 15:04:05"),
+			record.SyncStatus, record.NodeBlock, record.LatestBlock, record.Diff, peers)
+	}
+}
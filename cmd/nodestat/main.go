@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/api"
+	"github.com/morzhanov/nodestat/pkg/checker"
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/forward"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes
+const (
+	// ExitSynced means every node was checked and is synced
+	ExitSynced = 0
+	// ExitBehind means some nodes are not synced (behind, syncing, healing, forked, unknown)
+	ExitBehind = 1
+	// ExitCheckError means some checks failed on RPC, port-forward or Kubernetes errors
+	ExitCheckError = 2
+	// ExitConfigError means invalid usage or configuration
+	ExitConfigError = 3
+	// ExitAssertionFailed means every node was checked but some failed their assertions
+	ExitAssertionFailed = 4
+	// ExitProblems means every node is synced but some report problems, e.g. a fork they aren't ready for
+	ExitProblems = 5
+)
+
+// exitCode returns the exit code describing the outcome of a run
+func exitCode(nodes map[string]config.Node, results map[string]checker.Result) int {
+	if len(results) < len(nodes) {
+		return ExitCheckError
+	}
+	if summary := checker.SummarizeAssertions(results); summary != nil && summary.Verdict == checker.VerdictFail {
+		return ExitAssertionFailed
+	}
+	code := ExitSynced
+	for _, res := range results {
+		if res.SyncStatus != "synced" {
+			return ExitBehind
+		}
+		if len(res.Problems()) > 0 {
+			code = ExitProblems
+		}
+	}
+	return code
+}
+
+// globalOptions are the flags shared by every command
+type globalOptions struct {
+	configPath  string
+	output      string
+	timeout     time.Duration
+	historyPath string
+	reportPath  string
+	// checks are the options of the checker, except those read from the configuration
+	checks checker.Options
+}
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(ExitConfigError)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	opts := &globalOptions{checks: checker.Options{Nagios: checker.DefaultNagiosThresholds}}
+	root := &cobra.Command{
+		Use:   "nodestat",
+		Short: "Check the sync status and health of blockchain nodes",
+		// nodestat <node> is kept as a shorthand of nodestat check <node>
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if !checker.ValidOutput(opts.output) {
+				return fmt.Errorf("invalid output format %q, expected text, table, csv, nagios, markdown, influx, json or yaml", opts.output)
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runCheck(opts, args)
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.configPath, "config", "", "path to the config file (default $NODESTAT_CONFIG, ./nodestat.yaml, ~/.config/nodestat/config.yaml, ~/bin/nodes_conf.yaml)")
+	flags.StringVarP(&opts.output, "output", "o", checker.OutputText, "output format: text, table, csv, nagios, markdown, influx, json or yaml")
+	flags.BoolVarP(&opts.checks.Quiet, "quiet", "q", false, "print only the nodes that are not synced or failed their checks")
+	flags.CountVarP(&opts.checks.RPC.Verbose, "verbose", "v", "log every JSON-RPC call with its duration to stderr, -vv also dumps the request and response bodies")
+	flags.BoolVar(&opts.checks.PeerDetails, "peers", false, "list every connected peer of the nodes exposing admin_peers")
+	flags.BoolVar(&opts.checks.NoColor, "no-color", false, "disable the colors of the table output")
+	flags.Int64Var(&opts.checks.Nagios.WarningDiff, "warning-diff", opts.checks.Nagios.WarningDiff, "nagios output: warning when a node is more blocks behind")
+	flags.Int64Var(&opts.checks.Nagios.CriticalDiff, "critical-diff", opts.checks.Nagios.CriticalDiff, "nagios output: critical when a node is more blocks behind")
+	flags.Int64Var(&opts.checks.Nagios.WarningPeers, "warning-peers", opts.checks.Nagios.WarningPeers, "nagios output: warning when a node has fewer peers")
+	flags.Int64Var(&opts.checks.Nagios.CriticalPeers, "critical-peers", opts.checks.Nagios.CriticalPeers, "nagios output: critical when a node has fewer peers")
+	flags.DurationVar(&opts.timeout, "timeout", 0, "deadline of a check run, 0 disables it")
+	flags.StringVar(&opts.historyPath, "history", "", "SQLite history database, e.g. ~/.nodestat/history.db")
+	flags.StringVar(&opts.reportPath, "report", "", "also render the results into a self-contained HTML page, e.g. out.html")
+
+	root.AddCommand(newCheckCmd(opts), newServeCmd(opts), newHistoryCmd(opts), newConfigCmd(opts), newChainlistCmd(), newBenchCmd(opts))
+	return root
+}
+
+func newCheckCmd(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check [node|chain...]",
+		Short: "Check the given nodes or chains once, all nodes by default",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCheck(opts, args)
+		},
+	}
+}
+
+func newServeCmd(opts *globalOptions) *cobra.Command {
+	var interval, breakerProbe time.Duration
+	var breakerFailures int
+	var listen, grpcListen string
+	cmd := &cobra.Command{
+		Use:   "serve [node|chain...]",
+		Short: "Check the given nodes or chains repeatedly every interval",
+		Run: func(cmd *cobra.Command, args []string) {
+			var breaker *checker.Breaker
+			if breakerFailures > 0 {
+				breaker = checker.NewBreaker(breakerFailures, breakerProbe)
+				breaker.OnChange = func(nodeName string, down bool) {
+					if down {
+						fmt.Fprintf(os.Stderr, "Node %s failed its checks %d times in a row, marked down and probed every %s\n", nodeName, breakerFailures, breakerProbe)
+					} else {
+						fmt.Fprintf(os.Stderr, "Node %s passed its checks again, marked up\n", nodeName)
+					}
+				}
+			}
+			runServe(opts, interval, breaker, listen, grpcListen, args)
+		},
+	}
+	cmd.Flags().DurationVar(&interval, "interval", time.Minute, "interval between checks")
+	cmd.Flags().IntVar(&breakerFailures, "breaker-failures", 3, "mark a node down after failing its checks that many runs in a row, 0 disables the circuit breaker")
+	cmd.Flags().DurationVar(&breakerProbe, "breaker-probe", 10*time.Minute, "interval between the checks of a node marked down")
+	cmd.Flags().StringVar(&listen, "listen", "", "address of the REST API serving the latest results, e.g. :9280")
+	cmd.Flags().StringVar(&grpcListen, "grpc-listen", "", "address of the gRPC API serving the results, e.g. :9281")
+	return cmd
+}
+
+func newHistoryCmd(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "history <node>",
+		Short: "Print the recent results of a node recorded with --history",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runHistory(opts.historyPath, opts.output, args[0])
+		},
+	}
+}
+
+func newBenchCmd(opts *globalOptions) *cobra.Command {
+	bench := checker.BenchOptions{}
+	var params string
+	cmd := &cobra.Command{
+		Use:   "bench <node>",
+		Short: "Drive load against a node through its port forward and report throughput, errors and latency percentiles",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runBench(opts, args[0], params, bench)
+		},
+	}
+	cmd.Flags().IntVar(&bench.RPS, "rps", 50, "requests started per second")
+	cmd.Flags().DurationVar(&bench.Duration, "duration", 30*time.Second, "duration of the load")
+	cmd.Flags().StringVar(&bench.Method, "method", "eth_blockNumber", "JSON-RPC method called")
+	cmd.Flags().StringVar(&params, "params", "", `params of the method as a JSON array, e.g. '["latest", false]'`)
+	return cmd
+}
+
+func newConfigCmd(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration file",
+	}
+	var force bool
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a starter configuration to --config, $NODESTAT_CONFIG or ~/.config/nodestat/config.yaml",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := config.Init(opts.configPath, force)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing configuration:", err)
+				os.Exit(ExitConfigError)
+			}
+			fmt.Println("Configuration written to", path)
+		},
+	}
+	initCmd.Flags().BoolVar(&force, "force", false, "overwrite an existing configuration")
+	cmd.AddCommand(initCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration for unknown fields, missing settings and inconsistencies",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path, problems, err := config.Validate(opts.configPath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error reading configuration:", err)
+				os.Exit(ExitConfigError)
+			}
+			for _, problem := range problems {
+				if problem.Line > 0 {
+					fmt.Printf("%s:%d: %s\n", path, problem.Line, problem.Message)
+				} else {
+					fmt.Printf("%s: %s\n", path, problem.Message)
+				}
+			}
+			if len(problems) > 0 {
+				os.Exit(ExitConfigError)
+			}
+			fmt.Println("Configuration is valid")
+		},
+	})
+	return cmd
+}
+
+func newChainlistCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chainlist",
+		Short: "Manage the chainlist dataset resolving public_apis chain_id to public RPC endpoints",
+	}
+	var url string
+	updateCmd := &cobra.Command{
+		Use:   "update",
+		Short: "Download the latest chainlist dataset, used instead of the embedded one",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runChainlistUpdate(url)
+		},
+	}
+	updateCmd.Flags().StringVar(&url, "url", config.ChainlistURL, "URL of the chainlist dataset")
+	cmd.AddCommand(updateCmd)
+	return cmd
+}
+
+// checkRun holds what every run of checks needs
+type checkRun struct {
+	config  config.NodeConfig
+	nodes   map[string]config.Node
+	checker *checker.Checker
+	history *sql.DB
+	// flushTraces exports the remaining spans before exiting
+	flushTraces func()
+}
+
+// exit flushes the traces of the run and exits with code
+func (r *checkRun) exit(code int) {
+	r.flushTraces()
+	os.Exit(code)
+}
+
+// setupRun loads the configuration, selects the nodes and installs the interrupt handler, exiting on errors
+func setupRun(opts *globalOptions, args []string) *checkRun {
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading configuration:", err)
+		os.Exit(ExitConfigError)
+	}
+
+	nodes, err := selectNodes(cfg, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error selecting nodes:", err)
+		os.Exit(ExitConfigError)
+	}
+
+	flushTraces, err := setupTracing()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error setting up tracing:", err)
+		os.Exit(ExitConfigError)
+	}
+
+	run := &checkRun{config: cfg, nodes: nodes, checker: checker.New(cfg, nodes), flushTraces: flushTraces}
+	run.checker.Options = checkOptions(opts, run.checker.Options.RPC)
+
+	// Remove port forwards created by this process on interrupt
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		fmt.Fprintf(os.Stderr, "Received %s, removing port forwards\n", sig)
+		forward.CloseAllForwards()
+		run.exit(ExitCheckError)
+	}()
+
+	run.checker.OnError = func(nodeName string, err error) {
+		fmt.Fprintf(os.Stderr, "Error checking %s: %v\n", nodeName, err)
+	}
+	// Text results are printed as soon as each node is done, the summary follows the last one
+	if opts.output == checker.OutputText {
+		run.checker.OnResult = func(nodeName string, res checker.Result) {
+			run.checker.PrintResult(os.Stdout, nodeName, res)
+		}
+	}
+	if opts.historyPath != "" {
+		run.history, err = openHistory(opts.historyPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error opening history database:", err)
+			os.Exit(ExitConfigError)
+		}
+		run.checker.Canaries = historyCanaryStore{db: run.history}
+	}
+	return run
+}
+
+// checkOptions returns the checker options of the flags, with the call timeout and retries of rpc
+// read from the configuration
+func checkOptions(opts *globalOptions, rpcOpts rpc.Options) checker.Options {
+	checks := opts.checks
+	checks.RPC.Timeout, checks.RPC.Retry = rpcOpts.Timeout, rpcOpts.Retry
+	checks.RPC.Log = os.Stderr
+	return checks
+}
+
+// selectNodes returns the nodes named by args, a chain name selects every node of the chain.
+// No args select all nodes.
+func selectNodes(cfg config.NodeConfig, args []string) (map[string]config.Node, error) {
+	if len(args) == 0 {
+		return cfg.Nodes, nil
+	}
+
+	nodes := make(map[string]config.Node)
+	for _, name := range args {
+		if node, ok := cfg.Nodes[name]; ok {
+			nodes[name] = node
+			continue
+		}
+		found := false
+		for nodeName, node := range cfg.Nodes {
+			if node.ChainName(nodeName) == name {
+				nodes[nodeName] = node
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("node %s not found in configuration", name)
+		}
+	}
+	return nodes, nil
+}
+
+// runCheck checks the nodes once and exits with the outcome
+func runCheck(opts *globalOptions, args []string) {
+	run := setupRun(opts, args)
+
+	// Abort the run when it exceeds the deadline
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	results, err := run.checker.Run(ctx)
+	if err != nil {
+		// The checks still running remove their port forwards in the background, the process exits first
+		forward.CloseAllForwards()
+		fmt.Fprintf(os.Stderr, "Checks did not finish within %s, port forwards removed\n", opts.timeout)
+		if opts.output == checker.OutputNagios {
+			fmt.Printf("NODESTAT UNKNOWN - checks did not finish within %s\n", opts.timeout)
+			run.exit(checker.NagiosUnknown)
+		}
+		run.exit(ExitCheckError)
+	}
+	if err := run.checker.WriteSummary(os.Stdout, opts.output, results, checker.AggregateFleet(run.nodes, results, run.config.MaxGroupDivergence)); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing results:", err)
+		run.exit(ExitCheckError)
+	}
+	notifyWebhook(ctx, run.config, run.nodes, results)
+	writeInflux(ctx, run.config, run.nodes, results)
+	saveHistory(run.history, results)
+	writeHTMLReport(opts.reportPath, run, results)
+	// Nagios plugins report their state through the exit code
+	if opts.output == checker.OutputNagios {
+		state, _ := run.checker.NagiosState(results)
+		run.exit(state)
+	}
+	run.exit(exitCode(run.nodes, results))
+}
+
+// runServe checks the nodes repeatedly, every iteration lasts at least one interval.
+// Nodes marked down by breaker, if set, are only checked when due for a probe.
+// With listen or grpcListen set, the results are also served over the REST or gRPC API.
+func runServe(opts *globalOptions, interval time.Duration, breaker *checker.Breaker, listen string, grpcListen string, args []string) {
+	run := setupRun(opts, args)
+	run.checker.Hold = interval
+	run.checker.Breaker = breaker
+
+	var server *api.Server
+	if listen != "" || grpcListen != "" {
+		server = api.NewServer(run.checker)
+		// Results are published to gRPC streams as soon as each node is done
+		if onResult := run.checker.OnResult; onResult != nil {
+			run.checker.OnResult = func(nodeName string, res checker.Result) {
+				onResult(nodeName, res)
+				server.Publish(nodeName, res)
+			}
+		} else {
+			run.checker.OnResult = server.Publish
+		}
+	}
+	if listen != "" {
+		go func() {
+			if err := server.ListenAndServe(context.Background(), listen); err != nil {
+				fmt.Fprintln(os.Stderr, "Error serving API:", err)
+				os.Exit(ExitConfigError)
+			}
+		}()
+	}
+	if grpcListen != "" {
+		go func() {
+			if err := server.ServeGRPC(context.Background(), grpcListen); err != nil {
+				fmt.Fprintln(os.Stderr, "Error serving gRPC API:", err)
+				os.Exit(ExitConfigError)
+			}
+		}()
+	}
+
+	// Canaries run on their own schedule, their SLIs are reported with the results of every iteration
+	go run.checker.RunCanaries(context.Background())
+
+	for {
+		start := time.Now()
+		// Every iteration is bounded like a one-shot run, a hung node or webhook must not stall the daemon
+		ctx, cancel := context.Background(), func() {}
+		if opts.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		}
+		results, err := run.checker.Run(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Checks did not finish within %s\n", opts.timeout)
+		}
+		if server != nil {
+			server.Update(results)
+		}
+		if err := run.checker.WriteSummary(os.Stdout, opts.output, results, checker.AggregateFleet(run.nodes, results, run.config.MaxGroupDivergence)); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing results:", err)
+		}
+		notifyWebhook(ctx, run.config, run.nodes, results)
+		writeInflux(ctx, run.config, run.nodes, results)
+		cancel()
+		saveHistory(run.history, results)
+		writeHTMLReport(opts.reportPath, run, results)
+		time.Sleep(time.Until(start.Add(interval)))
+	}
+}
+
+// saveHistory appends the results to the history database if one is open
+func saveHistory(history *sql.DB, results map[string]checker.Result) {
+	if history == nil {
+		return
+	}
+	if err := recordHistory(history, results); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing history:", err)
+	}
+}
+
+// writeInflux writes the results to the configured InfluxDB
+func writeInflux(ctx context.Context, cfg config.NodeConfig, nodes map[string]config.Node, results map[string]checker.Result) {
+	if cfg.InfluxDB == nil {
+		return
+	}
+	if err := checker.WriteInflux(ctx, *cfg.InfluxDB, nodes, results); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing to InfluxDB:", err)
+	}
+}
+
+// writeHTMLReport renders the results into the HTML report if one is requested,
+// with the recent diffs of every node when the history database is open
+func writeHTMLReport(path string, run *checkRun, results map[string]checker.Result) {
+	if path == "" {
+		return
+	}
+
+	var history map[string][]checker.DiffSample
+	if run.history != nil {
+		history = make(map[string][]checker.DiffSample, len(run.nodes))
+		for nodeName := range run.nodes {
+			records, err := queryHistory(run.history, nodeName, historyLimit)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error reading history:", err)
+				continue
+			}
+			// Records are newest first, the sparkline is drawn oldest first
+			for i := len(records) - 1; i >= 0; i-- {
+				history[nodeName] = append(history[nodeName], checker.DiffSample{Time: records[i].Time, Diff: records[i].Diff})
+			}
+		}
+	}
+
+	if err := checker.WriteHTMLReport(path, run.nodes, results, history); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing HTML report:", err)
+	}
+}
+
+// runHistory prints the recent records of a node
+func runHistory(historyPath string, output string, nodeName string) {
+	if historyPath == "" {
+		historyPath = defaultHistoryPath
+	}
+
+	history, err := openHistory(historyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening history database:", err)
+		os.Exit(ExitConfigError)
+	}
+	defer history.Close()
+
+	records, err := queryHistory(history, nodeName, historyLimit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading history:", err)
+		os.Exit(ExitCheckError)
+	}
+	if err := writeHistory(output, records); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing history:", err)
+		os.Exit(ExitCheckError)
+	}
+}
+
+// notifyWebhook reports status changes to the configured webhook
+func notifyWebhook(ctx context.Context, cfg config.NodeConfig, nodes map[string]config.Node, results map[string]checker.Result) {
+	if cfg.Webhook == "" {
+		return
+	}
+	if err := checker.NotifyStatusChanges(ctx, cfg.Webhook, nodes, results); err != nil {
+		fmt.Fprintln(os.Stderr, "Error calling webhook:", err)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"slices"
+	"sort"
+
+	"github.com/morzhanov/nodestat/pkg/api/nodestatpb"
+	"github.com/morzhanov/nodestat/pkg/checker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcService implements the NodeStat gRPC service defined in proto/nodestat/v1/nodestat.proto
+type grpcService struct {
+	nodestatpb.UnimplementedNodeStatServer
+	server *Server
+}
+
+// ServeGRPC serves the NodeStat gRPC service on addr until ctx is done
+func (s *Server) ServeGRPC(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer()
+	nodestatpb.RegisterNodeStatServer(srv, &grpcService{server: s})
+	go func() {
+		<-ctx.Done()
+		srv.Stop()
+	}()
+	return srv.Serve(lis)
+}
+
+func (g *grpcService) ListResults(ctx context.Context, req *nodestatpb.ListResultsRequest) (*nodestatpb.ListResultsResponse, error) {
+	results := g.server.latest()
+	nodeNames := make([]string, 0, len(results))
+	for nodeName := range results {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	resp := &nodestatpb.ListResultsResponse{}
+	for _, nodeName := range nodeNames {
+		res, err := toProto(nodeName, results[nodeName])
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Results = append(resp.Results, res)
+	}
+	return resp, nil
+}
+
+func (g *grpcService) Check(ctx context.Context, req *nodestatpb.CheckRequest) (*nodestatpb.NodeResult, error) {
+	res, err := g.server.check(ctx, req.GetNode())
+	switch err {
+	case nil:
+	case errNodeNotFound:
+		return nil, status.Errorf(codes.NotFound, "node %s: %v", req.GetNode(), err)
+	default:
+		return nil, status.Errorf(codes.Unavailable, "node %s: %v", req.GetNode(), err)
+	}
+	nodeResult, err := toProto(req.GetNode(), res)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return nodeResult, nil
+}
+
+func (g *grpcService) StreamResults(req *nodestatpb.StreamResultsRequest, stream grpc.ServerStreamingServer[nodestatpb.NodeResult]) error {
+	for _, nodeName := range req.GetNodes() {
+		if _, ok := g.server.node(nodeName); !ok {
+			return status.Errorf(codes.NotFound, "node %s: %v", nodeName, errNodeNotFound)
+		}
+	}
+
+	results, unsubscribe := g.server.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case nodeResult := <-results:
+			if len(req.GetNodes()) > 0 && !slices.Contains(req.GetNodes(), nodeResult.Name) {
+				continue
+			}
+			res, err := toProto(nodeResult.Name, nodeResult.Result)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// toProto converts a result, the optional checks are carried in details as in the JSON output
+func toProto(nodeName string, res checker.Result) (*nodestatpb.NodeResult, error) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	details := &structpb.Struct{}
+	if err := details.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+
+	return &nodestatpb.NodeResult{
+		Node:           nodeName,
+		Chain:          res.Chain,
+		Cluster:        res.Cluster,
+		SyncStatus:     res.SyncStatus,
+		NodeBlockNum:   res.NodeBlockNum,
+		LatestBlockNum: res.LatestBlockNum,
+		Diff:           res.Diff,
+		PeersCount:     res.PeersCount,
+		Details:        details,
+	}, nil
+}
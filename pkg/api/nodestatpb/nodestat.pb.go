@@ -0,0 +1,398 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: nodestat/v1/nodestat.proto
+
+package nodestatpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListResultsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsRequest) Reset() {
+	*x = ListResultsRequest{}
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsRequest) ProtoMessage() {}
+
+func (x *ListResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsRequest.ProtoReflect.Descriptor instead.
+func (*ListResultsRequest) Descriptor() ([]byte, []int) {
+	return file_nodestat_v1_nodestat_proto_rawDescGZIP(), []int{0}
+}
+
+type ListResultsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*NodeResult          `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsResponse) Reset() {
+	*x = ListResultsResponse{}
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsResponse) ProtoMessage() {}
+
+func (x *ListResultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsResponse.ProtoReflect.Descriptor instead.
+func (*ListResultsResponse) Descriptor() ([]byte, []int) {
+	return file_nodestat_v1_nodestat_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListResultsResponse) GetResults() []*NodeResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type CheckRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Node is the name of the node in the configuration.
+	Node          string `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_nodestat_v1_nodestat_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CheckRequest) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+type StreamResultsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Nodes limits the stream to these nodes, all nodes if empty.
+	Nodes         []string `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamResultsRequest) Reset() {
+	*x = StreamResultsRequest{}
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamResultsRequest) ProtoMessage() {}
+
+func (x *StreamResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamResultsRequest.ProtoReflect.Descriptor instead.
+func (*StreamResultsRequest) Descriptor() ([]byte, []int) {
+	return file_nodestat_v1_nodestat_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamResultsRequest) GetNodes() []string {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+// NodeResult is the result of the checks of a node.
+type NodeResult struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Node    string                 `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Chain   string                 `protobuf:"bytes,2,opt,name=chain,proto3" json:"chain,omitempty"`
+	Cluster string                 `protobuf:"bytes,3,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	// SyncStatus is synced, syncing, behind, healing, forked or unknown.
+	SyncStatus     string `protobuf:"bytes,4,opt,name=sync_status,json=syncStatus,proto3" json:"sync_status,omitempty"`
+	NodeBlockNum   int64  `protobuf:"varint,5,opt,name=node_block_num,json=nodeBlockNum,proto3" json:"node_block_num,omitempty"`
+	LatestBlockNum int64  `protobuf:"varint,6,opt,name=latest_block_num,json=latestBlockNum,proto3" json:"latest_block_num,omitempty"`
+	Diff           int64  `protobuf:"varint,7,opt,name=diff,proto3" json:"diff,omitempty"`
+	// PeersCount is unset for chains without a peer count.
+	PeersCount *int64 `protobuf:"varint,8,opt,name=peers_count,json=peersCount,proto3,oneof" json:"peers_count,omitempty"`
+	// Details is the full result as in the JSON output, including the optional checks.
+	Details       *structpb.Struct `protobuf:"bytes,9,opt,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeResult) Reset() {
+	*x = NodeResult{}
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeResult) ProtoMessage() {}
+
+func (x *NodeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_nodestat_v1_nodestat_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeResult.ProtoReflect.Descriptor instead.
+func (*NodeResult) Descriptor() ([]byte, []int) {
+	return file_nodestat_v1_nodestat_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *NodeResult) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *NodeResult) GetChain() string {
+	if x != nil {
+		return x.Chain
+	}
+	return ""
+}
+
+func (x *NodeResult) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *NodeResult) GetSyncStatus() string {
+	if x != nil {
+		return x.SyncStatus
+	}
+	return ""
+}
+
+func (x *NodeResult) GetNodeBlockNum() int64 {
+	if x != nil {
+		return x.NodeBlockNum
+	}
+	return 0
+}
+
+func (x *NodeResult) GetLatestBlockNum() int64 {
+	if x != nil {
+		return x.LatestBlockNum
+	}
+	return 0
+}
+
+func (x *NodeResult) GetDiff() int64 {
+	if x != nil {
+		return x.Diff
+	}
+	return 0
+}
+
+func (x *NodeResult) GetPeersCount() int64 {
+	if x != nil && x.PeersCount != nil {
+		return *x.PeersCount
+	}
+	return 0
+}
+
+func (x *NodeResult) GetDetails() *structpb.Struct {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+var File_nodestat_v1_nodestat_proto protoreflect.FileDescriptor
+
+const file_nodestat_v1_nodestat_proto_rawDesc = "" +
+	"\n" +
+	"\x1anodestat/v1/nodestat.proto\x12\vnodestat.v1\x1a\x1cgoogle/protobuf/struct.proto\"\x14\n" +
+	"\x12ListResultsRequest\"H\n" +
+	"\x13ListResultsResponse\x121\n" +
+	"\aresults\x18\x01 \x03(\v2\x17.nodestat.v1.NodeResultR\aresults\"\"\n" +
+	"\fCheckRequest\x12\x12\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\",\n" +
+	"\x14StreamResultsRequest\x12\x14\n" +
+	"\x05nodes\x18\x01 \x03(\tR\x05nodes\"\xbe\x02\n" +
+	"\n" +
+	"NodeResult\x12\x12\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\x12\x14\n" +
+	"\x05chain\x18\x02 \x01(\tR\x05chain\x12\x18\n" +
+	"\acluster\x18\x03 \x01(\tR\acluster\x12\x1f\n" +
+	"\vsync_status\x18\x04 \x01(\tR\n" +
+	"syncStatus\x12$\n" +
+	"\x0enode_block_num\x18\x05 \x01(\x03R\fnodeBlockNum\x12(\n" +
+	"\x10latest_block_num\x18\x06 \x01(\x03R\x0elatestBlockNum\x12\x12\n" +
+	"\x04diff\x18\a \x01(\x03R\x04diff\x12$\n" +
+	"\vpeers_count\x18\b \x01(\x03H\x00R\n" +
+	"peersCount\x88\x01\x01\x121\n" +
+	"\adetails\x18\t \x01(\v2\x17.google.protobuf.StructR\adetailsB\x0e\n" +
+	"\f_peers_count2\xe8\x01\n" +
+	"\bNodeStat\x12P\n" +
+	"\vListResults\x12\x1f.nodestat.v1.ListResultsRequest\x1a .nodestat.v1.ListResultsResponse\x12;\n" +
+	"\x05Check\x12\x19.nodestat.v1.CheckRequest\x1a\x17.nodestat.v1.NodeResult\x12M\n" +
+	"\rStreamResults\x12!.nodestat.v1.StreamResultsRequest\x1a\x17.nodestat.v1.NodeResult0\x01B=Z;github.com/morzhanov/nodestat/pkg/api/nodestatpb;nodestatpbb\x06proto3"
+
+var (
+	file_nodestat_v1_nodestat_proto_rawDescOnce sync.Once
+	file_nodestat_v1_nodestat_proto_rawDescData []byte
+)
+
+func file_nodestat_v1_nodestat_proto_rawDescGZIP() []byte {
+	file_nodestat_v1_nodestat_proto_rawDescOnce.Do(func() {
+		file_nodestat_v1_nodestat_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_nodestat_v1_nodestat_proto_rawDesc), len(file_nodestat_v1_nodestat_proto_rawDesc)))
+	})
+	return file_nodestat_v1_nodestat_proto_rawDescData
+}
+
+var file_nodestat_v1_nodestat_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_nodestat_v1_nodestat_proto_goTypes = []any{
+	(*ListResultsRequest)(nil),   // 0: nodestat.v1.ListResultsRequest
+	(*ListResultsResponse)(nil),  // 1: nodestat.v1.ListResultsResponse
+	(*CheckRequest)(nil),         // 2: nodestat.v1.CheckRequest
+	(*StreamResultsRequest)(nil), // 3: nodestat.v1.StreamResultsRequest
+	(*NodeResult)(nil),           // 4: nodestat.v1.NodeResult
+	(*structpb.Struct)(nil),      // 5: google.protobuf.Struct
+}
+var file_nodestat_v1_nodestat_proto_depIdxs = []int32{
+	4, // 0: nodestat.v1.ListResultsResponse.results:type_name -> nodestat.v1.NodeResult
+	5, // 1: nodestat.v1.NodeResult.details:type_name -> google.protobuf.Struct
+	0, // 2: nodestat.v1.NodeStat.ListResults:input_type -> nodestat.v1.ListResultsRequest
+	2, // 3: nodestat.v1.NodeStat.Check:input_type -> nodestat.v1.CheckRequest
+	3, // 4: nodestat.v1.NodeStat.StreamResults:input_type -> nodestat.v1.StreamResultsRequest
+	1, // 5: nodestat.v1.NodeStat.ListResults:output_type -> nodestat.v1.ListResultsResponse
+	4, // 6: nodestat.v1.NodeStat.Check:output_type -> nodestat.v1.NodeResult
+	4, // 7: nodestat.v1.NodeStat.StreamResults:output_type -> nodestat.v1.NodeResult
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_nodestat_v1_nodestat_proto_init() }
+func file_nodestat_v1_nodestat_proto_init() {
+	if File_nodestat_v1_nodestat_proto != nil {
+		return
+	}
+	file_nodestat_v1_nodestat_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nodestat_v1_nodestat_proto_rawDesc), len(file_nodestat_v1_nodestat_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_nodestat_v1_nodestat_proto_goTypes,
+		DependencyIndexes: file_nodestat_v1_nodestat_proto_depIdxs,
+		MessageInfos:      file_nodestat_v1_nodestat_proto_msgTypes,
+	}.Build()
+	File_nodestat_v1_nodestat_proto = out.File
+	file_nodestat_v1_nodestat_proto_goTypes = nil
+	file_nodestat_v1_nodestat_proto_depIdxs = nil
+}
@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: nodestat/v1/nodestat.proto
+
+package nodestatpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	NodeStat_ListResults_FullMethodName   = "/nodestat.v1.NodeStat/ListResults"
+	NodeStat_Check_FullMethodName         = "/nodestat.v1.NodeStat/Check"
+	NodeStat_StreamResults_FullMethodName = "/nodestat.v1.NodeStat/StreamResults"
+)
+
+// NodeStatClient is the client API for NodeStat service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// NodeStat serves the results of the nodes checked by nodestat serve.
+type NodeStatClient interface {
+	// ListResults returns the latest result of every node.
+	ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error)
+	// Check checks a node now and returns its result.
+	Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*NodeResult, error)
+	// StreamResults sends the result of every node as soon as its checks finish.
+	StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NodeResult], error)
+}
+
+type nodeStatClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNodeStatClient(cc grpc.ClientConnInterface) NodeStatClient {
+	return &nodeStatClient{cc}
+}
+
+func (c *nodeStatClient) ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResultsResponse)
+	err := c.cc.Invoke(ctx, NodeStat_ListResults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeStatClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*NodeResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeResult)
+	err := c.cc.Invoke(ctx, NodeStat_Check_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeStatClient) StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NodeResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NodeStat_ServiceDesc.Streams[0], NodeStat_StreamResults_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamResultsRequest, NodeResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NodeStat_StreamResultsClient = grpc.ServerStreamingClient[NodeResult]
+
+// NodeStatServer is the server API for NodeStat service.
+// All implementations must embed UnimplementedNodeStatServer
+// for forward compatibility.
+//
+// NodeStat serves the results of the nodes checked by nodestat serve.
+type NodeStatServer interface {
+	// ListResults returns the latest result of every node.
+	ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error)
+	// Check checks a node now and returns its result.
+	Check(context.Context, *CheckRequest) (*NodeResult, error)
+	// StreamResults sends the result of every node as soon as its checks finish.
+	StreamResults(*StreamResultsRequest, grpc.ServerStreamingServer[NodeResult]) error
+	mustEmbedUnimplementedNodeStatServer()
+}
+
+// UnimplementedNodeStatServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedNodeStatServer struct{}
+
+func (UnimplementedNodeStatServer) ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListResults not implemented")
+}
+func (UnimplementedNodeStatServer) Check(context.Context, *CheckRequest) (*NodeResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedNodeStatServer) StreamResults(*StreamResultsRequest, grpc.ServerStreamingServer[NodeResult]) error {
+	return status.Error(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedNodeStatServer) mustEmbedUnimplementedNodeStatServer() {}
+func (UnimplementedNodeStatServer) testEmbeddedByValue()                  {}
+
+// UnsafeNodeStatServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NodeStatServer will
+// result in compilation errors.
+type UnsafeNodeStatServer interface {
+	mustEmbedUnimplementedNodeStatServer()
+}
+
+func RegisterNodeStatServer(s grpc.ServiceRegistrar, srv NodeStatServer) {
+	// If the following call panics, it indicates UnimplementedNodeStatServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&NodeStat_ServiceDesc, srv)
+}
+
+func _NodeStat_ListResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeStatServer).ListResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeStat_ListResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeStatServer).ListResults(ctx, req.(*ListResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeStat_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeStatServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NodeStat_Check_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeStatServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NodeStat_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NodeStatServer).StreamResults(m, &grpc.GenericServerStream[StreamResultsRequest, NodeResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NodeStat_StreamResultsServer = grpc.ServerStreamingServer[NodeResult]
+
+// NodeStat_ServiceDesc is the grpc.ServiceDesc for NodeStat service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NodeStat_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nodestat.v1.NodeStat",
+	HandlerType: (*NodeStatServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListResults",
+			Handler:    _NodeStat_ListResults_Handler,
+		},
+		{
+			MethodName: "Check",
+			Handler:    _NodeStat_Check_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _NodeStat_StreamResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nodestat/v1/nodestat.proto",
+}
@@ -0,0 +1,235 @@
+// Package api serves the results of the daemon over a REST and a gRPC API.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/morzhanov/nodestat/pkg/checker"
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// subscriberBuffer is the number of results queued for a subscriber, results are dropped for slower subscribers
+const subscriberBuffer = 64
+
+var (
+	errNodeNotFound = errors.New("node not found")
+	errNoResult     = errors.New("no result yet")
+	errCheckFailed  = errors.New("checks failed")
+)
+
+// Server serves the latest results of the checked nodes:
+//
+//	GET  /api/v1/nodes               results of all nodes keyed by node name
+//	GET  /api/v1/nodes/{name}        result of a node
+//	POST /api/v1/nodes/{name}/check  checks a node now and returns its result
+type Server struct {
+	checker *checker.Checker
+
+	mu          sync.RWMutex
+	results     map[string]checker.Result
+	subscribers map[chan checker.NodeResult]struct{}
+
+	// checkMu serializes on-demand checks, they share their local ports
+	checkMu sync.Mutex
+}
+
+// errorResponse is the body of failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer returns a Server of the nodes checked by chk, on-demand checks share its Kubernetes clients
+func NewServer(chk *checker.Checker) *Server {
+	return &Server{
+		checker:     chk,
+		results:     make(map[string]checker.Result),
+		subscribers: make(map[chan checker.NodeResult]struct{}),
+	}
+}
+
+// Update replaces the served results with those of the latest run
+func (s *Server) Update(results map[string]checker.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = make(map[string]checker.Result, len(results))
+	for nodeName, res := range results {
+		s.results[nodeName] = res
+	}
+}
+
+// Publish stores the result of a node as soon as its checks finish and sends it to the subscribers
+func (s *Server) Publish(nodeName string, res checker.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[nodeName] = res
+	for sub := range s.subscribers {
+		select {
+		case sub <- checker.NodeResult{Name: nodeName, Result: res}:
+		default:
+		}
+	}
+}
+
+// subscribe returns a channel receiving every published result until unsubscribe is called
+func (s *Server) subscribe() (<-chan checker.NodeResult, func()) {
+	sub := make(chan checker.NodeResult, subscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub, func() {
+		s.mu.Lock()
+		delete(s.subscribers, sub)
+		s.mu.Unlock()
+	}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/nodes", s.listNodes)
+	mux.HandleFunc("GET /api/v1/nodes/{name}", s.getNode)
+	mux.HandleFunc("POST /api/v1/nodes/{name}/check", s.checkNode)
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) listNodes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.latest())
+}
+
+func (s *Server) getNode(w http.ResponseWriter, r *http.Request) {
+	res, err := s.result(r.PathValue("name"))
+	if err != nil {
+		writeError(w, r.PathValue("name"), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) checkNode(w http.ResponseWriter, r *http.Request) {
+	res, err := s.check(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeError(w, r.PathValue("name"), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// latest returns a copy of the latest results
+func (s *Server) latest() map[string]checker.Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results := make(map[string]checker.Result, len(s.results))
+	for nodeName, res := range s.results {
+		results[nodeName] = res
+	}
+	return results
+}
+
+// result returns the latest result of a node
+func (s *Server) result(name string) (checker.Result, error) {
+	if _, ok := s.node(name); !ok {
+		return checker.Result{}, errNodeNotFound
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res, ok := s.results[name]
+	if !ok {
+		return checker.Result{}, errNoResult
+	}
+	return res, nil
+}
+
+// check checks a node now and publishes its result, the check is abandoned when ctx is done
+func (s *Server) check(ctx context.Context, name string) (checker.Result, error) {
+	node, ok := s.node(name)
+	if !ok {
+		return checker.Result{}, errNodeNotFound
+	}
+
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+
+	// Forward past the local ports of the daemon's runs so both can check at once
+	port := s.checker.Port
+	if port == 0 {
+		port = checker.DefaultPort
+	}
+	chk := &checker.Checker{
+		Config:     s.checker.Config,
+		Nodes:      map[string]config.Node{name: node},
+		Kube:       s.checker.Kube,
+		References: s.checker.References,
+		Canaries:   s.checker.Canaries,
+		Options:    s.checker.Options,
+		Port:       port + len(s.nodes()) + 1,
+	}
+	var checkErr error
+	chk.OnError = func(_ string, err error) {
+		checkErr = err
+	}
+	results, err := chk.Run(ctx)
+	if err != nil {
+		return checker.Result{}, err
+	}
+	res, ok := results[name]
+	if !ok {
+		if checkErr != nil {
+			return checker.Result{}, fmt.Errorf("%w: %v", errCheckFailed, checkErr)
+		}
+		return checker.Result{}, errCheckFailed
+	}
+	s.Publish(name, res)
+	return res, nil
+}
+
+// nodes returns the nodes of the checker
+func (s *Server) nodes() map[string]config.Node {
+	if s.checker.Nodes == nil {
+		return s.checker.Config.Nodes
+	}
+	return s.checker.Nodes
+}
+
+// node returns the node of the checker named name
+func (s *Server) node(name string) (config.Node, bool) {
+	node, ok := s.nodes()[name]
+	return node, ok
+}
+
+// writeError writes the error of a request about a node with its status code
+func writeError(w http.ResponseWriter, name string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errNodeNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, errNoResult):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, errCheckFailed):
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, errorResponse{Error: fmt.Sprintf("node %s: %v", name, err)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// ChainAdapter implements the core checks every node gets for a family of chains
+type ChainAdapter interface {
+	// SyncStatus reports "synced", "syncing" or "unknown" given the reference chain head
+	SyncStatus(ctx context.Context, node config.Node, localPort int, referenceHead int64) (string, error)
+	// Head returns the latest block height of the node
+	Head(ctx context.Context, node config.Node, localPort int) (int64, error)
+	// PeerCount returns the number of connected peers, ok is false when the chain does not expose it
+	PeerCount(ctx context.Context, node config.Node, localPort int) (count int64, ok bool, err error)
+	// ReferenceHead returns the chain head reported by the public reference API
+	ReferenceHead(ctx context.Context, apiConf config.PublicAPI) (int64, error)
+}
+
+// batchedAdapter is implemented by adapters whose core checks call param-less JSON-RPC methods,
+// prefetched as a single batch before the checks
+type batchedAdapter interface {
+	batchMethods() []string
+}
+
+// syncingAdapter is implemented by adapters whose sync status comes from an eth_syncing answer,
+// returned along with the status so that its progress fields are read without calling eth_syncing again
+type syncingAdapter interface {
+	syncing(ctx context.Context, node config.Node, localPort int, referenceHead int64) (string, interface{}, error)
+}
+
+// chainAdapters are the registered adapters by chain type
+var chainAdapters = map[string]ChainAdapter{
+	config.ChainTypeEVM:       evmAdapter{},
+	config.ChainTypeArbitrum:  arbitrumAdapter{},
+	config.ChainTypeCosmos:    cosmosAdapter{},
+	config.ChainTypeBitcoin:   bitcoinAdapter{},
+	config.ChainTypeSubstrate: substrateAdapter{},
+}
+
+// adapterFor returns the adapter of the node's chain type, EVM by default
+func adapterFor(node config.Node) (ChainAdapter, error) {
+	chainType := node.Type
+	if chainType == "" {
+		chainType = config.ChainTypeEVM
+	}
+	adapter, ok := chainAdapters[chainType]
+	if !ok {
+		return nil, fmt.Errorf("unknown chain type %q", chainType)
+	}
+	return adapter, nil
+}
+
+// evmAdapter serves Ethereum JSON-RPC nodes with an Etherscan-compatible reference API
+type evmAdapter struct{}
+
+func (a evmAdapter) SyncStatus(ctx context.Context, node config.Node, localPort int, referenceHead int64) (string, error) {
+	syncStatus, _, err := a.syncing(ctx, node, localPort, referenceHead)
+	return syncStatus, err
+}
+
+func (evmAdapter) syncing(ctx context.Context, node config.Node, localPort int, referenceHead int64) (string, interface{}, error) {
+	status, err := rpc.Call(ctx, node, localPort, "eth_syncing")
+	if err != nil {
+		return "", nil, err
+	}
+	syncStatus, err := getSyncStatus(status, referenceHead, node.StartLag())
+	return syncStatus, status, err
+}
+
+func (evmAdapter) Head(ctx context.Context, node config.Node, localPort int) (int64, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "eth_blockNumber")
+	if err != nil {
+		return 0, err
+	}
+	var head string
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return 0, err
+	}
+	return rpc.ParseHex(head)
+}
+
+func (evmAdapter) PeerCount(ctx context.Context, node config.Node, localPort int) (int64, bool, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "net_peerCount")
+	if err != nil {
+		return 0, false, err
+	}
+	var peersCount string
+	if err := json.Unmarshal(raw, &peersCount); err != nil {
+		return 0, false, err
+	}
+	count, err := rpc.ParseHex(peersCount)
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
+func (evmAdapter) batchMethods() []string {
+	return []string{"net_peerCount", "eth_blockNumber", "eth_syncing"}
+}
+
+func (evmAdapter) ReferenceHead(ctx context.Context, apiConf config.PublicAPI) (int64, error) {
+	if err := waitRateLimit(ctx, apiConf); err != nil {
+		return 0, err
+	}
+	switch {
+	case apiConf.Provider == config.ProviderRPC:
+		return fetchRPCHead(ctx, apiConf.URL)
+	case apiConf.URL == "":
+		return fetchRPCHead(ctx, apiConf.RPCURL)
+	}
+	return fetchLatestBlock(ctx, apiConf)
+}
+
+// arbitrumAdapter serves Arbitrum Nitro nodes, which have no P2P peers to count
+type arbitrumAdapter struct {
+	evmAdapter
+}
+
+func (arbitrumAdapter) PeerCount(ctx context.Context, node config.Node, localPort int) (int64, bool, error) {
+	return 0, false, nil
+}
+
+func (arbitrumAdapter) batchMethods() []string {
+	return []string{"eth_blockNumber", "eth_syncing"}
+}
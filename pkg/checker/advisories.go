@@ -0,0 +1,107 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"gopkg.in/yaml.v2"
+)
+
+// Advisory represents a published security advisory affecting a range of client versions
+type Advisory struct {
+	ID       string `json:"id" yaml:"id"`
+	Client   string `json:"client" yaml:"client"`
+	Severity string `json:"severity" yaml:"severity"`
+	// Introduced is the first affected version (empty means all versions), Fixed the first patched one
+	Introduced string `json:"introduced" yaml:"introduced"`
+	Fixed      string `json:"fixed" yaml:"fixed"`
+	URL        string `json:"url" yaml:"url"`
+}
+
+// advisoryFeedTTL is how long a loaded advisory feed is used before it is read again
+const advisoryFeedTTL = time.Hour
+
+var (
+	advisoryFeedMu     sync.Mutex
+	advisoryFeed       []Advisory
+	advisoryFeedLoaded time.Time
+)
+
+// Critical reports whether the advisory is of high or critical severity, which makes affected nodes vulnerable
+func (a Advisory) Critical() bool {
+	return strings.EqualFold(a.Severity, "critical") || strings.EqualFold(a.Severity, "high")
+}
+
+// loadAdvisoryFeed reads the advisory feed from a local file or an HTTP(S) URL, again once advisoryFeedTTL passed
+// so that daemons follow updates. A failed read is retried by the next call, the previous feed is used meanwhile.
+func loadAdvisoryFeed(ctx context.Context, location string) ([]Advisory, error) {
+	advisoryFeedMu.Lock()
+	defer advisoryFeedMu.Unlock()
+
+	if !advisoryFeedLoaded.IsZero() && time.Since(advisoryFeedLoaded) < advisoryFeedTTL {
+		return advisoryFeed, nil
+	}
+	feed, err := readAdvisoryFeed(ctx, location)
+	if err != nil {
+		if !advisoryFeedLoaded.IsZero() {
+			return advisoryFeed, nil
+		}
+		return nil, err
+	}
+	advisoryFeed, advisoryFeedLoaded = feed, time.Now()
+	return advisoryFeed, nil
+}
+
+func readAdvisoryFeed(ctx context.Context, location string) ([]Advisory, error) {
+	var data []byte
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		resp, err := httpGet(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("advisory feed returned %s", resp.Status)
+		}
+		if data, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if data, err = ioutil.ReadFile(config.ExpandHome(location)); err != nil {
+			return nil, err
+		}
+	}
+
+	var feed struct {
+		Advisories []Advisory `yaml:"advisories"`
+	}
+	if err := yaml.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+	return feed.Advisories, nil
+}
+
+// matchAdvisories returns the advisories affecting the given client version
+func matchAdvisories(feed []Advisory, client ClientVersion) []Advisory {
+	var affected []Advisory
+	for _, advisory := range feed {
+		if !strings.EqualFold(advisory.Client, client.Client) || client.Version == "" {
+			continue
+		}
+		if advisory.Introduced != "" && compareVersions(client.Version, advisory.Introduced) < 0 {
+			continue
+		}
+		if advisory.Fixed != "" && compareVersions(client.Version, advisory.Fixed) >= 0 {
+			continue
+		}
+		affected = append(affected, advisory)
+	}
+	return affected
+}
@@ -0,0 +1,39 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchAdvisories(t *testing.T) {
+	feed := []Advisory{
+		{ID: "GETH-1", Client: "geth", Severity: "high", Introduced: "1.10.0", Fixed: "1.13.5"},
+		{ID: "GETH-2", Client: "Geth", Severity: "low", Fixed: "1.14.0"},
+		{ID: "ERIGON-1", Client: "erigon", Severity: "critical", Introduced: "2.50.0"},
+	}
+	tests := []struct {
+		name    string
+		version ClientVersion
+		want    []string
+	}{
+		{name: "affected by both", version: parseClientVersion("Geth/v1.12.0-stable/linux-amd64/go1.21"), want: []string{"GETH-1", "GETH-2"}},
+		{name: "fixed version", version: parseClientVersion("Geth/v1.13.5-stable/linux-amd64/go1.21"), want: []string{"GETH-2"}},
+		{name: "before introduced", version: parseClientVersion("Geth/v1.9.25-stable/linux-amd64/go1.16"), want: []string{"GETH-2"}},
+		{name: "introduced version", version: parseClientVersion("Geth/v1.10.0-stable/linux-amd64/go1.16"), want: []string{"GETH-1", "GETH-2"}},
+		{name: "all fixed", version: parseClientVersion("Geth/v1.14.0-stable/linux-amd64/go1.22"), want: nil},
+		{name: "never fixed", version: parseClientVersion("erigon/2.55.1/linux-amd64/go1.21.5"), want: []string{"ERIGON-1"}},
+		{name: "other client", version: parseClientVersion("besu/v24.1.2/linux-x86_64/openjdk-java-17"), want: nil},
+		{name: "unknown version", version: parseClientVersion("Geth/stable/linux-amd64"), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, advisory := range matchAdvisories(feed, tt.version) {
+				got = append(got, advisory.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchAdvisories(%s) = %v, want %v", tt.version.Raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const (
+	defaultArchiveBlock   = 1
+	defaultArchiveAddress = "0x0000000000000000000000000000000000000000"
+)
+
+// ArchiveState represents whether the node serves the state of a historical block
+type ArchiveState struct {
+	Block     int64  `json:"block" yaml:"block"`
+	Available bool   `json:"available" yaml:"available"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// checkArchiveState queries a balance at a historical block; pruned nodes answer with an error such as "missing trie node"
+func checkArchiveState(ctx context.Context, node config.Node, localPort int, conf config.ArchiveConfig) *ArchiveState {
+	if conf.Block == 0 {
+		conf.Block = defaultArchiveBlock
+	}
+	if conf.Address == "" {
+		conf.Address = defaultArchiveAddress
+	}
+
+	state := &ArchiveState{Block: conf.Block}
+	raw, err := rpc.CallRaw(ctx, node, localPort, "eth_getBalance", conf.Address, fmt.Sprintf("0x%x", conf.Block))
+	if err == nil {
+		_, err = quantity(raw)
+	}
+	if err != nil {
+		state.Error = err.Error()
+		return state
+	}
+	state.Available = true
+	return state
+}
@@ -0,0 +1,185 @@
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// Verdicts of the assertions of a run
+const (
+	VerdictPass = "pass"
+	VerdictFail = "fail"
+)
+
+// metricAliases are the short names of result fields usable in assertions
+var metricAliases = map[string]string{
+	"peers":     "peers_count",
+	"block":     "node_block_num",
+	"reference": "latest_block_num",
+	"status":    "sync_status",
+}
+
+// AssertionResult represents the outcome of an assertion on the result of a node
+type AssertionResult struct {
+	Assertion string `json:"assertion" yaml:"assertion"`
+	Passed    bool   `json:"passed" yaml:"passed"`
+	// Value is the asserted metric of the result, nil when the node doesn't report it
+	Value interface{} `json:"value" yaml:"value"`
+	Error string      `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// AssertionSummary is the aggregate verdict of the assertions of a run
+type AssertionSummary struct {
+	Passed  int    `json:"passed" yaml:"passed"`
+	Failed  int    `json:"failed" yaml:"failed"`
+	Verdict string `json:"verdict" yaml:"verdict"`
+}
+
+// AssertionsPassed reports whether every assertion on the result passed
+func (res Result) AssertionsPassed() bool {
+	for _, assertion := range res.Assertions {
+		if !assertion.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// SummarizeAssertions returns the aggregate verdict of the assertions of the results, nil when there are none
+func SummarizeAssertions(results map[string]Result) *AssertionSummary {
+	summary := &AssertionSummary{Verdict: VerdictPass}
+	for _, res := range results {
+		for _, assertion := range res.Assertions {
+			if assertion.Passed {
+				summary.Passed++
+			} else {
+				summary.Failed++
+				summary.Verdict = VerdictFail
+			}
+		}
+	}
+	if summary.Passed+summary.Failed == 0 {
+		return nil
+	}
+	return summary
+}
+
+// evaluateAssertions checks the assertions against the metrics of res, as named by its JSON fields
+func evaluateAssertions(exprs []string, res Result) []AssertionResult {
+	if len(exprs) == 0 {
+		return nil
+	}
+	var metrics map[string]interface{}
+	if data, err := json.Marshal(res); err == nil {
+		json.Unmarshal(data, &metrics)
+	}
+
+	results := make([]AssertionResult, 0, len(exprs))
+	for _, expr := range exprs {
+		assertion, err := config.ParseAssertion(expr)
+		if err != nil {
+			results = append(results, AssertionResult{Assertion: expr, Error: err.Error()})
+			continue
+		}
+		results = append(results, evaluateAssertion(assertion, metrics))
+	}
+	return results
+}
+
+func evaluateAssertion(assertion config.Assertion, metrics map[string]interface{}) AssertionResult {
+	res := AssertionResult{Assertion: assertion.String()}
+	metric := assertion.Metric
+	if alias, ok := metricAliases[metric]; ok {
+		metric = alias
+	}
+	var value interface{} = metrics
+	for _, key := range strings.Split(metric, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			value = nil
+			break
+		}
+		value = fields[key]
+	}
+	res.Value = value
+
+	switch actual := value.(type) {
+	case nil:
+		res.Error = fmt.Sprintf("%s is not reported", assertion.Metric)
+	case float64:
+		expected, err := assertionNumber(assertion.Value)
+		if err != nil {
+			res.Error = err.Error()
+			break
+		}
+		res.Passed = compare(assertion.Operator, actual, expected)
+	case string:
+		res.Passed, res.Error = compareEquality(assertion.Operator, actual == assertion.Value)
+	case bool:
+		expected, err := strconv.ParseBool(assertion.Value)
+		if err != nil {
+			res.Error = fmt.Sprintf("%s is a boolean, got %q", assertion.Metric, assertion.Value)
+			break
+		}
+		res.Passed, res.Error = compareEquality(assertion.Operator, actual == expected)
+	default:
+		res.Error = fmt.Sprintf("%s is not a number, string or boolean", assertion.Metric)
+	}
+	if !res.Passed && res.Error == "" {
+		res.Error = fmt.Sprintf("%s is %v", assertion.Metric, formatMetric(value))
+	}
+	return res
+}
+
+// assertionNumber parses the compared value of a numeric metric, durations as nanoseconds like the results
+func assertionNumber(value string) (float64, error) {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return float64(duration), nil
+	}
+	return 0, fmt.Errorf("%q is not a number or a duration", value)
+}
+
+func compare(operator string, actual float64, expected float64) bool {
+	switch operator {
+	case "==":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	case "<":
+		return actual < expected
+	case "<=":
+		return actual <= expected
+	case ">":
+		return actual > expected
+	default:
+		return actual >= expected
+	}
+}
+
+// compareEquality applies an equality operator to the outcome of an equality test, other operators are errors
+func compareEquality(operator string, equal bool) (bool, string) {
+	switch operator {
+	case "==":
+		return equal, ""
+	case "!=":
+		return !equal, ""
+	default:
+		return false, fmt.Sprintf("operator %s only compares numbers", operator)
+	}
+}
+
+// formatMetric prints a metric value, whole numbers without exponent
+func formatMetric(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
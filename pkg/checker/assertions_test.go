@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluateAssertions(t *testing.T) {
+	peers := int64(8)
+	res := Result{
+		Chain:          "eth",
+		SyncStatus:     "synced",
+		NodeBlockNum:   1000,
+		LatestBlockNum: 1010,
+		Diff:           10,
+		PeersCount:     &peers,
+		HeadAge:        12 * time.Second,
+		Finality:       &Finality{Finalized: 936, Lag: 64},
+	}
+
+	tests := []struct {
+		expr   string
+		passed bool
+		// value is the reported metric, error a part of the error, empty when none is expected
+		value interface{}
+		error string
+	}{
+		{expr: "diff < 50", passed: true, value: 10.0},
+		{expr: "diff >= 50", value: 10.0, error: "diff is 10"},
+		{expr: "diff == 10", passed: true, value: 10.0},
+		{expr: "diff != 10", value: 10.0, error: "diff is 10"},
+		{expr: "peers >= 5", passed: true, value: 8.0},
+		{expr: "peers > 8", value: 8.0, error: "peers is 8"},
+		{expr: "block <= 1000", passed: true, value: 1000.0},
+		{expr: "reference == 1010", passed: true, value: 1010.0},
+		{expr: "finality.lag <= 64", passed: true, value: 64.0},
+		{expr: "head_age < 30s", passed: true, value: float64(12 * time.Second)},
+		{expr: "head_age < 10s", value: float64(12 * time.Second), error: "head_age is 12000000000"},
+		{expr: "status == synced", passed: true, value: "synced"},
+		{expr: `sync_status != "synced"`, value: "synced", error: "sync_status is synced"},
+		{expr: "status < synced", value: "synced", error: "operator < only compares numbers"},
+		{expr: "finality.stalled == false", passed: true, value: false},
+		{expr: "finality.stalled == no", value: false, error: "is a boolean"},
+		{expr: "diff < many", value: 10.0, error: `"many" is not a number or a duration`},
+		{expr: "gas_price.base_fee < 100", error: "gas_price.base_fee is not reported"},
+		{expr: "finality > 1", value: map[string]interface{}{}, error: "not a number, string or boolean"},
+		{expr: "diff", error: "invalid assertion"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			results := evaluateAssertions([]string{tt.expr}, res)
+			if len(results) != 1 {
+				t.Fatalf("evaluateAssertions returned %d results, want 1", len(results))
+			}
+			got := results[0]
+			if got.Passed != tt.passed {
+				t.Errorf("passed = %t, want %t (error %q)", got.Passed, tt.passed, got.Error)
+			}
+			if tt.error == "" && got.Error != "" {
+				t.Errorf("error = %q, want none", got.Error)
+			}
+			if tt.error != "" && !strings.Contains(got.Error, tt.error) {
+				t.Errorf("error = %q, want %q in it", got.Error, tt.error)
+			}
+			if _, isMap := tt.value.(map[string]interface{}); !isMap && got.Value != tt.value {
+				t.Errorf("value = %#v, want %#v", got.Value, tt.value)
+			}
+		})
+	}
+}
+
+func TestSummarizeAssertions(t *testing.T) {
+	tests := []struct {
+		name    string
+		results map[string]Result
+		want    *AssertionSummary
+	}{
+		{name: "no assertions", results: map[string]Result{"a": {}}, want: nil},
+		{
+			name: "all passed",
+			results: map[string]Result{
+				"a": {Assertions: []AssertionResult{{Passed: true}, {Passed: true}}},
+				"b": {Assertions: []AssertionResult{{Passed: true}}},
+			},
+			want: &AssertionSummary{Passed: 3, Verdict: VerdictPass},
+		},
+		{
+			name: "one failed",
+			results: map[string]Result{
+				"a": {Assertions: []AssertionResult{{Passed: true}}},
+				"b": {Assertions: []AssertionResult{{Passed: false}}},
+			},
+			want: &AssertionSummary{Passed: 1, Failed: 1, Verdict: VerdictFail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SummarizeAssertions(tt.results)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("SummarizeAssertions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
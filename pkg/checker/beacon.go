@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// BeaconStatus represents the state of the consensus client paired with an execution node
+type BeaconStatus struct {
+	Version      string `json:"version" yaml:"version"`
+	HeadSlot     int64  `json:"head_slot" yaml:"head_slot"`
+	SyncDistance int64  `json:"sync_distance" yaml:"sync_distance"`
+	IsSyncing    bool   `json:"is_syncing" yaml:"is_syncing"`
+	IsOptimistic bool   `json:"is_optimistic" yaml:"is_optimistic"`
+	ELOffline    bool   `json:"el_offline" yaml:"el_offline"`
+	PeersCount   int64  `json:"peers_count" yaml:"peers_count"`
+}
+
+// checkBeacon queries the Beacon REST API of the node's beacon endpoint
+func checkBeacon(ctx context.Context, node config.Node, localPort int) (*BeaconStatus, error) {
+	url := endpointURL(node, localPort, findEndpoint(node, config.EndpointBeacon))
+
+	var syncing struct {
+		HeadSlot     string `json:"head_slot"`
+		SyncDistance string `json:"sync_distance"`
+		IsSyncing    bool   `json:"is_syncing"`
+		IsOptimistic bool   `json:"is_optimistic"`
+		ELOffline    bool   `json:"el_offline"`
+	}
+	if err := fetchBeacon(ctx, url+"/eth/v1/node/syncing", &syncing); err != nil {
+		return nil, fmt.Errorf("getting sync status: %v", err)
+	}
+	status := &BeaconStatus{
+		IsSyncing:    syncing.IsSyncing,
+		IsOptimistic: syncing.IsOptimistic,
+		ELOffline:    syncing.ELOffline,
+	}
+	var err error
+	if status.HeadSlot, err = strconv.ParseInt(syncing.HeadSlot, 10, 64); err != nil {
+		return nil, fmt.Errorf("getting sync status: %v", err)
+	}
+	if status.SyncDistance, err = strconv.ParseInt(syncing.SyncDistance, 10, 64); err != nil {
+		return nil, fmt.Errorf("getting sync status: %v", err)
+	}
+
+	var peerCount struct {
+		Connected string `json:"connected"`
+	}
+	if err := fetchBeacon(ctx, url+"/eth/v1/node/peer_count", &peerCount); err != nil {
+		return nil, fmt.Errorf("getting peers count: %v", err)
+	}
+	if status.PeersCount, err = strconv.ParseInt(peerCount.Connected, 10, 64); err != nil {
+		return nil, fmt.Errorf("getting peers count: %v", err)
+	}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := fetchBeacon(ctx, url+"/eth/v1/node/version", &version); err != nil {
+		return nil, fmt.Errorf("getting version: %v", err)
+	}
+	status.Version = version.Version
+
+	return status, nil
+}
+
+// fetchBeacon performs a Beacon API GET request and decodes its data field
+func fetchBeacon(ctx context.Context, url string, out interface{}) error {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	return json.Unmarshal(body.Data, out)
+}
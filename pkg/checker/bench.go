@@ -0,0 +1,107 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/forward"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// BenchOptions configures the load driven against a node
+type BenchOptions struct {
+	Method string
+	Params []interface{}
+	// RPS is the rate at which requests are started, Duration how long the load lasts
+	RPS      int
+	Duration time.Duration
+	// ErrOut receives the errors of the port forward or SSH tunnel during the load, they are dropped if nil
+	ErrOut io.Writer
+}
+
+// BenchResult represents the throughput, error rate and latency percentiles of a load run
+type BenchResult struct {
+	Method     string        `json:"method" yaml:"method"`
+	Requests   int           `json:"requests" yaml:"requests"`
+	Errors     int           `json:"errors" yaml:"errors"`
+	Duration   time.Duration `json:"duration" yaml:"duration"`
+	Throughput float64       `json:"throughput" yaml:"throughput"`
+	ErrorRate  float64       `json:"error_rate" yaml:"error_rate"`
+	P50        time.Duration `json:"p50" yaml:"p50"`
+	P90        time.Duration `json:"p90" yaml:"p90"`
+	P99        time.Duration `json:"p99" yaml:"p99"`
+	Max        time.Duration `json:"max" yaml:"max"`
+	// FirstError is the error of the first failed request
+	FirstError string `json:"first_error,omitempty" yaml:"first_error,omitempty"`
+}
+
+// Bench connects to the node the way its checks do, through localPort, and calls opts.Method at opts.RPS
+// for opts.Duration. Requests are started on schedule even when the node falls behind answering them,
+// so a slow node shows in the latencies rather than in a lower request rate.
+func Bench(ctx context.Context, kubes *forward.KubeClients, nodeName string, node config.Node, localPort int, opts BenchOptions) (*BenchResult, error) {
+	errOut := opts.ErrOut
+	if errOut == nil {
+		errOut = io.Discard
+	}
+	node, _, closeForward, err := connectNode(ctx, kubes, nodeName, node, localPort, errOut)
+	if err != nil {
+		return nil, err
+	}
+	defer closeForward()
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		latencies  []time.Duration
+		failed     int
+		firstError string
+	)
+	ticker := time.NewTicker(time.Second / time.Duration(opts.RPS))
+	defer ticker.Stop()
+	deadline := time.NewTimer(opts.Duration)
+	defer deadline.Stop()
+
+	start := time.Now()
+load:
+	for {
+		select {
+		case <-ticker.C:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				callStart := time.Now()
+				_, err := rpc.CallRaw(ctx, node, localPort, opts.Method, opts.Params...)
+				latency := time.Since(callStart)
+				mu.Lock()
+				defer mu.Unlock()
+				latencies = append(latencies, latency)
+				if err != nil {
+					failed++
+					if firstError == "" {
+						firstError = err.Error()
+					}
+				}
+			}()
+		case <-deadline.C:
+			break load
+		case <-ctx.Done():
+			break load
+		}
+	}
+	wg.Wait()
+
+	res := &BenchResult{Method: opts.Method, Requests: len(latencies), Errors: failed, Duration: time.Since(start), FirstError: firstError}
+	if res.Requests == 0 {
+		return res, ctx.Err()
+	}
+	res.Throughput = float64(res.Requests-res.Errors) / res.Duration.Seconds()
+	res.ErrorRate = float64(res.Errors) / float64(res.Requests)
+	res.P50 = percentile(latencies, 50)
+	res.P90 = percentile(latencies, 90)
+	res.P99 = percentile(latencies, 99)
+	res.Max = percentile(latencies, 100)
+	return res, ctx.Err()
+}
@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// bitcoinMaxHeaderLag is the number of known headers a node may lack blocks for and still count as synced
+const bitcoinMaxHeaderLag = 2
+
+// blockchainInfo is the part of the getblockchaininfo response nodestat uses
+type blockchainInfo struct {
+	Blocks               int64 `json:"blocks"`
+	Headers              int64 `json:"headers"`
+	InitialBlockDownload bool  `json:"initialblockdownload"`
+}
+
+// bitcoinAdapter serves Bitcoin Core nodes.
+// The reference head comes from an Esplora API (mempool.space, blockstream.info) configured as url.
+type bitcoinAdapter struct{}
+
+func (bitcoinAdapter) SyncStatus(ctx context.Context, node config.Node, localPort int, referenceHead int64) (string, error) {
+	info, err := fetchBlockchainInfo(ctx, node, localPort)
+	if err != nil {
+		return "", err
+	}
+	if info.InitialBlockDownload || info.Headers-info.Blocks > bitcoinMaxHeaderLag {
+		return "syncing", nil
+	}
+	return "synced", nil
+}
+
+func (bitcoinAdapter) Head(ctx context.Context, node config.Node, localPort int) (int64, error) {
+	info, err := fetchBlockchainInfo(ctx, node, localPort)
+	if err != nil {
+		return 0, err
+	}
+	return info.Blocks, nil
+}
+
+func (bitcoinAdapter) PeerCount(ctx context.Context, node config.Node, localPort int) (int64, bool, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "getnetworkinfo")
+	if err != nil {
+		return 0, false, err
+	}
+	var info struct {
+		Connections int64 `json:"connections"`
+	}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return 0, false, err
+	}
+	return info.Connections, true, nil
+}
+
+func (bitcoinAdapter) batchMethods() []string {
+	return []string{"getnetworkinfo", "getblockchaininfo"}
+}
+
+func (bitcoinAdapter) ReferenceHead(ctx context.Context, apiConf config.PublicAPI) (int64, error) {
+	resp, err := httpGet(ctx, strings.TrimSuffix(apiConf.URL, "/")+"/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+}
+
+func fetchBlockchainInfo(ctx context.Context, node config.Node, localPort int) (*blockchainInfo, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "getblockchaininfo")
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, fmt.Errorf("empty getblockchaininfo response")
+	}
+	var info blockchainInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// rpcCaller performs a JSON-RPC call against either a node or a reference endpoint
+type rpcCaller func(method string, params ...interface{}) (json.RawMessage, error)
+
+func nodeCaller(ctx context.Context, node config.Node, localPort int) rpcCaller {
+	return func(method string, params ...interface{}) (json.RawMessage, error) {
+		return rpc.CallRaw(ctx, node, localPort, method, params...)
+	}
+}
+
+func referenceCaller(ctx context.Context, apiConf config.PublicAPI) (rpcCaller, error) {
+	if apiConf.RPCURL == "" {
+		return nil, errors.New("no reference rpc_url configured in public_apis")
+	}
+	return func(method string, params ...interface{}) (json.RawMessage, error) {
+		return rpc.CallURL(ctx, apiConf.RPCURL, method, params...)
+	}, nil
+}
+
+// BlockHeader represents the relevant fields of an eth_getBlockByNumber response
+type BlockHeader struct {
+	Number        string `json:"number"`
+	Hash          string `json:"hash"`
+	ParentHash    string `json:"parentHash"`
+	Timestamp     string `json:"timestamp"`
+	GasLimit      string `json:"gasLimit"`
+	GasUsed       string `json:"gasUsed"`
+	BaseFeePerGas string `json:"baseFeePerGas"`
+	// Transactions are the transaction hashes of the block
+	Transactions []string `json:"transactions"`
+}
+
+// fetchBlock returns the header of the block with the given number or tag ("latest", "finalized", ...)
+func fetchBlock(call rpcCaller, block interface{}) (BlockHeader, error) {
+	tag, ok := block.(string)
+	if !ok {
+		tag = fmt.Sprintf("0x%x", block)
+	}
+
+	raw, err := call("eth_getBlockByNumber", tag, false)
+	if err != nil {
+		return BlockHeader{}, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return BlockHeader{}, fmt.Errorf("block %s not found", tag)
+	}
+
+	var header BlockHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return BlockHeader{}, err
+	}
+	return header, nil
+}
+
+// checkHeadAge returns how old the timestamp of the node's latest block is
+func checkHeadAge(ctx context.Context, node config.Node, localPort int) (time.Duration, error) {
+	header, err := fetchBlock(nodeCaller(ctx, node, localPort), "latest")
+	if err != nil {
+		return 0, err
+	}
+	timestamp, err := rpc.ParseHex(header.Timestamp)
+	if err != nil {
+		return 0, err
+	}
+	// Clock skew may put a fresh block in the future
+	return max(time.Since(time.Unix(timestamp, 0)).Round(time.Second), 0), nil
+}
+
+const defaultHashCheckDepth = 12
+
+// checkBlockHash compares the hash of block head-depth on the node and the reference.
+// Matching heights with different hashes mean the node follows another fork.
+func checkBlockHash(ctx context.Context, node config.Node, localPort int, apiConf config.PublicAPI, headBlock int64, depth int64) (string, error) {
+	reference, err := referenceCaller(ctx, apiConf)
+	if err != nil {
+		return "", err
+	}
+	if depth == 0 {
+		depth = defaultHashCheckDepth
+	}
+
+	num := headBlock - depth
+	nodeBlock, err := fetchBlock(nodeCaller(ctx, node, localPort), num)
+	if err != nil {
+		return "", err
+	}
+	refBlock, err := fetchBlock(reference, num)
+	if err != nil {
+		return "", fmt.Errorf("reference: %v", err)
+	}
+
+	if !strings.EqualFold(nodeBlock.Hash, refBlock.Hash) {
+		return fmt.Sprintf("block %d hash %s, reference %s", num, nodeBlock.Hash, refBlock.Hash), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/forward"
+)
+
+// bootnodeTimeout is the number of seconds nc waits for each bootnode probe
+const bootnodeTimeout = 3
+
+// errNoNetcat is returned when the node image has no nc binary to probe the bootnodes with
+var errNoNetcat = errors.New("nc is not available in the node container")
+
+// bootnodeAddr represents the network endpoints of a single bootnode
+type bootnodeAddr struct {
+	Host    string
+	TCPPort string
+	UDPPort string
+}
+
+// parseBootnode accepts an enode URL or a plain host:port pair
+func parseBootnode(bootnode string) (bootnodeAddr, error) {
+	if !strings.Contains(bootnode, "://") {
+		host, port, err := net.SplitHostPort(bootnode)
+		if err != nil {
+			return bootnodeAddr{}, err
+		}
+		return bootnodeAddr{Host: host, TCPPort: port, UDPPort: port}, nil
+	}
+
+	u, err := url.Parse(bootnode)
+	if err != nil {
+		return bootnodeAddr{}, err
+	}
+	addr := bootnodeAddr{Host: u.Hostname(), TCPPort: u.Port(), UDPPort: u.Port()}
+	if discPort := u.Query().Get("discport"); discPort != "" {
+		addr.UDPPort = discPort
+	}
+	if addr.Host == "" || addr.TCPPort == "" {
+		return bootnodeAddr{}, fmt.Errorf("bootnode %s has no host or port", bootnode)
+	}
+	return addr, nil
+}
+
+// checkBootnodes probes every configured bootnode from inside the node pod
+// and returns a description of each failed probe. The probes stop with errNoNetcat
+// when the container can't run them at all.
+func checkBootnodes(ctx context.Context, kube *forward.KubeClient, node config.Node) ([]string, error) {
+	var failures []string
+	for _, bootnode := range node.Bootnodes {
+		addr, err := parseBootnode(bootnode)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", bootnode, err))
+			continue
+		}
+
+		if err := probeFromPod(ctx, kube, node, "-z", addr.Host, addr.TCPPort); errors.Is(err, errNoNetcat) {
+			return nil, err
+		} else if err != nil {
+			failures = append(failures, fmt.Sprintf("%s:%s/tcp: %v", addr.Host, addr.TCPPort, err))
+		}
+		// UDP probes are best-effort: nc only reports ICMP port unreachable replies
+		if err := probeFromPod(ctx, kube, node, "-zu", addr.Host, addr.UDPPort); errors.Is(err, errNoNetcat) {
+			return nil, err
+		} else if err != nil {
+			failures = append(failures, fmt.Sprintf("%s:%s/udp: %v", addr.Host, addr.UDPPort, err))
+		}
+	}
+	return failures, nil
+}
+
+func probeFromPod(ctx context.Context, kube *forward.KubeClient, node config.Node, ncFlags string, host string, port string) error {
+	out, err := kube.Exec(ctx, node.Namespace, node.Service, []string{"nc", ncFlags, "-w", fmt.Sprint(bootnodeTimeout), host, port})
+	if err != nil {
+		if missingCommand(err, out) {
+			return errNoNetcat
+		}
+		if msg := strings.TrimSpace(out); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// missingCommandMessages are the "not found" errors of shells and container runtimes for a missing command
+var missingCommandMessages = []string{"executable file not found", "command not found", "nc: not found"}
+
+// missingCommand reports whether the exec failed because the command isn't installed:
+// exit status 127 from a shell, or the runtime's "not found" error
+func missingCommand(err error, out string) bool {
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() == 127 {
+		return true
+	}
+	for _, msg := range missingCommandMessages {
+		if strings.Contains(err.Error(), msg) || strings.Contains(out, msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func TestMissingCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		out  string
+		want bool
+	}{
+		{name: "exit status 127", err: utilexec.CodeExitError{Err: errors.New("command terminated with exit code 127"), Code: 127}, want: true},
+		{name: "wrapped exit status 127", err: fmt.Errorf("exec: %w", utilexec.CodeExitError{Err: errors.New("exit 127"), Code: 127}), want: true},
+		{name: "runtime error", err: errors.New(`exec: "nc": executable file not found in $PATH: unknown`), want: true},
+		{name: "busybox shell", err: errors.New("command terminated with exit code 1"), out: "sh: nc: not found", want: true},
+		{name: "closed port", err: utilexec.CodeExitError{Err: errors.New("command terminated with exit code 1"), Code: 1}, want: false},
+		{name: "unresolvable host", err: errors.New("command terminated with exit code 1"), out: "nc: getaddrinfo for host \"boot\" port 30303: Name or service not known", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingCommand(tt.err, tt.out); got != tt.want {
+				t.Errorf("missingCommand(%v, %q) = %t, want %t", tt.err, tt.out, got, tt.want)
+			}
+		})
+	}
+}
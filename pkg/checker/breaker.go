@@ -0,0 +1,70 @@
+package checker
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker is a per-node circuit breaker for repeated runs: a node failing its checks
+// Failures runs in a row is marked down and only probed once every Probe until it passes them again,
+// instead of being hammered (and reported) every run
+type Breaker struct {
+	Failures int
+	Probe    time.Duration
+	// OnChange, if set, is called when a node is marked down, with down true, and when it is marked up again
+	OnChange func(nodeName string, down bool)
+
+	mu    sync.Mutex
+	nodes map[string]*breakerState
+}
+
+// breakerState tracks the consecutive failures of a node
+type breakerState struct {
+	failures  int
+	nextProbe time.Time
+}
+
+// NewBreaker returns a Breaker marking nodes down after failures failed runs, probed every probe
+func NewBreaker(failures int, probe time.Duration) *Breaker {
+	return &Breaker{Failures: failures, Probe: probe, nodes: make(map[string]*breakerState)}
+}
+
+// Allow reports whether a node is checked at now: it is up or due for a probe
+func (b *Breaker) Allow(nodeName string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.nodes[nodeName]
+	return !ok || state.failures < b.Failures || !now.Before(state.nextProbe)
+}
+
+// Record records the outcome of a checked node at now
+func (b *Breaker) Record(nodeName string, ok bool, now time.Time) {
+	changed, down := b.record(nodeName, ok, now)
+	if changed && b.OnChange != nil {
+		b.OnChange(nodeName, down)
+	}
+}
+
+// record records the outcome of a checked node and reports whether it was marked down or up by it
+func (b *Breaker) record(nodeName string, ok bool, now time.Time) (changed bool, down bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.nodes[nodeName]
+	if state == nil {
+		state = &breakerState{}
+		b.nodes[nodeName] = state
+	}
+
+	if ok {
+		changed = state.failures >= b.Failures
+		state.failures = 0
+		return changed, false
+	}
+
+	state.failures++
+	if state.failures < b.Failures {
+		return false, false
+	}
+	state.nextProbe = now.Add(b.Probe)
+	return state.failures == b.Failures, true
+}
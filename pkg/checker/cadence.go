@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const (
+	defaultCadenceWindow    = time.Minute
+	defaultCadenceBlockTime = 12 * time.Second
+	// cadenceMaxGapBlocks is the number of block times without a new head before the node is stalled
+	cadenceMaxGapBlocks = 3
+)
+
+// HeadCadence represents how new heads arrived over a newHeads subscription held for a window
+type HeadCadence struct {
+	Window    time.Duration `json:"window" yaml:"window"`
+	BlockTime time.Duration `json:"block_time" yaml:"block_time"`
+	Heads     int           `json:"heads" yaml:"heads"`
+	// FirstBlock and LastBlock are the numbers of the first and last new heads of the window
+	FirstBlock int64 `json:"first_block,omitempty" yaml:"first_block,omitempty"`
+	LastBlock  int64 `json:"last_block,omitempty" yaml:"last_block,omitempty"`
+	// MeanInterval is the mean time between new heads, LongestGap the longest time without one,
+	// counting from the start of the window to its end
+	MeanInterval time.Duration `json:"mean_interval" yaml:"mean_interval"`
+	LongestGap   time.Duration `json:"longest_gap" yaml:"longest_gap"`
+	// Stalled is set when LongestGap exceeds the maximum gap, the node stopped importing blocks
+	Stalled bool   `json:"stalled" yaml:"stalled"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// newHeadsURL returns the WebSocket URL to subscribe to new heads with its credentials: the ws endpoint,
+// or the node URL when the node is checked over WebSocket. ok is false when the node has neither.
+func newHeadsURL(node config.Node, localPort int) (string, *config.NodeAuth, bool) {
+	if i := findEndpoint(node, config.EndpointWS); i >= 0 {
+		return endpointURL(node, localPort, i), node.Endpoints[i].Auth, true
+	}
+	if url := rpc.NodeURL(node, localPort); rpc.IsWebSocket(url) {
+		return url, node.Auth, true
+	}
+	return "", nil, false
+}
+
+// watchHeadCadence holds a newHeads subscription for the window of conf and reports how often new heads arrived.
+// A node can answer eth_blockNumber fine and yet have stopped importing blocks.
+func watchHeadCadence(ctx context.Context, url string, auth *config.NodeAuth, conf config.HeadCadenceConfig) *HeadCadence {
+	if conf.Window == 0 {
+		conf.Window = defaultCadenceWindow
+	}
+	if conf.BlockTime == 0 {
+		conf.BlockTime = defaultCadenceBlockTime
+	}
+	if conf.MaxGap == 0 {
+		conf.MaxGap = cadenceMaxGapBlocks * conf.BlockTime
+	}
+	cadence := &HeadCadence{Window: conf.Window, BlockTime: conf.BlockTime}
+
+	// A node that accepts the connection but never answers eth_subscribe must not hold the check past its window
+	subscribeTimeout := conf.Window + rpc.OptionsFrom(ctx).Timeout
+	subscribeDeadline := time.Now().Add(subscribeTimeout)
+	subscribeCtx, cancel := context.WithDeadline(ctx, subscribeDeadline)
+	conn, ids, err := subscribe(subscribeCtx, url, auth, []interface{}{[]interface{}{"newHeads"}})
+	cancel()
+	if err != nil {
+		cadence.Error = err.Error()
+		if ctx.Err() == nil && !time.Now().Before(subscribeDeadline) {
+			cadence.Error = fmt.Sprintf("newHeads subscription timed out after %s", subscribeTimeout)
+		}
+		return cadence
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	deadline := start.Add(conf.Window)
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	var first, last time.Time
+	for {
+		var msg wsNotification
+		if err = conn.ReadJSON(&msg); err != nil {
+			break
+		}
+		if msg.Params.Subscription != ids[0] {
+			continue
+		}
+		var head struct {
+			Number string `json:"number"`
+		}
+		if json.Unmarshal(msg.Params.Result, &head) != nil {
+			continue
+		}
+		num, err := rpc.ParseHex(head.Number)
+		// Reorged heads at or below the last number are not new blocks
+		if err != nil || (cadence.Heads > 0 && num <= cadence.LastBlock) {
+			continue
+		}
+
+		now := time.Now()
+		if cadence.Heads == 0 {
+			first, cadence.FirstBlock = now, num
+			cadence.LongestGap = now.Sub(start)
+		} else {
+			cadence.LongestGap = max(cadence.LongestGap, now.Sub(last))
+		}
+		last, cadence.LastBlock = now, num
+		cadence.Heads++
+	}
+
+	// Reaching the deadline is the expected way to leave the read loop
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() || time.Now().Before(deadline) {
+		cadence.Error = err.Error()
+		if ctx.Err() != nil {
+			cadence.Error = ctx.Err().Error()
+		}
+		return cadence
+	}
+	if cadence.Heads == 0 {
+		cadence.LongestGap = conf.Window
+	} else {
+		cadence.LongestGap = max(cadence.LongestGap, deadline.Sub(last))
+	}
+	if cadence.Heads > 1 {
+		cadence.MeanInterval = last.Sub(first) / time.Duration(cadence.Heads-1)
+	}
+	cadence.Stalled = cadence.LongestGap > conf.MaxGap
+	return cadence
+}
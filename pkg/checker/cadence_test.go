@@ -0,0 +1,35 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+func TestWatchHeadCadenceSubscribeTimeout(t *testing.T) {
+	// Notifications keep every read short, but eth_subscribe itself is never answered
+	url := subscribeServer(t, func(conn *websocket.Conn, id json.RawMessage) {
+		for {
+			notification := `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xother","result":{}}}`
+			if conn.WriteMessage(websocket.TextMessage, []byte(notification)) != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+	ctx := rpc.WithOptions(context.Background(), rpc.Options{Timeout: 200 * time.Millisecond})
+	start := time.Now()
+	cadence := watchHeadCadence(ctx, url, nil, config.HeadCadenceConfig{Window: 100 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("watchHeadCadence returned after %s, past the window and the RPC timeout", elapsed)
+	}
+	if !strings.Contains(cadence.Error, "timed out") {
+		t.Errorf("cadence error = %q, want a timeout", cadence.Error)
+	}
+}
@@ -0,0 +1,283 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// Canary operation types
+const (
+	CanaryBalance = "balance"
+	CanaryLogs    = "logs"
+	CanaryWS      = "ws"
+	CanaryTx      = "tx"
+)
+
+const (
+	defaultCanaryEvery = 5 * time.Minute
+	// canaryHistorySize is the number of latest runs of a node and canary the SLIs are computed from
+	canaryHistorySize = 1000
+	// canaryPortOffset separates the local ports forwarded for canaries from those of the checks
+	canaryPortOffset = 500
+)
+
+// CanarySLI represents the service level indicators of a canary computed over its stored history
+type CanarySLI struct {
+	Name        string        `json:"name" yaml:"name"`
+	Type        string        `json:"type" yaml:"type"`
+	Runs        int           `json:"runs" yaml:"runs"`
+	SuccessRate float64       `json:"success_rate" yaml:"success_rate"`
+	P50         time.Duration `json:"p50" yaml:"p50"`
+	P95         time.Duration `json:"p95" yaml:"p95"`
+	LastError   string        `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+}
+
+// CanaryRecord represents a single run of a canary, Err is empty when it succeeded
+type CanaryRecord struct {
+	At      time.Time
+	Latency time.Duration
+	Err     string
+}
+
+// CanaryStore keeps the runs of the canaries of every node
+type CanaryStore interface {
+	// AddCanaryRecord stores a run of the canary of the node
+	AddCanaryRecord(nodeName string, canary string, record CanaryRecord) error
+	// CanaryRecords returns the latest limit runs of the canary of the node, oldest first
+	CanaryRecords(nodeName string, canary string, limit int) ([]CanaryRecord, error)
+}
+
+// memoryCanaryStore keeps the latest canaryHistorySize runs of every node and canary in memory
+type memoryCanaryStore struct {
+	mu      sync.Mutex
+	records map[string][]CanaryRecord
+}
+
+func newMemoryCanaryStore() *memoryCanaryStore {
+	return &memoryCanaryStore{records: make(map[string][]CanaryRecord)}
+}
+
+func (s *memoryCanaryStore) AddCanaryRecord(nodeName string, canary string, record CanaryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := nodeName + "/" + canary
+	records := append(s.records[key], record)
+	if len(records) > canaryHistorySize {
+		records = records[len(records)-canaryHistorySize:]
+	}
+	s.records[key] = records
+	return nil
+}
+
+func (s *memoryCanaryStore) CanaryRecords(nodeName string, canary string, limit int) ([]CanaryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.records[nodeName+"/"+canary]
+	if len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return slices.Clone(records), nil
+}
+
+// RunCanaries executes the canaries of the nodes on their own schedule until ctx is done,
+// every node is reached through its own connection, separate from the one of its checks.
+// The runs are kept in c.Canaries, from which Run reports the SLIs.
+func (c *Checker) RunCanaries(ctx context.Context) {
+	c.init()
+	ctx = rpc.WithOptions(ctx, c.Options.RPC)
+	nodes := c.nodes()
+	port := c.port() + canaryPortOffset
+
+	tick := time.Duration(0)
+	for nodeName, node := range nodes {
+		for _, canary := range c.Config.Canaries[node.ChainName(nodeName)] {
+			if tick == 0 || canaryEvery(canary) < tick {
+				tick = canaryEvery(canary)
+			}
+		}
+	}
+	if tick == 0 {
+		return
+	}
+
+	lastRuns := make(map[string]time.Time)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		var wg sync.WaitGroup
+		localPort := port
+		now := time.Now()
+		for nodeName, node := range nodes {
+			chain := node.ChainName(nodeName)
+			var due []config.Canary
+			for _, canary := range c.Config.Canaries[chain] {
+				key := nodeName + "/" + canary.Name
+				// Ticks may fire slightly early, canaries due within half a tick run on it
+				if now.Sub(lastRuns[key]) >= canaryEvery(canary)-tick/2 {
+					due = append(due, canary)
+					lastRuns[key] = now
+				}
+			}
+			if len(due) == 0 {
+				continue
+			}
+
+			wg.Add(1)
+			go func(nodeName string, node config.Node, localPort int) {
+				defer wg.Done()
+				c.runNodeCanaries(ctx, chain, nodeName, node, localPort, due)
+			}(nodeName, node, localPort)
+			localPort++
+		}
+		wg.Wait()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runNodeCanaries connects to the node and stores a run of every canary of due,
+// a failed run of each when the node can't be reached
+func (c *Checker) runNodeCanaries(ctx context.Context, chain string, nodeName string, node config.Node, localPort int, due []config.Canary) {
+	// The failed calls are recorded in the runs, the errors of the port forward itself are not
+	node, _, closeForward, err := connectNode(ctx, c.Kube, nodeName, node, localPort, io.Discard)
+	if err != nil {
+		now := time.Now()
+		for _, canary := range due {
+			c.storeCanaryRecord(nodeName, canary, CanaryRecord{At: now, Err: err.Error()})
+		}
+		return
+	}
+	defer closeForward()
+
+	for _, canary := range due {
+		// A run that hangs is cut at the next one rather than blocking the canaries of every node
+		runCtx, cancel := context.WithTimeout(ctx, canaryEvery(canary))
+		start := time.Now()
+		err := runCanary(runCtx, c.Config, chain, node, localPort, canary)
+		cancel()
+		record := CanaryRecord{At: start, Latency: time.Since(start)}
+		if err != nil {
+			record.Err = err.Error()
+		}
+		c.storeCanaryRecord(nodeName, canary, record)
+	}
+}
+
+// storeCanaryRecord stores a run of the canary of the node, reporting the store errors to OnError
+func (c *Checker) storeCanaryRecord(nodeName string, canary config.Canary, record CanaryRecord) {
+	if err := c.Canaries.AddCanaryRecord(nodeName, canary.Name, record); err != nil {
+		c.reportError(nodeName, fmt.Errorf("storing canary %s: %v", canary.Name, err))
+	}
+}
+
+// canarySLIs returns the SLIs of the chain's canaries which ran on the node
+func canarySLIs(store CanaryStore, canaries []config.Canary, nodeName string) ([]CanarySLI, error) {
+	var slis []CanarySLI
+	for _, canary := range canaries {
+		records, err := store.CanaryRecords(nodeName, canary.Name, canaryHistorySize)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			slis = append(slis, canarySLI(canary, records))
+		}
+	}
+	return slis, nil
+}
+
+func canaryEvery(canary config.Canary) time.Duration {
+	if canary.Every == 0 {
+		return defaultCanaryEvery
+	}
+	return canary.Every
+}
+
+func canarySLI(canary config.Canary, records []CanaryRecord) CanarySLI {
+	sli := CanarySLI{Name: canary.Name, Type: canary.Type, Runs: len(records)}
+	var latencies []time.Duration
+	for _, record := range records {
+		if record.Err != "" {
+			sli.LastError = record.Err
+			continue
+		}
+		latencies = append(latencies, record.Latency)
+	}
+	if sli.Runs > 0 {
+		sli.SuccessRate = float64(len(latencies)) / float64(sli.Runs)
+	}
+	sli.P50 = percentile(latencies, 50)
+	sli.P95 = percentile(latencies, 95)
+	return sli
+}
+
+func runCanary(ctx context.Context, cfg config.NodeConfig, chain string, node config.Node, localPort int, canary config.Canary) error {
+	switch canary.Type {
+	case CanaryBalance:
+		_, err := rpc.Call(ctx, node, localPort, "eth_getBalance", canary.Address, "latest")
+		return err
+	case CanaryLogs:
+		head, err := rpc.Call(ctx, node, localPort, "eth_blockNumber")
+		if err != nil {
+			return err
+		}
+		headStr, _ := head.(string)
+		headNum, err := rpc.ParseHex(headStr)
+		if err != nil {
+			return err
+		}
+		conf := config.LogsCheckConfig{Address: canary.Address, Range: canary.Range}
+		if conf.Range == 0 {
+			conf.Range = defaultLogsRange
+		}
+		_, err = fetchLogs(nodeCaller(ctx, node, localPort), logsFilter(conf, headNum-conf.Range+1, headNum))
+		return err
+	case CanaryWS:
+		i := findEndpoint(node, config.EndpointWS)
+		if i < 0 {
+			return errors.New("no ws endpoint configured")
+		}
+		conn, _, err := subscribe(ctx, endpointURL(node, localPort, i), node.Endpoints[i].Auth, []interface{}{[]interface{}{"newHeads"}})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		// Wait for the first new head notification until the run ends
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetReadDeadline(deadline)
+		}
+		stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+		defer stop()
+		var msg wsNotification
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		return nil
+	case CanaryTx:
+		account, ok := cfg.CanaryAccounts[chain]
+		if !ok {
+			return fmt.Errorf("no canary account configured for %s", chain)
+		}
+		account.Samples = 1
+		_, err := checkInclusionLatency(ctx, chain, node, localPort, account)
+		return err
+	default:
+		return fmt.Errorf("unknown canary type %q", canary.Type)
+	}
+}
@@ -0,0 +1,30 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+func TestRunCanaryWSDeadline(t *testing.T) {
+	// The subscription is created but no new head ever arrives
+	url := subscribeServer(t, func(conn *websocket.Conn, id json.RawMessage) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":`+string(id)+`,"result":"0xs1"}`))
+	})
+	node := config.Node{Endpoints: []config.Endpoint{{Type: config.EndpointWS, URL: url}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := runCanary(ctx, config.NodeConfig{}, "eth", node, 0, config.Canary{Name: "heads", Type: CanaryWS})
+	if err == nil {
+		t.Error("runCanary succeeded without a new head")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("runCanary returned after %s, past the deadline of its run", elapsed)
+	}
+}
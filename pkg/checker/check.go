@@ -0,0 +1,738 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/forward"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// prefixWriter prefixes every line written to out, used to tell the port forward and SSH tunnel errors apart
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if _, err := fmt.Fprintf(w.out, "%s%s\n", w.prefix, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// errorLog collects the errors of the checks of a node which don't fail it,
+// the port forward or SSH tunnel of the node writes its errors to it line by line
+type errorLog struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *errorLog) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line != "" {
+			l.add("%s", line)
+		}
+	}
+	return len(p), nil
+}
+
+// add records an error
+func (l *errorLog) add(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+// list returns the errors recorded so far
+func (l *errorLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.errors)
+}
+
+// NodeResult is the result of a single node, sent as soon as its checks finish.
+// Err is set instead of Result when the node could not be checked.
+type NodeResult struct {
+	Name   string
+	Result Result
+	Err    error
+}
+
+// runChecks port-forwards every node and sends the result of its checks to results,
+// closing results once every node is done.
+// basePort is the local port of a single node, several nodes use the ports after it.
+func (c *Checker) runChecks(ctx context.Context, nodes map[string]config.Node, basePort int, results chan<- NodeResult) {
+	// Create a wait group to ensure all port forwards are removed
+	var wg sync.WaitGroup
+	all := len(nodes) > 1
+	localPortCounter := 1
+	refs := newReferenceHeads(c.References)
+	refs.proxyErrors = configureReferenceProxies(c.Config.PublicApis)
+
+	// Iterate over nodes and perform checks
+	for nodeName, node := range nodes {
+		wg.Add(1)
+
+		lp := basePort
+		if all {
+			lp += localPortCounter
+			localPortCounter++
+		}
+
+		go func(nodeName string, node config.Node, localPort int) {
+			defer wg.Done()
+
+			ctx, span := tracer.Start(ctx, "check", trace.WithAttributes(
+				attribute.String("node", nodeName), attribute.String("chain", node.ChainName(nodeName))))
+			defer span.End()
+
+			errs := &errorLog{}
+			node, kube, closeForward, err := connectNode(ctx, c.Kube, nodeName, node, localPort, errs)
+			if err != nil {
+				recordError(span, err)
+				results <- NodeResult{Name: nodeName, Err: err}
+				return
+			}
+			// Remove port forward, as soon as ctx is done if the checks are still running by then
+			stop := context.AfterFunc(ctx, closeForward)
+			defer func() {
+				if stop() {
+					closeForward()
+				}
+			}()
+
+			res, err := c.checkNode(ctx, kube, refs, nodeName, node, localPort, errs)
+			if err != nil {
+				// The port forward may have failed the checks, its errors explain them
+				if forwardErrs := errs.list(); len(forwardErrs) > 0 {
+					err = fmt.Errorf("%v (%s)", err, strings.Join(forwardErrs, "; "))
+				}
+				recordError(span, err)
+				results <- NodeResult{Name: nodeName, Err: err}
+				return
+			}
+			if kube != nil {
+				res.Cluster = kube.Context
+			}
+			res.Errors = errs.list()
+			res.Assertions = evaluateAssertions(append(slices.Clip(c.Config.Assertions), node.Assertions...), res)
+			results <- NodeResult{Name: nodeName, Result: res}
+		}(nodeName, node, lp)
+	}
+
+	wg.Wait()
+	close(results)
+}
+
+// connectNode makes node reachable through localPort. Nodes with a direct URL are queried as is,
+// nodes with an SSH section through an SSH tunnel, nodes in Docker containers on their published ports,
+// nodes with an IPC socket through calls piped into their pod, in-cluster, services are reached
+// through the cluster DNS and otherwise port-forwarded.
+// It returns the node to query, its Kubernetes client if any and the function removing the port forward.
+// The errors of the port forward or SSH tunnel while it is open are written to errOut.
+func connectNode(ctx context.Context, kubes *forward.KubeClients, nodeName string, node config.Node, localPort int, errOut io.Writer) (config.Node, *forward.KubeClient, func(), error) {
+	node, kube, closeForward, err := forwardNode(ctx, kubes, nodeName, node, localPort, errOut)
+	if err != nil {
+		return node, nil, nil, err
+	}
+	if rpcURL := rpc.NodeURL(node, localPort); rpc.IsWebSocket(rpcURL) {
+		// Close the connection along with the port forward it may go through, the next run reopens it
+		closeForwardOnly := closeForward
+		closeForward = func() {
+			rpc.CloseWebSocket(rpcURL)
+			closeForwardOnly()
+		}
+	}
+	if node.TLS != nil || node.Proxy != "" {
+		settings := rpc.HostSettings{TLS: node.TLS, Proxy: node.Proxy}
+		if err := rpc.ConfigureHost(rpc.NodeURL(node, localPort), settings); err != nil {
+			closeForward()
+			return node, nil, nil, fmt.Errorf("configuring connections to %s: %v", nodeName, err)
+		}
+	}
+	return node, kube, closeForward, nil
+}
+
+// forwardNode reaches node as connectNode does, without its TLS and proxy settings
+func forwardNode(ctx context.Context, kubes *forward.KubeClients, nodeName string, node config.Node, localPort int, errOut io.Writer) (config.Node, *forward.KubeClient, func(), error) {
+	if node.URL != "" {
+		return node, nil, func() {}, nil
+	}
+	if node.SSH != nil {
+		return tunnelNode(ctx, nodeName, node, localPort, errOut)
+	}
+	if node.Docker != nil {
+		node, err := forward.DockerNode(ctx, node)
+		if err != nil {
+			return node, nil, nil, fmt.Errorf("finding Docker container of %s: %v", nodeName, err)
+		}
+		return node, nil, func() {}, nil
+	}
+	if node.Teleport != nil {
+		return teleportNode(ctx, kubes, nodeName, node, localPort, errOut)
+	}
+	kube, err := kubes.Get(node)
+	if err != nil {
+		return node, nil, nil, fmt.Errorf("creating Kubernetes client for %s: %v", nodeName, err)
+	}
+	if node.IPC != nil {
+		return serveIPC(ctx, kube, nodeName, node, localPort, errOut)
+	}
+	if kube.InCluster {
+		return forward.ClusterDNSNode(node), kube, func() {}, nil
+	}
+
+	ports := append([]string{fmt.Sprintf("%d:%d", localPort, node.Port)}, endpointPorts(node, localPort)...)
+	pf, err := forwardPorts(ctx, kube, nodeName, node, ports, errOut)
+	if err != nil {
+		return node, nil, nil, err
+	}
+	return node, kube, pf.Close, nil
+}
+
+// serveIPC reaches node over its IPC socket through localPort, its additional endpoints as forwardNode does
+func serveIPC(ctx context.Context, kube *forward.KubeClient, nodeName string, node config.Node, localPort int, errOut io.Writer) (config.Node, *forward.KubeClient, func(), error) {
+	closeEndpoints := func() {}
+	if kube.InCluster {
+		node = forward.ClusterDNSNode(node)
+		node.URL = ""
+	} else if len(node.Endpoints) > 0 {
+		pf, err := forwardPorts(ctx, kube, nodeName, node, endpointPorts(node, localPort), errOut)
+		if err != nil {
+			return node, nil, nil, err
+		}
+		closeEndpoints = pf.Close
+	}
+
+	bridge, err := kube.ServeIPC(ctx, node.Namespace, node.Service, *node.IPC, localPort)
+	if err != nil {
+		closeEndpoints()
+		return node, nil, nil, fmt.Errorf("serving IPC socket of %s: %v", nodeName, err)
+	}
+	return node, kube, func() {
+		bridge.Close()
+		closeEndpoints()
+	}, nil
+}
+
+// tunnelNode reaches the port and the additional endpoints of node through its SSH tunnel
+func tunnelNode(ctx context.Context, nodeName string, node config.Node, localPort int, errOut io.Writer) (config.Node, *forward.KubeClient, func(), error) {
+	ports := append([]string{fmt.Sprintf("%d:%d", localPort, node.Port)}, endpointPorts(node, localPort)...)
+	tunnel, err := forward.ForwardSSH(ctx, *node.SSH, ports, &prefixWriter{prefix: "SSH tunnel: ", out: errOut})
+	if err != nil {
+		return node, nil, nil, fmt.Errorf("opening SSH tunnel to %s for %s: %v", node.SSH.Host, nodeName, err)
+	}
+	return node, nil, tunnel.Close, nil
+}
+
+// teleportNode reaches node as forwardNode does, through the kubeconfig of a tsh proxy for its cluster
+func teleportNode(ctx context.Context, kubes *forward.KubeClients, nodeName string, node config.Node, localPort int, errOut io.Writer) (config.Node, *forward.KubeClient, func(), error) {
+	conf := *node.Teleport
+	if conf.KubeCluster == "" {
+		conf.KubeCluster = node.Context
+	}
+	kubeconfig, release, err := forward.TeleportKubeconfig(ctx, conf)
+	if err != nil {
+		return node, nil, nil, fmt.Errorf("starting tsh proxy kube for %s: %v", nodeName, err)
+	}
+	// The kubeconfig of the proxy selects the cluster by its current context
+	node.Kubeconfig, node.Context, node.Teleport = kubeconfig, "", nil
+	// The next run may get another proxy port and kubeconfig, the client of this one is dropped with it
+	proxied := node
+	node, kube, closeForward, err := forwardNode(ctx, kubes, nodeName, node, localPort, errOut)
+	if err != nil {
+		kubes.Forget(proxied)
+		release()
+		return node, nil, nil, err
+	}
+	return node, kube, func() {
+		closeForward()
+		kubes.Forget(proxied)
+		release()
+	}, nil
+}
+
+// endpointPorts returns the "local:remote" port pairs of the additional endpoints of node
+func endpointPorts(node config.Node, localPort int) []string {
+	ports := make([]string, 0, len(node.Endpoints))
+	for i, endpoint := range node.Endpoints {
+		ports = append(ports, fmt.Sprintf("%d:%d", endpointLocalPort(localPort, i), endpoint.Port))
+	}
+	return ports
+}
+
+// forwardPorts port-forwards the "local:remote" port pairs to the service of node
+func forwardPorts(ctx context.Context, kube *forward.KubeClient, nodeName string, node config.Node, ports []string, errOut io.Writer) (*forward.PortForward, error) {
+	_, fwdSpan := tracer.Start(ctx, "port-forward", trace.WithAttributes(
+		attribute.String("namespace", node.Namespace), attribute.String("service", node.Service)))
+	pf, err := kube.ForwardService(ctx, node.Namespace, node.Service, ports, &prefixWriter{prefix: "port forward: ", out: errOut})
+	endSpan(fwdSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("starting port forward for %s: %v", nodeName, err)
+	}
+	return pf, nil
+}
+
+// checkNode performs all checks of a single node through its forwarded local port,
+// the reference head of its chain is shared through refs with the other nodes of the run.
+// The optional checks which fail record their error to errs and leave their part of the result empty.
+func (c *Checker) checkNode(ctx context.Context, kube *forward.KubeClient, refs *referenceHeads, nodeName string, node config.Node, localPort int, errs *errorLog) (Result, error) {
+	cfg, hold := c.Config, c.Hold
+	chain := node.ChainName(nodeName)
+
+	// Long-lived WebSocket stability test, runs for the whole daemon interval
+	var wsDone chan *WSStability
+	if hold > 0 && findEndpoint(node, config.EndpointWS) >= 0 {
+		wsDone = make(chan *WSStability, 1)
+		go func() {
+			wsDone <- monitorWSStability(ctx, node, localPort, hold)
+		}()
+	}
+
+	// newHeads cadence, watched alongside the other checks for its window
+	var cadenceDone chan *HeadCadence
+	if node.HeadCadence != nil {
+		if url, auth, ok := newHeadsURL(node, localPort); ok {
+			cadenceDone = make(chan *HeadCadence, 1)
+			go func() {
+				cadenceDone <- watchHeadCadence(ctx, url, auth, *node.HeadCadence)
+			}()
+		}
+	}
+
+	adapter, err := adapterFor(node)
+	if err != nil {
+		return Result{}, err
+	}
+	// Time every call to the node
+	ctx, latencies := rpc.WithLatencies(ctx, rpc.NodeURL(node, localPort))
+
+	// Query the core checks in a single round trip
+	if batched, ok := adapter.(batchedAdapter); ok {
+		ctx = rpc.Prefetch(ctx, node, localPort, batched.batchMethods()...)
+	}
+
+	var peersCount *int64
+	if count, ok, err := adapter.PeerCount(ctx, node, localPort); err != nil {
+		return Result{}, fmt.Errorf("getting peers count: %v", err)
+	} else if ok {
+		peersCount = &count
+	}
+
+	// Peers by direction and client when the admin namespace is exposed
+	var peerSummary *PeerSummary
+	if node.IsEVM() && peersCount != nil {
+		var rpcErr *rpc.RPCError
+		peerSummary, err = checkPeerSummary(ctx, node, localPort, c.Options.PeerDetails)
+		if err != nil && !errors.As(err, &rpcErr) {
+			errs.add("getting peer details: %v", err)
+		}
+	}
+
+	// Static and trusted peers verification
+	var missingStatic, missingTrusted []string
+	if len(node.StaticPeers) > 0 || len(node.TrustedPeers) > 0 {
+		missingStatic, missingTrusted, err = checkPeering(ctx, node, localPort)
+		if err != nil {
+			errs.add("verifying static/trusted peers: %v", err)
+		}
+	}
+
+	// Bootnode connectivity tests
+	var bootnodeFailures []string
+	if len(node.Bootnodes) > 0 && kube != nil {
+		bootnodeFailures, err = checkBootnodes(ctx, kube, node)
+		if err != nil {
+			errs.add("probing bootnodes: %v", err)
+		}
+	}
+
+	// External P2P reachability check
+	p2pReachability := ""
+	if node.P2PReachability {
+		addr, err := p2pAddress(ctx, node, localPort)
+		if err != nil {
+			errs.add("getting P2P address: %v", err)
+		} else if err := checkP2PReachability(ctx, addr, cfg.P2PProbeURL); err != nil {
+			p2pReachability = fmt.Sprintf("unreachable (%v)", err)
+		} else {
+			p2pReachability = "reachable"
+		}
+	}
+
+	// Enode advertisement sanity check
+	advertisementIssue := ""
+	if node.ExternalAddress != "" {
+		advertisementIssue, err = checkAdvertisement(ctx, node, localPort)
+		if err != nil {
+			errs.add("checking enode advertisement: %v", err)
+		}
+	}
+
+	// Peer discovery health metrics
+	var discovery *DiscoveryStats
+	if node.Discovery != nil {
+		discovery, err = checkDiscovery(ctx, node, localPort)
+		if err != nil {
+			errs.add("getting discovery metrics: %v", err)
+		}
+	}
+
+	// Peer geography and client-diversity breakdown
+	var diversity *PeerDiversity
+	if node.PeerDiversity {
+		diversity, err = checkPeerDiversity(ctx, node, localPort, cfg.GeoIPURL)
+		if err != nil {
+			errs.add("getting peer diversity: %v", err)
+		}
+	}
+
+	// Pending transaction gossip check
+	var txGossip *TxGossip
+	if node.TxGossipWindow > 0 {
+		txGossip, err = checkTxGossip(ctx, node, localPort, node.TxGossipWindow)
+		if err != nil {
+			errs.add("sampling pending transactions: %v", err)
+		}
+	}
+
+	currentNodeBlockNum, err := adapter.Head(ctx, node, localPort)
+	if err != nil {
+		return Result{}, fmt.Errorf("getting latest block: %v", err)
+	}
+
+	// Age of the head block, comparable across chains with different block times
+	var headAge time.Duration
+	if node.IsEVM() {
+		headAge, err = checkHeadAge(ctx, node, localPort)
+		if err != nil {
+			errs.add("getting head block age: %v", err)
+		}
+	}
+
+	// Client version, shared by the fork, release and security advisories
+	forks := upcomingForks(cfg.Forks[chain], currentNodeBlockNum)
+	var clientVersion ClientVersion
+	var clientVersionErr error
+	if len(forks) > 0 || node.ReleaseCheck || cfg.AdvisoryFeed != "" {
+		clientVersion, clientVersionErr = fetchClientVersion(ctx, node, localPort)
+		if clientVersionErr != nil {
+			errs.add("getting client version: %v", clientVersionErr)
+		}
+	}
+
+	// Fork-ID and network upgrade readiness check
+	var forkReadiness []ForkReadiness
+	var advisories []ForkAdvisory
+	if len(forks) > 0 {
+		forkReadiness, err = checkForkReadiness(ctx, node, localPort, forks)
+		if err != nil {
+			errs.add("checking fork readiness: %v", err)
+		}
+
+		// Scheduled hardfork countdown and advisory
+		advisories = forkAdvisories(forks, currentNodeBlockNum, clientVersion)
+	}
+
+	// Extra checks declared in the config
+	var customChecks []CustomCheckResult
+	if len(node.Checks) > 0 {
+		customChecks = runCustomChecks(ctx, node, localPort)
+	}
+
+	// Historical state availability for archive nodes
+	var archive *ArchiveState
+	if node.Archive != nil {
+		archive = checkArchiveState(ctx, node, localPort, *node.Archive)
+	}
+
+	// Trace-block benchmark for archive nodes
+	var traceBenchmark *TraceBenchmark
+	if node.Trace != nil {
+		traceBenchmark = benchmarkTrace(ctx, node, localPort, *node.Trace, currentNodeBlockNum)
+	}
+
+	// Distance of the finalized block from head
+	var finality *Finality
+	if node.FinalityCheck {
+		finality, err = checkFinality(ctx, node, localPort, currentNodeBlockNum)
+		if err != nil {
+			errs.add("checking finality: %v", err)
+		}
+	}
+
+	// Gas price and base fee compared with the reference
+	var gasPrice *GasPrice
+	if node.GasPriceCheck {
+		gasPrice, err = checkGasPrice(ctx, node, localPort, cfg.PublicApis[chain])
+		if err != nil {
+			errs.add("checking gas price: %v", err)
+		}
+	}
+
+	// Transaction pool size
+	var txPool *TxPoolStatus
+	if node.TxPool != nil {
+		txPool, err = checkTxPool(ctx, node, localPort, *node.TxPool)
+		if err != nil {
+			errs.add("getting txpool status: %v", err)
+		}
+	}
+
+	// getLogs correctness cross-check
+	var logs *LogsComparison
+	if node.LogsCheck != nil {
+		logs, err = checkLogs(ctx, node, localPort, cfg.PublicApis[chain], *node.LogsCheck, currentNodeBlockNum)
+		if err != nil {
+			errs.add("cross-checking logs: %v", err)
+		}
+	}
+
+	// getLogs latency benchmark
+	var logsBenchmark *LogsBenchmark
+	if node.LogsBenchmark != nil {
+		logsBenchmark = benchmarkLogs(ctx, node, localPort, *node.LogsBenchmark, currentNodeBlockNum)
+	}
+
+	// Recent receipts availability check
+	var receipts *ReceiptsAvailability
+	if node.ReceiptsCheckBlocks > 0 {
+		receipts, err = checkReceipts(ctx, node, localPort, currentNodeBlockNum, node.ReceiptsCheckBlocks)
+		if err != nil {
+			errs.add("checking receipts: %v", err)
+		}
+	}
+
+	// eth_feeHistory correctness probe
+	var feeHistoryProblems []string
+	if node.FeeHistoryCheck {
+		feeHistoryProblems, err = checkFeeHistory(ctx, node, localPort, cfg.PublicApis[chain])
+		if err != nil {
+			feeHistoryProblems = []string{err.Error()}
+		}
+	}
+
+	// Transaction inclusion latency probe
+	var inclusion *InclusionLatency
+	if canary, ok := cfg.CanaryAccounts[chain]; ok {
+		inclusion, err = checkInclusionLatency(ctx, chain, node, localPort, canary)
+		if err != nil {
+			errs.add("probing inclusion latency: %v", err)
+		}
+	}
+
+	refCtx, refSpan := tracer.Start(ctx, "reference head")
+	reference, err := refs.get(refCtx, chain, adapter, cfg.PublicApis[chain])
+	endSpan(refSpan, err)
+	if err != nil {
+		return Result{}, fmt.Errorf("getting latest block from scanner: %v", err)
+	}
+	latestBlock := reference.head
+	for _, skipped := range reference.skipped {
+		errs.add("getting reference head from %s", skipped)
+	}
+	for _, proxyErr := range refs.proxyErrors[chain] {
+		errs.add("%s", proxyErr)
+	}
+
+	// Get sync status, keeping the eth_syncing answer for the progress reporting
+	var syncStatus string
+	var syncing interface{}
+	if s, ok := adapter.(syncingAdapter); ok {
+		syncStatus, syncing, err = s.syncing(ctx, node, localPort, latestBlock)
+	} else {
+		syncStatus, err = adapter.SyncStatus(ctx, node, localPort, latestBlock)
+	}
+	if err != nil {
+		errs.add("determining sync status: %v", err)
+	}
+	if syncStatus == "synced" && node.MaxBlockLag > 0 && latestBlock-currentNodeBlockNum > node.MaxBlockLag {
+		syncStatus = "behind"
+	}
+	if syncStatus == "synced" && node.MinPeers > 0 && peersCount != nil && *peersCount < node.MinPeers {
+		syncStatus = "degraded"
+	}
+
+	// eth_syncing progress and geth state-healing progress reporting
+	var progress *SyncProgress
+	var healing *HealProgress
+	if syncing != nil {
+		progress = syncProgress(syncing)
+		healing = checkHealing(ctx, node, localPort, syncing)
+	}
+	if healing != nil {
+		syncStatus = "healing"
+	}
+
+	// Erigon staged-sync progress breakdown
+	var stagedSync *StagedSync
+	if node.IsEVM() && syncStatus != "synced" {
+		stagedSync = checkStagedSync(ctx, node, localPort)
+	}
+
+	// Sync speed and time to catch up with the reference
+	var syncETA *SyncETA
+	if syncStatus == "syncing" || syncStatus == "behind" {
+		syncETA, err = estimateSyncETA(ctx, adapter, node, localPort, cfg.PublicApis[chain])
+		if err != nil {
+			errs.add("estimating sync ETA: %v", err)
+		}
+	}
+
+	// Block hash cross-verification with reference, below both heads so the block exists on each side
+	hashMismatch := ""
+	if cfg.PublicApis[chain].RPCURL != "" && node.IsEVM() {
+		hashMismatch, err = checkBlockHash(ctx, node, localPort, cfg.PublicApis[chain], min(currentNodeBlockNum, latestBlock), node.HashCheckDepth)
+		if err != nil {
+			errs.add("cross-verifying block hash: %v", err)
+		}
+		if hashMismatch != "" {
+			syncStatus = "forked"
+		}
+	}
+
+	// Base fee and gas limit trend sanity checks, below both heads so the blocks exist on each side
+	var feeTrend *FeeTrend
+	if node.FeeTrendBlocks > 0 {
+		feeTrend, err = checkFeeTrend(ctx, node, localPort, cfg.PublicApis[chain], min(currentNodeBlockNum, latestBlock), node.FeeTrendBlocks)
+		if err != nil {
+			errs.add("checking fee trend: %v", err)
+		}
+	}
+
+	// Additional endpoints are checked in the same pass
+	var endpoints []EndpointStatus
+	if len(node.Endpoints) > 0 {
+		endpoints = checkEndpoints(ctx, node, localPort)
+	}
+
+	// Consensus client checks through the beacon endpoint
+	var consensus *BeaconStatus
+	if findEndpoint(node, config.EndpointBeacon) >= 0 {
+		consensus, err = checkBeacon(ctx, node, localPort)
+		if err != nil {
+			errs.add("checking consensus client: %v", err)
+		}
+	}
+
+	// Selected series of the node's own Prometheus metrics
+	var metrics map[string]float64
+	if len(node.MetricsSeries) > 0 {
+		metrics, err = scrapeMetrics(ctx, node, localPort)
+		if err != nil {
+			errs.add("scraping metrics: %v", err)
+		}
+	}
+
+	// Pod log error-pattern scanning
+	var logMatches []LogMatch
+	if node.LogScan != nil && kube != nil {
+		logMatches, err = scanPodLogs(ctx, kube, node, *node.LogScan)
+		if err != nil {
+			errs.add("scanning pod logs: %v", err)
+		}
+	}
+
+	// Client release update advisory
+	var releaseAdvisory *ReleaseAdvisory
+	if node.ReleaseCheck && clientVersionErr == nil {
+		releaseAdvisory, err = checkReleases(ctx, node, clientVersion)
+		if err != nil {
+			errs.add("checking client releases: %v", err)
+		}
+	}
+
+	// Security advisory matching for client versions
+	var securityAdvisories []Advisory
+	if cfg.AdvisoryFeed != "" {
+		feed, err := loadAdvisoryFeed(ctx, cfg.AdvisoryFeed)
+		if err != nil {
+			errs.add("loading advisory feed: %v", err)
+		} else if clientVersionErr == nil {
+			securityAdvisories = matchAdvisories(feed, clientVersion)
+		}
+	}
+	if syncStatus == "synced" && slices.ContainsFunc(securityAdvisories, Advisory.Critical) {
+		syncStatus = "vulnerable"
+	}
+
+	var headCadence *HeadCadence
+	if cadenceDone != nil {
+		headCadence = <-cadenceDone
+	}
+
+	var wsStability *WSStability
+	var dropRate *SubscriptionDropRate
+	if wsDone != nil {
+		wsStability = <-wsDone
+
+		// Subscription drop-rate metric accumulated over the daemon lifetime
+		rate := recordSubscriptionDrops(nodeName, wsStability)
+		dropRate = &rate
+	}
+
+	return Result{
+		Chain:            chain,
+		SyncStatus:       syncStatus,
+		NodeBlockNum:     currentNodeBlockNum,
+		LatestBlockNum:   latestBlock,
+		ReferenceAge:     reference.age,
+		ReferenceSource:  reference.source,
+		ReferenceLatency: reference.latency,
+		RPCLatency:       latencies.Methods(),
+		Diff:             latestBlock - currentNodeBlockNum,
+		PeersCount:       peersCount,
+		Peers:            peerSummary,
+
+		MissingStaticPeers:  missingStatic,
+		MissingTrustedPeers: missingTrusted,
+		BootnodeFailures:    bootnodeFailures,
+		P2PReachability:     p2pReachability,
+		AdvertisementIssue:  advertisementIssue,
+		Discovery:           discovery,
+		PeerDiversity:       diversity,
+		ForkReadiness:       forkReadiness,
+		ForkAdvisories:      advisories,
+		FeeTrend:            feeTrend,
+		TxGossip:            txGossip,
+		InclusionLatency:    inclusion,
+		FeeHistoryProblems:  feeHistoryProblems,
+		Archive:             archive,
+		Checks:              customChecks,
+		TraceBenchmark:      traceBenchmark,
+		TxPool:              txPool,
+		GasPrice:            gasPrice,
+		Finality:            finality,
+		Logs:                logs,
+		LogsBenchmark:       logsBenchmark,
+		BlockHashMismatch:   hashMismatch,
+		Receipts:            receipts,
+		HeadAge:             headAge,
+		SyncProgress:        progress,
+		Healing:             healing,
+		StagedSync:          stagedSync,
+		SyncETA:             syncETA,
+		HeadCadence:         headCadence,
+		WSStability:         wsStability,
+		SubscriptionDrops:   dropRate,
+		Endpoints:           endpoints,
+		Consensus:           consensus,
+		Metrics:             metrics,
+		LogMatches:          logMatches,
+		Release:             releaseAdvisory,
+		SecurityAdvisories:  securityAdvisories,
+	}, nil
+}
@@ -0,0 +1,188 @@
+// Package checker checks the sync status and health of blockchain nodes.
+package checker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/forward"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// DefaultPort is the first local port of the port forwards
+const DefaultPort = 8080
+
+// Options configure the checks of a Checker and how their results are written
+type Options struct {
+	// RPC configures the JSON-RPC and HTTP API calls of the checks.
+	// Its Verbose level also adds the reference and RPC latencies to the text output.
+	RPC rpc.Options
+	// PeerDetails lists every connected peer in the results of the nodes exposing admin_peers
+	PeerDetails bool
+	// Quiet limits the output to the nodes needing attention: not synced, behind or failing their checks.
+	// The Nagios and InfluxDB outputs are not filtered, they report every node.
+	Quiet bool
+	// NoColor disables the colors of the table output, which are also off when it isn't written to a terminal or NO_COLOR is set
+	NoColor bool
+	// Nagios are the thresholds of the Nagios output, DefaultNagiosThresholds if zero
+	Nagios NagiosThresholds
+}
+
+// Checker runs the checks of a set of nodes
+type Checker struct {
+	// Config is the loaded configuration, Nodes the nodes to check (all configured nodes if nil)
+	Config config.NodeConfig
+	Nodes  map[string]config.Node
+	// Kube caches the Kubernetes clients of the nodes, created on first Run if nil
+	Kube *forward.KubeClients
+	// Port is the first local port of the port forwards, DefaultPort if 0.
+	// A single node is forwarded to Port, several nodes to the ports after it.
+	Port int
+	// Hold keeps the WebSocket stability subscription open for that long, 0 skips the test
+	Hold time.Duration
+	// OnResult, if set, is called with the result of every node as soon as its checks finish.
+	// Calls are never concurrent.
+	OnResult func(nodeName string, res Result)
+	// OnError, if set, is called with the error of every node that could not be checked, which has no result,
+	// and with the errors of RunCanaries. Calls are never concurrent.
+	OnError func(nodeName string, err error)
+	// References keeps the reference heads across runs, created on first Run if nil
+	References *ReferenceCache
+	// Breaker, if set, skips the nodes marked down by repeated failures until their next probe
+	Breaker *Breaker
+	// Canaries stores the runs of RunCanaries, kept in memory if nil
+	Canaries CanaryStore
+	// Options configure the checks and the output
+	Options Options
+
+	// errorMu serializes the calls to OnError of Run and RunCanaries
+	errorMu sync.Mutex
+}
+
+// New returns a Checker of the nodes, all configured nodes if nodes is nil,
+// whose calls follow the rpc_timeout and retry of cfg
+func New(cfg config.NodeConfig, nodes map[string]config.Node) *Checker {
+	if nodes == nil {
+		nodes = cfg.Nodes
+	}
+	return &Checker{
+		Config:     cfg,
+		Nodes:      nodes,
+		Kube:       forward.NewKubeClients(),
+		References: newReferenceCache(cfg),
+		Canaries:   newMemoryCanaryStore(),
+		Options:    Options{RPC: rpc.Options{Timeout: cfg.RPCTimeout, Retry: cfg.Retry}},
+	}
+}
+
+// newReferenceCache returns the reference cache of the configured TTL
+func newReferenceCache(cfg config.NodeConfig) *ReferenceCache {
+	ttl := cfg.ReferenceTTL
+	if ttl == 0 {
+		ttl = DefaultReferenceTTL
+	}
+	return NewReferenceCache(ttl)
+}
+
+// Run checks every node and returns the results of the nodes whose checks succeeded.
+// When ctx is done first, the port forwards of the run are removed and ctx.Err() is returned.
+func (c *Checker) Run(ctx context.Context) (map[string]Result, error) {
+	c.init()
+	nodes := c.nodes()
+	port := c.port()
+	if c.Breaker != nil {
+		nodes = c.allowed(nodes, time.Now())
+	}
+
+	// Buffered so checks still running after ctx is done never block
+	stream := make(chan NodeResult, len(nodes))
+	go c.runChecks(rpc.WithOptions(ctx, c.Options.RPC), nodes, port, stream)
+
+	results := make(map[string]Result, len(nodes))
+	for {
+		select {
+		case nodeResult, ok := <-stream:
+			if !ok {
+				if c.Breaker != nil {
+					now := time.Now()
+					for nodeName := range nodes {
+						_, passed := results[nodeName]
+						c.Breaker.Record(nodeName, passed, now)
+					}
+				}
+				return results, nil
+			}
+			if nodeResult.Err != nil {
+				c.reportError(nodeResult.Name, nodeResult.Err)
+				continue
+			}
+			node := nodes[nodeResult.Name]
+			if canaries := c.Config.Canaries[node.ChainName(nodeResult.Name)]; len(canaries) > 0 {
+				slis, err := canarySLIs(c.Canaries, canaries, nodeResult.Name)
+				if err != nil {
+					nodeResult.Result.Errors = append(nodeResult.Result.Errors, fmt.Sprintf("reading canary runs: %v", err))
+				}
+				nodeResult.Result.Canaries = slis
+			}
+			results[nodeResult.Name] = nodeResult.Result
+			if c.OnResult != nil {
+				c.OnResult(nodeResult.Name, nodeResult.Result)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reportError passes the error of the node to OnError, if set
+func (c *Checker) reportError(nodeName string, err error) {
+	if c.OnError == nil {
+		return
+	}
+	c.errorMu.Lock()
+	defer c.errorMu.Unlock()
+	c.OnError(nodeName, err)
+}
+
+// init creates the caches left nil
+func (c *Checker) init() {
+	if c.Kube == nil {
+		c.Kube = forward.NewKubeClients()
+	}
+	if c.References == nil {
+		c.References = newReferenceCache(c.Config)
+	}
+	if c.Canaries == nil {
+		c.Canaries = newMemoryCanaryStore()
+	}
+}
+
+// nodes returns the nodes to check, all configured nodes if c.Nodes is nil
+func (c *Checker) nodes() map[string]config.Node {
+	if c.Nodes == nil {
+		return c.Config.Nodes
+	}
+	return c.Nodes
+}
+
+// port returns the first local port of the port forwards
+func (c *Checker) port() int {
+	if c.Port == 0 {
+		return DefaultPort
+	}
+	return c.Port
+}
+
+// allowed returns the nodes the breaker lets through at now
+func (c *Checker) allowed(nodes map[string]config.Node, now time.Time) map[string]config.Node {
+	allowed := make(map[string]config.Node, len(nodes))
+	for nodeName, node := range nodes {
+		if c.Breaker.Allow(nodeName, now) {
+			allowed[nodeName] = node
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// tendermintStatus is the part of the Tendermint /status response nodestat uses
+type tendermintStatus struct {
+	SyncInfo struct {
+		LatestBlockHeight string `json:"latest_block_height"`
+		CatchingUp        bool   `json:"catching_up"`
+	} `json:"sync_info"`
+}
+
+// cosmosAdapter serves Tendermint/CometBFT based chains (Cosmos Hub, Osmosis, ...).
+// The reference head comes from a public Tendermint RPC (rpc_url) or an LCD endpoint (url).
+type cosmosAdapter struct{}
+
+func (cosmosAdapter) SyncStatus(ctx context.Context, node config.Node, localPort int, referenceHead int64) (string, error) {
+	var status tendermintStatus
+	if err := fetchTendermint(ctx, rpc.NodeURL(node, localPort), "/status", &status); err != nil {
+		return "", err
+	}
+	if status.SyncInfo.CatchingUp {
+		return "syncing", nil
+	}
+	return "synced", nil
+}
+
+func (cosmosAdapter) Head(ctx context.Context, node config.Node, localPort int) (int64, error) {
+	return fetchTendermintHeight(ctx, rpc.NodeURL(node, localPort))
+}
+
+func (cosmosAdapter) PeerCount(ctx context.Context, node config.Node, localPort int) (int64, bool, error) {
+	var netInfo struct {
+		NPeers string `json:"n_peers"`
+	}
+	if err := fetchTendermint(ctx, rpc.NodeURL(node, localPort), "/net_info", &netInfo); err != nil {
+		return 0, false, err
+	}
+	count, err := strconv.ParseInt(netInfo.NPeers, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
+func (cosmosAdapter) ReferenceHead(ctx context.Context, apiConf config.PublicAPI) (int64, error) {
+	if apiConf.RPCURL != "" {
+		return fetchTendermintHeight(ctx, apiConf.RPCURL)
+	}
+	if apiConf.URL == "" {
+		return 0, fmt.Errorf("no reference endpoint configured")
+	}
+
+	// LCD (REST) endpoint
+	resp, err := httpGet(ctx, strings.TrimSuffix(apiConf.URL, "/")+"/cosmos/base/tendermint/v1beta1/blocks/latest")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var latest struct {
+		Block struct {
+			Header struct {
+				Height string `json:"height"`
+			} `json:"header"`
+		} `json:"block"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(latest.Block.Header.Height, 10, 64)
+}
+
+func fetchTendermintHeight(ctx context.Context, baseURL string) (int64, error) {
+	var status tendermintStatus
+	if err := fetchTendermint(ctx, baseURL, "/status", &status); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+}
+
+// fetchTendermint performs a GET request against the Tendermint RPC and decodes its result field
+func fetchTendermint(ctx context.Context, baseURL string, path string, out interface{}) error {
+	resp, err := httpGet(ctx, strings.TrimSuffix(baseURL, "/")+path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	if len(body.Result) == 0 {
+		return fmt.Errorf("no result in %s response", path)
+	}
+	return json.Unmarshal(body.Result, out)
+}
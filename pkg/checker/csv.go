@@ -0,0 +1,47 @@
+package checker
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// csvHeader is the header row of the CSV output
+var csvHeader = []string{"timestamp", "node", "chain", "sync_status", "node_block", "reference_block", "diff", "peers"}
+
+// writeCSV writes a header and one row per node, all stamped with the time of writing.
+// Nodes whose checks failed are written as unreachable with empty numbers.
+func writeCSV(out io.Writer, nodes map[string]config.Node, results map[string]Result) error {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	w := csv.NewWriter(out)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+
+	nodeNames := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	for _, nodeName := range nodeNames {
+		row := []string{timestamp, nodeName, nodes[nodeName].ChainName(nodeName), statusUnreachable, "", "", "", ""}
+		if res, ok := results[nodeName]; ok {
+			row[3] = res.SyncStatus
+			row[4] = strconv.FormatInt(res.NodeBlockNum, 10)
+			row[5] = strconv.FormatInt(res.LatestBlockNum, 10)
+			row[6] = strconv.FormatInt(res.Diff, 10)
+			if res.PeersCount != nil {
+				row[7] = strconv.FormatInt(*res.PeersCount, 10)
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// CustomCheckResult represents the outcome of a custom check declared in the config
+type CustomCheckResult struct {
+	Name     string `json:"name" yaml:"name"`
+	Severity string `json:"severity" yaml:"severity"`
+	Passed   bool   `json:"passed" yaml:"passed"`
+	// Value is the result of the call, or the value selected by the check path
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	Error string      `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// runCustomChecks runs the custom checks of the node in order
+func runCustomChecks(ctx context.Context, node config.Node, localPort int) []CustomCheckResult {
+	results := make([]CustomCheckResult, 0, len(node.Checks))
+	for _, check := range node.Checks {
+		results = append(results, runCustomCheck(ctx, node, localPort, check))
+	}
+	return results
+}
+
+func runCustomCheck(ctx context.Context, node config.Node, localPort int, check config.CustomCheck) CustomCheckResult {
+	res := CustomCheckResult{Name: check.Name, Severity: check.Severity}
+	if res.Severity == "" {
+		res.Severity = config.SeverityWarning
+	}
+
+	params := make([]interface{}, len(check.Params))
+	for i, param := range check.Params {
+		params[i] = jsonValue(param)
+	}
+	result, err := rpc.Call(ctx, node, localPort, check.Method, params...)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Value = result
+	if check.Path != "" {
+		res.Value, err = selectPath(check.Path, result)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+	}
+
+	if check.Expect == nil {
+		res.Passed = true
+		return res
+	}
+	expected, err := json.Marshal(jsonValue(check.Expect))
+	if err != nil {
+		res.Error = fmt.Sprintf("invalid expected value: %v", err)
+		return res
+	}
+	actual, err := json.Marshal(res.Value)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Passed = bytes.Equal(expected, actual)
+	if !res.Passed {
+		res.Error = fmt.Sprintf("expected %s, got %s", expected, actual)
+	}
+	return res
+}
+
+// selectPath returns the value of the JSONPath expression in result, braces around the expression are optional
+func selectPath(path string, result interface{}) (interface{}, error) {
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	parser := jsonpath.New("check")
+	if err := parser.Parse(path); err != nil {
+		return nil, fmt.Errorf("invalid path %s: %v", path, err)
+	}
+	matches, err := parser.FindResults(result)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 || len(matches[0]) == 0 {
+		return nil, fmt.Errorf("path %s matches nothing", path)
+	}
+	return matches[0][0].Interface(), nil
+}
+
+// jsonValue converts a value decoded from the YAML config to its JSON equivalent, with string map keys
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, item := range value {
+			converted[i] = jsonValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
@@ -0,0 +1,167 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+func TestSelectPath(t *testing.T) {
+	result := map[string]interface{}{
+		"number": "0x10",
+		"peers":  []interface{}{map[string]interface{}{"name": "geth"}, map[string]interface{}{"name": "erigon"}},
+		"nested": map[string]interface{}{"synced": true},
+	}
+	tests := []struct {
+		path    string
+		want    interface{}
+		wantErr string
+	}{
+		{path: "{.number}", want: "0x10"},
+		{path: ".number", want: "0x10"},
+		{path: ".nested.synced", want: true},
+		{path: ".peers[1].name", want: "erigon"},
+		{path: ".peers[*].name", want: "geth"},
+		{path: ".missing", wantErr: "missing is not found"},
+		{path: ".peers[5]", wantErr: "out of bounds"},
+		{path: "{.number", wantErr: "invalid path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := selectPath(tt.path, result)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("selectPath(%s) = %v, %v, want error %q", tt.path, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectPath(%s): %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectPath(%s) = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "scalar", value: 5, want: 5},
+		{name: "string", value: "latest", want: "latest"},
+		{
+			name:  "map",
+			value: map[interface{}]interface{}{"to": "0x1", 1: true},
+			want:  map[string]interface{}{"to": "0x1", "1": true},
+		},
+		{
+			name:  "nested",
+			value: []interface{}{map[interface{}]interface{}{"data": []interface{}{map[interface{}]interface{}{"a": 1}}}, "latest"},
+			want:  []interface{}{map[string]interface{}{"data": []interface{}{map[string]interface{}{"a": 1}}}, "latest"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonValue(%#v) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCustomCheck(t *testing.T) {
+	// The node answers eth_chainId, eth_getBlockByNumber with the params it got and fails the other methods
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []interface{}   `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_chainId":
+			resp["result"] = "0x1"
+		case "eth_getBlockByNumber":
+			resp["result"] = map[string]interface{}{"number": "0x10", "params": req.Params}
+		default:
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+	node := config.Node{URL: srv.URL}
+
+	tests := []struct {
+		name  string
+		check config.CustomCheck
+		want  CustomCheckResult
+		// error is a part of the expected error
+		error string
+	}{
+		{
+			name:  "call succeeds",
+			check: config.CustomCheck{Name: "chain id", Method: "eth_chainId"},
+			want:  CustomCheckResult{Name: "chain id", Severity: config.SeverityWarning, Passed: true, Value: "0x1"},
+		},
+		{
+			name:  "expected value",
+			check: config.CustomCheck{Name: "chain id", Method: "eth_chainId", Expect: "0x1", Severity: config.SeverityCritical},
+			want:  CustomCheckResult{Name: "chain id", Severity: config.SeverityCritical, Passed: true, Value: "0x1"},
+		},
+		{
+			name:  "unexpected value",
+			check: config.CustomCheck{Name: "chain id", Method: "eth_chainId", Expect: "0x5"},
+			want:  CustomCheckResult{Name: "chain id", Severity: config.SeverityWarning, Value: "0x1"},
+			error: `expected "0x5", got "0x1"`,
+		},
+		{
+			name:  "path",
+			check: config.CustomCheck{Name: "head", Method: "eth_getBlockByNumber", Params: []interface{}{"latest", false}, Path: "{.number}", Expect: "0x10"},
+			want:  CustomCheckResult{Name: "head", Severity: config.SeverityWarning, Passed: true, Value: "0x10"},
+		},
+		{
+			name: "params from YAML",
+			check: config.CustomCheck{Name: "params", Method: "eth_getBlockByNumber", Path: ".params[0]",
+				Params: []interface{}{map[interface{}]interface{}{"to": "0x1"}}, Expect: map[interface{}]interface{}{"to": "0x1"}},
+			want: CustomCheckResult{Name: "params", Severity: config.SeverityWarning, Passed: true, Value: map[string]interface{}{"to": "0x1"}},
+		},
+		{
+			name:  "path matches nothing",
+			check: config.CustomCheck{Name: "head", Method: "eth_getBlockByNumber", Path: ".hash"},
+			want:  CustomCheckResult{Name: "head", Severity: config.SeverityWarning},
+			error: "hash is not found",
+		},
+		{
+			name:  "call fails",
+			check: config.CustomCheck{Name: "txpool", Method: "txpool_status"},
+			want:  CustomCheckResult{Name: "txpool", Severity: config.SeverityWarning},
+			error: "method not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runCustomCheck(context.Background(), node, 0, tt.check)
+			if !strings.Contains(got.Error, tt.error) || (tt.error == "" && got.Error != "") {
+				t.Errorf("error = %q, want %q", got.Error, tt.error)
+			}
+			got.Error = ""
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runCustomCheck = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const (
+	defaultChurnInterval = 10 * time.Second
+	defaultTableMetric   = "discover/bucket/"
+)
+
+// DiscoveryStats represents discovery table size and peer churn of a node
+type DiscoveryStats struct {
+	TableSize    int64         `json:"table_size" yaml:"table_size"`
+	PeersAdded   int           `json:"peers_added" yaml:"peers_added"`
+	PeersDropped int           `json:"peers_dropped" yaml:"peers_dropped"`
+	Interval     time.Duration `json:"interval" yaml:"interval"`
+}
+
+// checkDiscovery samples the node's discovery table size (via debug_metrics)
+// and peer churn (via two admin_peers samples)
+func checkDiscovery(ctx context.Context, node config.Node, localPort int) (*DiscoveryStats, error) {
+	conf := *node.Discovery
+	if conf.TableMetric == "" {
+		conf.TableMetric = defaultTableMetric
+	}
+	if conf.ChurnInterval == 0 {
+		conf.ChurnInterval = defaultChurnInterval
+	}
+
+	stats := &DiscoveryStats{TableSize: -1}
+
+	// The debug namespace is often disabled, so table size is best-effort
+	if metrics, err := fetchDebugMetrics(ctx, node, localPort); err == nil {
+		stats.TableSize = sumMetrics(metrics, conf.TableMetric)
+	}
+
+	before, err := fetchPeers(ctx, node, localPort)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Interval, err = sampleWait(ctx, conf.ChurnInterval); err != nil {
+		return nil, err
+	}
+	after, err := fetchPeers(ctx, node, localPort)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeIDs := peerIDs(before)
+	afterIDs := peerIDs(after)
+	for id := range afterIDs {
+		if !beforeIDs[id] {
+			stats.PeersAdded++
+		}
+	}
+	for id := range beforeIDs {
+		if !afterIDs[id] {
+			stats.PeersDropped++
+		}
+	}
+	return stats, nil
+}
+
+func peerIDs(peers []PeerInfo) map[string]bool {
+	ids := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		ids[peer.ID] = true
+	}
+	return ids
+}
+
+// fetchDebugMetrics returns the flattened debug_metrics output keyed by slash-separated metric names
+func fetchDebugMetrics(ctx context.Context, node config.Node, localPort int) (map[string]float64, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "debug_metrics", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]float64)
+	flattenMetrics("", tree, metrics)
+	return metrics, nil
+}
+
+func flattenMetrics(prefix string, tree map[string]interface{}, out map[string]float64) {
+	for key, val := range tree {
+		name := key
+		if prefix != "" {
+			name = prefix + "/" + key
+		}
+		switch v := val.(type) {
+		case float64:
+			out[name] = v
+		case map[string]interface{}:
+			// Gauges are reported as {"Value": n}
+			if gauge, ok := v["Value"].(float64); ok && len(v) == 1 {
+				out[name] = gauge
+				continue
+			}
+			flattenMetrics(name, v, out)
+		}
+	}
+}
+
+func sumMetrics(metrics map[string]float64, prefix string) int64 {
+	var sum float64
+	for name, val := range metrics {
+		if strings.HasPrefix(name, prefix) {
+			sum += val
+		}
+	}
+	return int64(sum)
+}
@@ -0,0 +1,165 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// endpointPortOffset separates the local ports forwarded to additional endpoints of a node
+const endpointPortOffset = 1000
+
+// EndpointStatus represents the health of a single endpoint
+type EndpointStatus struct {
+	Name    string        `json:"name" yaml:"name"`
+	Type    string        `json:"type" yaml:"type"`
+	Healthy bool          `json:"healthy" yaml:"healthy"`
+	Latency time.Duration `json:"latency" yaml:"latency"`
+	Error   string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// engineCapabilities are the Engine API methods exchanged with engine endpoints
+var engineCapabilities = []string{"engine_newPayloadV3", "engine_forkchoiceUpdatedV3", "engine_getPayloadV3"}
+
+// endpointLocalPort returns the local port forwarded to the i-th additional endpoint
+func endpointLocalPort(localPort int, i int) int {
+	return localPort + endpointPortOffset*(i+1)
+}
+
+// endpointURL returns the URL of the i-th additional endpoint on its forwarded local port
+func endpointURL(node config.Node, localPort int, i int) string {
+	if node.Endpoints[i].URL != "" {
+		return node.Endpoints[i].URL
+	}
+	scheme := "http"
+	if node.Endpoints[i].Type == config.EndpointWS {
+		scheme = "ws"
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%d%s", scheme, endpointLocalPort(localPort, i), node.Endpoints[i].Path)
+}
+
+// findEndpoint returns the index of the first endpoint of the given type or -1
+func findEndpoint(node config.Node, endpointType string) int {
+	for i, endpoint := range node.Endpoints {
+		if endpoint.Type == endpointType {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkEndpoints performs a basic health check of every additional endpoint
+func checkEndpoints(ctx context.Context, node config.Node, localPort int) []EndpointStatus {
+	statuses := make([]EndpointStatus, 0, len(node.Endpoints))
+	for i, endpoint := range node.Endpoints {
+		status := EndpointStatus{Name: endpoint.Name, Type: endpoint.Type}
+		url := endpointURL(node, localPort, i)
+
+		start := time.Now()
+		var err error
+		switch endpoint.Type {
+		case config.EndpointHTTP:
+			_, err = rpc.CallAuth(ctx, url, endpoint.Auth, "eth_blockNumber")
+		case config.EndpointEngine:
+			// The consensus client reaches the execution client the same way, with the shared JWT secret
+			_, err = rpc.CallAuth(ctx, url, endpoint.Auth, "engine_exchangeCapabilities", engineCapabilities)
+		case config.EndpointWS:
+			err = checkWSEndpoint(ctx, url)
+		case config.EndpointMetrics:
+			err = checkHTTPEndpoint(ctx, url)
+		case config.EndpointBeacon:
+			err = checkHTTPEndpoint(ctx, url+"/eth/v1/node/health")
+		case config.EndpointGraphQL:
+			err = checkGraphQLEndpoint(ctx, url+"/graphql")
+		default:
+			err = fmt.Errorf("unknown endpoint type %q", endpoint.Type)
+		}
+		status.Latency = time.Since(start)
+		status.Healthy = err == nil
+		if err != nil {
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// checkHTTPEndpoint expects a 2xx answer (beacon nodes answer 206 while syncing)
+func checkHTTPEndpoint(ctx context.Context, url string) error {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// checkGraphQLEndpoint queries the number of the latest block from geth's GraphQL endpoint,
+// served on the HTTP port but down independently of the JSON-RPC API
+func checkGraphQLEndpoint(ctx context.Context, url string) error {
+	resp, err := httpDo(ctx, http.MethodPost, url, "application/json", strings.NewReader(`{"query":"{ block { number } }"}`))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var answer struct {
+		Data struct {
+			Block *struct {
+				Number interface{} `json:"number"`
+			} `json:"block"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return fmt.Errorf("invalid GraphQL response: %v", err)
+	}
+	if len(answer.Errors) > 0 {
+		return fmt.Errorf("block query failed: %s", answer.Errors[0].Message)
+	}
+	if answer.Data.Block == nil || answer.Data.Block.Number == nil {
+		return errors.New("block query returned no block")
+	}
+	return nil
+}
+
+func checkWSEndpoint(ctx context.Context, url string) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "method": "eth_blockNumber", "params": []interface{}{}, "id": 1})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Result string        `json:"result"`
+		Error  *rpc.RPCError `json:"error"`
+	}
+	if err := conn.ReadJSON(&resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("eth_blockNumber failed: %v", resp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// StageProgress represents the progress of a single Erigon staged-sync stage
+type StageProgress struct {
+	Name  string `json:"name" yaml:"name"`
+	Block int64  `json:"block" yaml:"block"`
+}
+
+// StagedSync represents Erigon's staged-sync progress, Stage is the first stage behind the highest block
+type StagedSync struct {
+	Stage        string          `json:"stage" yaml:"stage"`
+	StageBlock   int64           `json:"stage_block" yaml:"stage_block"`
+	HighestBlock int64           `json:"highest_block" yaml:"highest_block"`
+	Progress     float64         `json:"progress" yaml:"progress"`
+	Stages       []StageProgress `json:"stages" yaml:"stages"`
+}
+
+// checkStagedSync reports Erigon's staged-sync progress from the stages array of its eth_syncing object,
+// it returns nil for other clients and synced nodes
+func checkStagedSync(ctx context.Context, node config.Node, localPort int) *StagedSync {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "eth_syncing")
+	if err != nil {
+		return nil
+	}
+	var status struct {
+		HighestBlock string `json:"highestBlock"`
+		Stages       []struct {
+			Name  string `json:"stage_name"`
+			Block string `json:"block_number"`
+		} `json:"stages"`
+	}
+	// A synced node answers false
+	if err := json.Unmarshal(raw, &status); err != nil || len(status.Stages) == 0 {
+		return nil
+	}
+
+	highest, err := rpc.ParseHex(status.HighestBlock)
+	if err != nil {
+		return nil
+	}
+	sync := &StagedSync{HighestBlock: highest}
+	for _, stage := range status.Stages {
+		block, err := rpc.ParseHex(stage.Block)
+		if err != nil {
+			continue
+		}
+		sync.Stages = append(sync.Stages, StageProgress{Name: stage.Name, Block: block})
+		if sync.Stage == "" && block < highest {
+			sync.Stage = stage.Name
+			sync.StageBlock = block
+		}
+	}
+	if sync.Stage != "" && highest > 0 {
+		sync.Progress = float64(sync.StageBlock) / float64(highest)
+	}
+	return sync
+}
@@ -0,0 +1,53 @@
+package checker
+
+import (
+	"context"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// syncSampleInterval is the time between the two head samples used to measure sync speed
+const syncSampleInterval = 10 * time.Second
+
+// SyncETA represents the sync speed of a node catching up with the reference head.
+// Rate is the node's speed in blocks per minute, ETA is zero when the node is not closing the gap.
+type SyncETA struct {
+	Rate float64       `json:"rate" yaml:"rate"`
+	ETA  time.Duration `json:"eta" yaml:"eta"`
+}
+
+// estimateSyncETA samples the node and reference heads twice and extrapolates the time to close the gap
+func estimateSyncETA(ctx context.Context, adapter ChainAdapter, node config.Node, localPort int, apiConf config.PublicAPI) (*SyncETA, error) {
+	nodeBefore, err := adapter.Head(ctx, node, localPort)
+	if err != nil {
+		return nil, err
+	}
+	refBefore, err := adapter.ReferenceHead(ctx, apiConf)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if _, err := sampleWait(ctx, syncSampleInterval); err != nil {
+		return nil, err
+	}
+
+	nodeAfter, err := adapter.Head(ctx, node, localPort)
+	if err != nil {
+		return nil, err
+	}
+	refAfter, err := adapter.ReferenceHead(ctx, apiConf)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+
+	eta := &SyncETA{Rate: float64(nodeAfter-nodeBefore) / elapsed.Minutes()}
+	// The gap closes at the node's speed minus the chain's own block production
+	closing := float64((nodeAfter-nodeBefore)-(refAfter-refBefore)) / elapsed.Seconds()
+	if gap := refAfter - nodeAfter; gap > 0 && closing > 0 {
+		eta.ETA = time.Duration(float64(gap)/closing) * time.Second
+	}
+	return eta, nil
+}
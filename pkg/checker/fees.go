@@ -0,0 +1,215 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// FeeTrend represents base fee and gas limit of the most recent block compared against the reference
+type FeeTrend struct {
+	Block         int64    `json:"block" yaml:"block"`
+	BaseFeePerGas int64    `json:"base_fee_per_gas" yaml:"base_fee_per_gas"`
+	GasLimit      int64    `json:"gas_limit" yaml:"gas_limit"`
+	Divergences   []string `json:"divergences" yaml:"divergences"`
+}
+
+// checkFeeTrend compares baseFeePerGas and gasLimit of the last n blocks served by the node
+// with the same blocks served by the reference, which must be identical on the canonical chain
+func checkFeeTrend(ctx context.Context, node config.Node, localPort int, apiConf config.PublicAPI, headBlock int64, n int) (*FeeTrend, error) {
+	reference, err := referenceCaller(ctx, apiConf)
+	if err != nil {
+		return nil, err
+	}
+	call := nodeCaller(ctx, node, localPort)
+
+	trend := &FeeTrend{Block: headBlock}
+	for num := headBlock - int64(n) + 1; num <= headBlock; num++ {
+		nodeBlock, err := fetchBlock(call, num)
+		if err != nil {
+			return nil, err
+		}
+		refBlock, err := fetchBlock(reference, num)
+		if err != nil {
+			return nil, fmt.Errorf("reference: %v", err)
+		}
+
+		if nodeBlock.BaseFeePerGas != refBlock.BaseFeePerGas {
+			trend.Divergences = append(trend.Divergences, fmt.Sprintf("block %d baseFeePerGas %s, reference %s", num, nodeBlock.BaseFeePerGas, refBlock.BaseFeePerGas))
+		}
+		if nodeBlock.GasLimit != refBlock.GasLimit {
+			trend.Divergences = append(trend.Divergences, fmt.Sprintf("block %d gasLimit %s, reference %s", num, nodeBlock.GasLimit, refBlock.GasLimit))
+		}
+
+		if num == headBlock {
+			if nodeBlock.BaseFeePerGas != "" {
+				if trend.BaseFeePerGas, err = rpc.ParseHex(nodeBlock.BaseFeePerGas); err != nil {
+					return nil, err
+				}
+			}
+			if trend.GasLimit, err = rpc.ParseHex(nodeBlock.GasLimit); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return trend, nil
+}
+
+const defaultMaxFeeDivergence = 0.5
+
+// GasPrice represents the fee view of the node, in wei, next to the reference's when one is configured
+type GasPrice struct {
+	GasPrice          int64    `json:"gas_price" yaml:"gas_price"`
+	BaseFee           int64    `json:"base_fee" yaml:"base_fee"`
+	ReferenceGasPrice int64    `json:"reference_gas_price,omitempty" yaml:"reference_gas_price,omitempty"`
+	ReferenceBaseFee  int64    `json:"reference_base_fee,omitempty" yaml:"reference_base_fee,omitempty"`
+	Divergences       []string `json:"divergences,omitempty" yaml:"divergences,omitempty"`
+}
+
+// checkGasPrice reads eth_gasPrice and the base fee of the latest block from the node and the reference rpc_url.
+// A fee view far from the reference's is a symptom of a node stuck on a fork.
+func checkGasPrice(ctx context.Context, node config.Node, localPort int, apiConf config.PublicAPI) (*GasPrice, error) {
+	gasPrice, baseFee, err := fetchFees(nodeCaller(ctx, node, localPort))
+	if err != nil {
+		return nil, err
+	}
+	fees := &GasPrice{GasPrice: gasPrice, BaseFee: baseFee}
+
+	reference, err := referenceCaller(ctx, apiConf)
+	if err != nil {
+		return fees, nil
+	}
+	if fees.ReferenceGasPrice, fees.ReferenceBaseFee, err = fetchFees(reference); err != nil {
+		return nil, fmt.Errorf("reference: %v", err)
+	}
+
+	maxDivergence := node.MaxFeeDivergence
+	if maxDivergence == 0 {
+		maxDivergence = defaultMaxFeeDivergence
+	}
+	if divergence(fees.GasPrice, fees.ReferenceGasPrice) > maxDivergence {
+		fees.Divergences = append(fees.Divergences, fmt.Sprintf("gas price %s, reference %s", formatGwei(fees.GasPrice), formatGwei(fees.ReferenceGasPrice)))
+	}
+	if divergence(fees.BaseFee, fees.ReferenceBaseFee) > maxDivergence {
+		fees.Divergences = append(fees.Divergences, fmt.Sprintf("base fee %s, reference %s", formatGwei(fees.BaseFee), formatGwei(fees.ReferenceBaseFee)))
+	}
+	return fees, nil
+}
+
+// fetchFees returns eth_gasPrice and the base fee of the latest block, 0 before London
+func fetchFees(call rpcCaller) (int64, int64, error) {
+	raw, err := call("eth_gasPrice")
+	if err != nil {
+		return 0, 0, err
+	}
+	gasPrice, err := quantity(raw)
+	if err != nil {
+		return 0, 0, fmt.Errorf("eth_gasPrice: %v", err)
+	}
+	header, err := fetchBlock(call, "latest")
+	if err != nil {
+		return 0, 0, err
+	}
+	if header.BaseFeePerGas == "" {
+		return gasPrice, 0, nil
+	}
+	baseFee, err := rpc.ParseHex(header.BaseFeePerGas)
+	if err != nil {
+		return 0, 0, fmt.Errorf("baseFeePerGas: %v", err)
+	}
+	return gasPrice, baseFee, nil
+}
+
+// divergence returns the difference of value from reference relative to reference
+func divergence(value int64, reference int64) float64 {
+	if reference == 0 {
+		return 0
+	}
+	return math.Abs(float64(value-reference)) / float64(reference)
+}
+
+// formatGwei formats a wei amount in gwei
+func formatGwei(wei int64) string {
+	return strconv.FormatFloat(float64(wei)/1e9, 'f', -1, 64) + " gwei"
+}
+
+const feeHistoryBlocks = 5
+
+var feeHistoryPercentiles = []float64{25, 50, 75}
+
+// feeHistory represents an eth_feeHistory response
+type feeHistory struct {
+	OldestBlock   string     `json:"oldestBlock"`
+	BaseFeePerGas []string   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64  `json:"gasUsedRatio"`
+	Reward        [][]string `json:"reward"`
+}
+
+// checkFeeHistory validates the structure and recency of the node's eth_feeHistory response
+// and compares its newest block with the reference, returning the problems found
+func checkFeeHistory(ctx context.Context, node config.Node, localPort int, apiConf config.PublicAPI) ([]string, error) {
+	nodeHistory, err := fetchFeeHistory(nodeCaller(ctx, node, localPort))
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if len(nodeHistory.BaseFeePerGas) != feeHistoryBlocks+1 {
+		problems = append(problems, fmt.Sprintf("baseFeePerGas has %d entries, expected %d", len(nodeHistory.BaseFeePerGas), feeHistoryBlocks+1))
+	}
+	if len(nodeHistory.GasUsedRatio) != feeHistoryBlocks {
+		problems = append(problems, fmt.Sprintf("gasUsedRatio has %d entries, expected %d", len(nodeHistory.GasUsedRatio), feeHistoryBlocks))
+	}
+	if len(nodeHistory.Reward) != feeHistoryBlocks {
+		problems = append(problems, fmt.Sprintf("reward has %d entries, expected %d", len(nodeHistory.Reward), feeHistoryBlocks))
+	}
+	for i, rewards := range nodeHistory.Reward {
+		if len(rewards) != len(feeHistoryPercentiles) {
+			problems = append(problems, fmt.Sprintf("reward[%d] has %d percentiles, expected %d", i, len(rewards), len(feeHistoryPercentiles)))
+			break
+		}
+	}
+
+	nodeOldest, err := rpc.ParseHex(nodeHistory.OldestBlock)
+	if err != nil {
+		return append(problems, fmt.Sprintf("invalid oldestBlock: %v", err)), nil
+	}
+
+	reference, err := referenceCaller(ctx, apiConf)
+	if err != nil {
+		return problems, nil
+	}
+	refHistory, err := fetchFeeHistory(reference)
+	if err != nil {
+		return nil, fmt.Errorf("reference: %v", err)
+	}
+	refOldest, err := rpc.ParseHex(refHistory.OldestBlock)
+	if err != nil {
+		return nil, fmt.Errorf("reference: invalid oldestBlock: %v", err)
+	}
+	if lag := refOldest - nodeOldest; lag > feeHistoryBlocks {
+		problems = append(problems, fmt.Sprintf("fee history is %d blocks behind the reference", lag))
+	}
+	return problems, nil
+}
+
+func fetchFeeHistory(call rpcCaller) (feeHistory, error) {
+	raw, err := call("eth_feeHistory", fmt.Sprintf("0x%x", feeHistoryBlocks), "latest", feeHistoryPercentiles)
+	if err != nil {
+		return feeHistory{}, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return feeHistory{}, fmt.Errorf("empty eth_feeHistory response")
+	}
+
+	var history feeHistory
+	if err := json.Unmarshal(raw, &history); err != nil {
+		return feeHistory{}, err
+	}
+	return history, nil
+}
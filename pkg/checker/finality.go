@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const defaultMaxFinalityLag = 128
+
+// Finality represents the safe and finalized blocks of a PoS chain as seen by the node
+type Finality struct {
+	Safe      int64 `json:"safe" yaml:"safe"`
+	Finalized int64 `json:"finalized" yaml:"finalized"`
+	// Lag is the distance of the finalized block from the node's head
+	Lag int64 `json:"lag" yaml:"lag"`
+	// Stalled is set when the head advances but the finalized block trails it by more than the limit
+	Stalled bool `json:"stalled" yaml:"stalled"`
+}
+
+// checkFinality reads the finalized and safe blocks of the node and compares them with its head
+func checkFinality(ctx context.Context, node config.Node, localPort int, headBlock int64) (*Finality, error) {
+	call := nodeCaller(ctx, node, localPort)
+	finalized, err := fetchBlockNumber(call, "finalized")
+	if err != nil {
+		return nil, err
+	}
+	safe, err := fetchBlockNumber(call, "safe")
+	if err != nil {
+		return nil, err
+	}
+
+	maxLag := node.MaxFinalityLag
+	if maxLag == 0 {
+		maxLag = defaultMaxFinalityLag
+	}
+	finality := &Finality{Safe: safe, Finalized: finalized, Lag: headBlock - finalized}
+	finality.Stalled = finality.Lag > maxLag
+	return finality, nil
+}
+
+// fetchBlockNumber returns the number of the block with the given tag
+func fetchBlockNumber(call rpcCaller, tag string) (int64, error) {
+	header, err := fetchBlock(call, tag)
+	if err != nil {
+		return 0, err
+	}
+	num, err := rpc.ParseHex(header.Number)
+	if err != nil {
+		return 0, fmt.Errorf("%s block number: %v", tag, err)
+	}
+	return num, nil
+}
@@ -0,0 +1,103 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+const defaultMaxGroupDivergence = 10
+
+// ChainGroup represents aggregated results of all nodes of a chain
+type ChainGroup struct {
+	Chain      string   `json:"chain" yaml:"chain"`
+	Nodes      int      `json:"nodes" yaml:"nodes"`
+	Checked    int      `json:"checked" yaml:"checked"`
+	Synced     int      `json:"synced" yaml:"synced"`
+	BestNode   string   `json:"best_node" yaml:"best_node"`
+	BestBlock  int64    `json:"best_block" yaml:"best_block"`
+	WorstNode  string   `json:"worst_node" yaml:"worst_node"`
+	WorstBlock int64    `json:"worst_block" yaml:"worst_block"`
+	Divergence int64    `json:"divergence" yaml:"divergence"`
+	Alerts     []string `json:"alerts,omitempty" yaml:"alerts,omitempty"`
+}
+
+// AggregateFleet groups results by chain, reporting best/worst height and divergence within each group.
+// Only chains with more than one configured node are aggregated.
+func AggregateFleet(nodes map[string]config.Node, results map[string]Result, maxDivergence int64) []ChainGroup {
+	if maxDivergence == 0 {
+		maxDivergence = defaultMaxGroupDivergence
+	}
+
+	members := make(map[string][]string)
+	for nodeName, node := range nodes {
+		chain := node.ChainName(nodeName)
+		members[chain] = append(members[chain], nodeName)
+	}
+
+	var groups []ChainGroup
+	for chain, nodeNames := range members {
+		if len(nodeNames) < 2 {
+			continue
+		}
+		sort.Strings(nodeNames)
+
+		group := ChainGroup{Chain: chain, Nodes: len(nodeNames)}
+		vulnerable := 0
+		for _, nodeName := range nodeNames {
+			res, ok := results[nodeName]
+			if !ok {
+				continue
+			}
+			group.Checked++
+			if slices.ContainsFunc(res.SecurityAdvisories, Advisory.Critical) {
+				vulnerable++
+			}
+			// Vulnerable nodes are synced, they get an alert of their own
+			if res.SyncStatus == "synced" || res.SyncStatus == "vulnerable" {
+				group.Synced++
+			}
+			if group.BestNode == "" || res.NodeBlockNum > group.BestBlock {
+				group.BestNode, group.BestBlock = nodeName, res.NodeBlockNum
+			}
+			if group.WorstNode == "" || res.NodeBlockNum < group.WorstBlock {
+				group.WorstNode, group.WorstBlock = nodeName, res.NodeBlockNum
+			}
+		}
+		group.Divergence = group.BestBlock - group.WorstBlock
+
+		if group.Synced == 0 {
+			group.Alerts = append(group.Alerts, "no synced node in the group")
+		}
+		if failed := group.Nodes - group.Checked; failed > 0 {
+			group.Alerts = append(group.Alerts, fmt.Sprintf("%d of %d nodes failed their checks", failed, group.Nodes))
+		}
+		if vulnerable > 0 {
+			group.Alerts = append(group.Alerts, fmt.Sprintf("%d of %d nodes affected by critical security advisories", vulnerable, group.Nodes))
+		}
+		if group.Divergence > maxDivergence {
+			group.Alerts = append(group.Alerts, fmt.Sprintf("nodes diverge by %d blocks", group.Divergence))
+		}
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Chain < groups[j].Chain })
+	return groups
+}
+
+// printFleet prints the aggregated chain groups
+func (p printer) printFleet(groups []ChainGroup) {
+	for _, group := range groups {
+		fmt.Fprintf(p.w, "Chain: %s (%d nodes, %d synced)\n", group.Chain, group.Nodes, group.Synced)
+		if group.Checked > 0 {
+			fmt.Fprintf(p.w, "Best: %s at %d, worst: %s at %d, divergence: %d\n",
+				group.BestNode, group.BestBlock, group.WorstNode, group.WorstBlock, group.Divergence)
+		}
+		for _, alert := range group.Alerts {
+			fmt.Fprintf(p.w, "Group alert: %s\n", alert)
+		}
+		fmt.Fprintln(p.w)
+	}
+}
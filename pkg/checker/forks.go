@@ -0,0 +1,177 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// ForkReadiness represents whether a node is configured for an upcoming fork
+type ForkReadiness struct {
+	Fork   string `json:"fork" yaml:"fork"`
+	Ready  bool   `json:"ready" yaml:"ready"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// ForkAdvisory represents the countdown to an upcoming fork and an outdated client warning, if any
+type ForkAdvisory struct {
+	Fork      string `json:"fork" yaml:"fork"`
+	Countdown string `json:"countdown" yaml:"countdown"`
+	Warning   string `json:"warning,omitempty" yaml:"warning,omitempty"`
+}
+
+// ethConfig represents the relevant part of the eth_config (EIP-7910) response
+type ethConfig struct {
+	Current *ethForkConfig `json:"current"`
+	Next    *ethForkConfig `json:"next"`
+	Last    *ethForkConfig `json:"last"`
+}
+
+type ethForkConfig struct {
+	ActivationTime int64  `json:"activationTime"`
+	ForkID         string `json:"forkId"`
+}
+
+// upcomingForks returns the forks which are not activated yet at the given head block
+func upcomingForks(forks []config.Fork, headBlock int64) []config.Fork {
+	now := time.Now().Unix()
+	var upcoming []config.Fork
+	for _, fork := range forks {
+		if (fork.Time > 0 && fork.Time > now) || (fork.Block > 0 && fork.Block > headBlock) {
+			upcoming = append(upcoming, fork)
+		}
+	}
+	return upcoming
+}
+
+// checkForkReadiness verifies that the node's chain config schedules every upcoming fork.
+// eth_config is used when the client supports it, admin_nodeInfo chain config otherwise.
+func checkForkReadiness(ctx context.Context, node config.Node, localPort int, forks []config.Fork) ([]ForkReadiness, error) {
+	// eth_config only reports timestamp based activations
+	timeBased := true
+	for _, fork := range forks {
+		if fork.Time == 0 {
+			timeBased = false
+		}
+	}
+
+	scheduled, source, err := scheduledActivations(ctx, node, localPort, timeBased)
+	if err != nil {
+		return nil, err
+	}
+
+	readiness := make([]ForkReadiness, 0, len(forks))
+	for _, fork := range forks {
+		ready := false
+		switch {
+		case fork.ConfigKey != "" && source == "admin_nodeInfo":
+			ready = scheduled[fork.ConfigKey] == activationOf(fork)
+		default:
+			for _, activation := range scheduled {
+				if activation == activationOf(fork) {
+					ready = true
+					break
+				}
+			}
+		}
+
+		detail := fmt.Sprintf("activation %d scheduled (%s)", activationOf(fork), source)
+		if !ready {
+			detail = fmt.Sprintf("activation %d not found in %s, node will fork off", activationOf(fork), source)
+		}
+		readiness = append(readiness, ForkReadiness{Fork: fork.Name, Ready: ready, Detail: detail})
+	}
+	return readiness, nil
+}
+
+func activationOf(fork config.Fork) int64 {
+	if fork.Time > 0 {
+		return fork.Time
+	}
+	return fork.Block
+}
+
+// scheduledActivations returns the fork activations known to the node keyed by their config name
+func scheduledActivations(ctx context.Context, node config.Node, localPort int, useEthConfig bool) (map[string]int64, string, error) {
+	if useEthConfig {
+		if scheduled, err := ethConfigActivations(ctx, node, localPort); err == nil {
+			return scheduled, "eth_config", nil
+		}
+	}
+
+	info, err := fetchNodeInfo(ctx, node, localPort)
+	if err != nil {
+		return nil, "", err
+	}
+	scheduled := make(map[string]int64)
+	for key, val := range info.Protocols.Eth.Config {
+		num, ok := val.(float64)
+		if ok && (strings.HasSuffix(key, "Block") || strings.HasSuffix(key, "Time")) {
+			scheduled[key] = int64(num)
+		}
+	}
+	if len(scheduled) == 0 {
+		return nil, "", fmt.Errorf("node exposes neither eth_config nor chain config in admin_nodeInfo")
+	}
+	return scheduled, "admin_nodeInfo", nil
+}
+
+func ethConfigActivations(ctx context.Context, node config.Node, localPort int) (map[string]int64, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "eth_config")
+	if err != nil {
+		return nil, err
+	}
+
+	var conf ethConfig
+	if err := json.Unmarshal(raw, &conf); err != nil {
+		return nil, err
+	}
+	if conf.Current == nil {
+		return nil, fmt.Errorf("eth_config is not supported")
+	}
+
+	scheduled := make(map[string]int64)
+	for key, fc := range map[string]*ethForkConfig{"current": conf.Current, "next": conf.Next, "last": conf.Last} {
+		if fc != nil {
+			scheduled[key] = fc.ActivationTime
+		}
+	}
+	return scheduled, nil
+}
+
+// forkAdvisories returns a countdown for every upcoming fork and warns when the node runs
+// a client release older than the configured fork-ready one
+func forkAdvisories(forks []config.Fork, headBlock int64, client ClientVersion) []ForkAdvisory {
+	advisories := make([]ForkAdvisory, 0, len(forks))
+	for _, fork := range forks {
+		advisory := ForkAdvisory{Fork: fork.Name}
+		if fork.Time > 0 {
+			left := time.Until(time.Unix(fork.Time, 0)).Round(time.Minute)
+			advisory.Countdown = fmt.Sprintf("in %s (%s)", formatDays(left), time.Unix(fork.Time, 0).UTC().Format(time.RFC3339))
+		} else {
+			advisory.Countdown = fmt.Sprintf("in %d blocks (block %d)", fork.Block-headBlock, fork.Block)
+		}
+
+		if minVersion, ok := fork.MinClientVersions[client.Client]; ok && client.Version != "" {
+			if compareVersions(client.Version, minVersion) < 0 {
+				advisory.Warning = fmt.Sprintf("%s %s is older than fork-ready release %s", client.Client, client.Version, minVersion)
+			}
+		}
+		advisories = append(advisories, advisory)
+	}
+	return advisories
+}
+
+// formatDays renders long durations as days and hours, e.g. "13d4h"
+func formatDays(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	if days == 0 {
+		return d.String()
+	}
+	return fmt.Sprintf("%dd%dh", days, int(d.Hours())%24)
+}
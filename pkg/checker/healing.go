@@ -0,0 +1,79 @@
+package checker
+
+import (
+	"context"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// healSampleInterval is the time between the two eth_syncing samples used to measure healing speed
+const healSampleInterval = 5 * time.Second
+
+// HealProgress represents geth snap sync state-healing progress
+type HealProgress struct {
+	HealedTrienodes  int64 `json:"healed_trienodes" yaml:"healed_trienodes"`
+	PendingTrienodes int64 `json:"pending_trienodes" yaml:"pending_trienodes"`
+	PendingBytecodes int64 `json:"pending_bytecodes" yaml:"pending_bytecodes"`
+	// Rate is the number of trie nodes healed per second, ETA is zero when it cannot be estimated
+	Rate float64       `json:"rate" yaml:"rate"`
+	ETA  time.Duration `json:"eta" yaml:"eta"`
+}
+
+// checkHealing reports state-healing progress when geth's eth_syncing object, status, shows pending heal tasks
+func checkHealing(ctx context.Context, node config.Node, localPort int, status interface{}) *HealProgress {
+	first, ok := healFields(status)
+	if !ok || (first.PendingTrienodes == 0 && first.PendingBytecodes == 0) {
+		return nil
+	}
+
+	waited, err := sampleWait(ctx, healSampleInterval)
+	if err != nil {
+		return first
+	}
+	status, err = rpc.Call(ctx, node, localPort, "eth_syncing")
+	if err != nil {
+		return first
+	}
+	second, ok := healFields(status)
+	if !ok {
+		return first
+	}
+
+	healed := second.HealedTrienodes - first.HealedTrienodes
+	if healed > 0 {
+		second.Rate = float64(healed) / waited.Seconds()
+		second.ETA = time.Duration(float64(second.PendingTrienodes)/second.Rate) * time.Second
+	}
+	return second
+}
+
+func healFields(status interface{}) (*HealProgress, bool) {
+	val, ok := status.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if _, ok := val["healingTrienodes"]; !ok {
+		return nil, false
+	}
+
+	return &HealProgress{
+		HealedTrienodes:  hexField(val, "healedTrienodes"),
+		PendingTrienodes: hexField(val, "healingTrienodes"),
+		PendingBytecodes: hexField(val, "healingBytecode"),
+	}, true
+}
+
+// hexField returns the numeric value of a hex quantity field, or zero if it is missing or malformed
+func hexField(val map[string]interface{}, key string) int64 {
+	str, ok := val[key].(string)
+	if !ok {
+		return 0
+	}
+	num, err := rpc.ParseHex(str)
+	if err != nil {
+		return 0
+	}
+	return num
+}
@@ -0,0 +1,155 @@
+package checker
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// Sparkline size in pixels
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+// DiffSample is a past diff of a node, drawn as a sparkline in the HTML report
+type DiffSample struct {
+	Time time.Time
+	Diff int64
+}
+
+// htmlRow is a node of the HTML report
+type htmlRow struct {
+	Node, Chain, Cluster, Status, Class string
+	Block, Reference, Diff, Peers       string
+	Sparkline                           string
+	SparklineTitle                      string
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>nodestat report {{.Generated}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; text-align: left; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.synced { color: #1a7f37; font-weight: bold; }
+.syncing { color: #9a6700; font-weight: bold; }
+.failed { color: #cf222e; font-weight: bold; }
+polyline { fill: none; stroke: #0969da; stroke-width: 1.5; }
+footer { margin-top: 1em; color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>nodestat report</h1>
+<p>Generated {{.Generated}}, {{.Synced}} of {{len .Rows}} nodes synced.</p>
+<table>
+<tr><th>Node</th><th>Chain</th>{{if .WithCluster}}<th>Cluster</th>{{end}}<th>Status</th><th>Block</th><th>Reference</th><th>Diff</th><th>Peers</th>{{if .WithHistory}}<th>Recent diffs</th>{{end}}</tr>
+{{- range .Rows}}
+<tr><td>{{.Node}}</td><td>{{.Chain}}</td>{{if $.WithCluster}}<td>{{.Cluster}}</td>{{end}}<td class="{{.Class}}">{{.Status}}</td><td class="num">{{.Block}}</td><td class="num">{{.Reference}}</td><td class="num">{{.Diff}}</td><td class="num">{{.Peers}}</td>
+{{- if $.WithHistory}}<td>{{if .Sparkline}}<svg width="{{$.Width}}" height="{{$.Height}}"><title>{{.SparklineTitle}}</title><polyline points="{{.Sparkline}}"/></svg>{{end}}</td>{{end}}</tr>
+{{- end}}
+</table>
+<footer>Generated by nodestat at {{.Generated}}</footer>
+</body>
+</html>
+`))
+
+// WriteHTMLReport renders the results of a run into a self-contained HTML page at path.
+// history holds the recent diffs of every node oldest first, nil when history is disabled.
+func WriteHTMLReport(path string, nodes map[string]config.Node, results map[string]Result, history map[string][]DiffSample) error {
+	nodeNames := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	data := struct {
+		Generated                string
+		Synced                   int
+		WithCluster, WithHistory bool
+		Width, Height            int
+		Rows                     []htmlRow
+	}{
+		Generated:   time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
+		WithHistory: history != nil,
+		Width:       sparklineWidth,
+		Height:      sparklineHeight,
+	}
+	for _, nodeName := range nodeNames {
+		row := htmlRow{Node: nodeName, Chain: nodes[nodeName].ChainName(nodeName), Status: statusUnreachable, Class: "failed",
+			Block: "-", Reference: "-", Diff: "-", Peers: "-"}
+		if res, ok := results[nodeName]; ok {
+			row.Cluster = res.Cluster
+			row.Status = res.SyncStatus
+			row.Class = statusClass(res.SyncStatus)
+			row.Block = fmt.Sprint(res.NodeBlockNum)
+			row.Reference = fmt.Sprint(res.LatestBlockNum)
+			row.Diff = fmt.Sprint(res.Diff)
+			if res.PeersCount != nil {
+				row.Peers = fmt.Sprint(*res.PeersCount)
+			}
+			if res.SyncStatus == "synced" {
+				data.Synced++
+			}
+			data.WithCluster = data.WithCluster || res.Cluster != ""
+		}
+		if samples := history[nodeName]; len(samples) > 1 {
+			row.Sparkline = sparkline(samples)
+			row.SparklineTitle = fmt.Sprintf("%d samples from %s to %s", len(samples),
+				samples[0].Time.UTC().Format("2006-01-02 15:04"), samples[len(samples)-1].Time.UTC().Format("2006-01-02 15:04"))
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	f, err := os.Create(config.ExpandHome(path))
+	if err != nil {
+		return err
+	}
+	if err := htmlReport.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// statusClass returns the CSS class of a sync status, matching the colors of the table output
+func statusClass(status string) string {
+	switch statusColor(status) {
+	case colorGreen:
+		return "synced"
+	case colorYellow:
+		return "syncing"
+	default:
+		return "failed"
+	}
+}
+
+// sparkline returns the SVG polyline points of the diffs scaled to the sparkline size
+func sparkline(samples []DiffSample) string {
+	lo, hi := samples[0].Diff, samples[0].Diff
+	for _, sample := range samples {
+		lo = min(lo, sample.Diff)
+		hi = max(hi, sample.Diff)
+	}
+
+	points := make([]string, len(samples))
+	for i, sample := range samples {
+		x := float64(i) * sparklineWidth / float64(len(samples)-1)
+		y := float64(sparklineHeight) / 2
+		if hi > lo {
+			// Leave a pixel at the edges so the line isn't clipped
+			y = 1 + float64(hi-sample.Diff)*(sparklineHeight-2)/float64(hi-lo)
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return strings.Join(points, " ")
+}
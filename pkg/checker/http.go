@@ -0,0 +1,27 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// httpGet performs a GET request canceled with ctx, sent as the JSON-RPC calls with rpc.Do
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	return httpDo(ctx, http.MethodGet, url, "", nil)
+}
+
+// httpDo performs a request canceled with ctx and retried on transient failures,
+// with body of the given content type if any
+func httpDo(ctx context.Context, method string, url string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return rpc.Do(req)
+}
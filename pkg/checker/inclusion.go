@@ -0,0 +1,211 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const (
+	defaultInclusionSamples = 3
+	defaultInclusionTimeout = 2 * time.Minute
+	inclusionPollInterval   = 500 * time.Millisecond
+)
+
+var (
+	canaryAccountsMu sync.Mutex
+	canaryAccounts   = make(map[string]chan struct{})
+)
+
+// lockCanaryAccount waits until no other probe uses the canary account of the chain and returns its unlock,
+// concurrent probes would read the same pending nonce and replace each other's transactions
+func lockCanaryAccount(ctx context.Context, chain string, from string) (func(), error) {
+	key := chain + "/" + strings.ToLower(from)
+	canaryAccountsMu.Lock()
+	lock, ok := canaryAccounts[key]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		canaryAccounts[key] = lock
+	}
+	canaryAccountsMu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InclusionLatency represents transaction inclusion latency percentiles measured through a node
+type InclusionLatency struct {
+	Samples int           `json:"samples" yaml:"samples"`
+	Failed  int           `json:"failed" yaml:"failed"`
+	P50     time.Duration `json:"p50" yaml:"p50"`
+	P95     time.Duration `json:"p95" yaml:"p95"`
+	// LastError is the error of the last failed canary transaction
+	LastError string `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+}
+
+// checkInclusionLatency broadcasts canary transactions through the node and measures
+// the time until their receipts are served by the same node. The probes of the chain's nodes take turns
+// with the account.
+func checkInclusionLatency(ctx context.Context, chain string, node config.Node, localPort int, canary config.CanaryAccount) (*InclusionLatency, error) {
+	if canary.SignerURL == "" || canary.From == "" {
+		return nil, errors.New("canary account requires from and signer_url")
+	}
+	if canary.To == "" {
+		canary.To = canary.From
+	}
+	if canary.Samples == 0 {
+		canary.Samples = defaultInclusionSamples
+	}
+	if canary.Timeout == 0 {
+		canary.Timeout = defaultInclusionTimeout
+	}
+
+	var latencies []time.Duration
+	failed := 0
+	lastError := ""
+	for i := 0; i < canary.Samples; i++ {
+		latency, err := measureInclusion(ctx, chain, node, localPort, canary)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			lastError = err.Error()
+			failed++
+			continue
+		}
+		latencies = append(latencies, latency)
+	}
+	if len(latencies) == 0 {
+		return nil, fmt.Errorf("all %d canary transactions failed, last: %s", canary.Samples, lastError)
+	}
+
+	return &InclusionLatency{
+		Samples: len(latencies),
+		Failed:  failed,
+		P50:     percentile(latencies, 50),
+		P95:     percentile(latencies, 95),
+
+		LastError: lastError,
+	}, nil
+}
+
+func measureInclusion(ctx context.Context, chain string, node config.Node, localPort int, canary config.CanaryAccount) (time.Duration, error) {
+	unlock, err := lockCanaryAccount(ctx, chain, canary.From)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	nonce, err := rpc.Call(ctx, node, localPort, "eth_getTransactionCount", canary.From, "pending")
+	if err != nil {
+		return 0, err
+	}
+	gasPrice, err := rpc.Call(ctx, node, localPort, "eth_gasPrice")
+	if err != nil {
+		return 0, err
+	}
+	chainID, err := rpc.Call(ctx, node, localPort, "eth_chainId")
+	if err != nil {
+		return 0, err
+	}
+
+	rawTx, err := signCanaryTx(ctx, canary.SignerURL, map[string]interface{}{
+		"from":     canary.From,
+		"to":       canary.To,
+		"value":    "0x0",
+		"gas":      "0x5208",
+		"gasPrice": gasPrice,
+		"nonce":    nonce,
+		"chainId":  chainID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("signing: %v", err)
+	}
+
+	// A retried broadcast could resubmit the transaction after a timeout
+	start := time.Now()
+	txHash, err := rpc.Call(rpc.WithoutRetry(ctx), node, localPort, "eth_sendRawTransaction", rawTx)
+	if err != nil {
+		return 0, err
+	}
+
+	poll := time.NewTicker(inclusionPollInterval)
+	defer poll.Stop()
+	pollFailed := false
+	for time.Since(start) < canary.Timeout {
+		select {
+		case <-poll.C:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		receipt, err := rpc.Call(ctx, node, localPort, "eth_getTransactionReceipt", txHash)
+		if err != nil {
+			// The transaction is already out, a single failed poll is retried on the next tick
+			if pollFailed || ctx.Err() != nil {
+				return 0, err
+			}
+			pollFailed = true
+			continue
+		}
+		pollFailed = false
+		if receipt != nil {
+			return time.Since(start), nil
+		}
+	}
+	return 0, fmt.Errorf("transaction %v not included within %s", txHash, canary.Timeout)
+}
+
+// signCanaryTx signs the transaction with eth_signTransaction and returns the raw transaction.
+// geth and clef respond with {"raw": ..., "tx": ...}, web3signer with the raw transaction string.
+func signCanaryTx(ctx context.Context, signerURL string, tx map[string]interface{}) (string, error) {
+	raw, err := rpc.CallURL(ctx, signerURL, "eth_signTransaction", tx)
+	if err != nil {
+		return "", err
+	}
+
+	var signed string
+	if err := json.Unmarshal(raw, &signed); err == nil {
+		return signed, nil
+	}
+	var envelope struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return "", err
+	}
+	if envelope.Raw == "" {
+		return "", errors.New("signer returned no raw transaction")
+	}
+	return envelope.Raw, nil
+}
+
+// percentile returns the p-th percentile of the given durations using the nearest-rank method
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted))/100)) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
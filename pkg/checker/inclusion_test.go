@@ -0,0 +1,95 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// inclusionServer serves a node and signer which includes every transaction at the second receipt poll,
+// failing the first one. It reports whether the transactions of the account were ever in flight together.
+func inclusionServer(t *testing.T) (string, *atomic.Bool) {
+	var mu sync.Mutex
+	polls := make(map[string]int)
+	sent, inFlight := 0, 0
+	var overlapped atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []interface{}   `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		answer := func(result string) {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,` + result + `}`))
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch req.Method {
+		case "eth_getTransactionCount", "eth_gasPrice", "eth_chainId":
+			answer(`"result":"0x1"`)
+		case "eth_signTransaction":
+			answer(`"result":"0xraw"`)
+		case "eth_sendRawTransaction":
+			sent++
+			inFlight++
+			if inFlight > 1 {
+				overlapped.Store(true)
+			}
+			answer(fmt.Sprintf(`"result":"0x%x"`, sent))
+		case "eth_getTransactionReceipt":
+			hash, _ := req.Params[0].(string)
+			polls[hash]++
+			switch polls[hash] {
+			case 1:
+				answer(`"error":{"code":-32000,"message":"temporarily unavailable"}`)
+			default:
+				inFlight--
+				answer(`"result":{"status":"0x1"}`)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &overlapped
+}
+
+func TestCheckInclusionLatency(t *testing.T) {
+	url, overlapped := inclusionServer(t)
+	canary := config.CanaryAccount{From: "0xCanary", SignerURL: url, Samples: 1, Timeout: 10 * time.Second}
+
+	// Two nodes of the chain probe with the same account at once
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	results := make([]*InclusionLatency, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = checkInclusionLatency(ctx, "eth", config.Node{URL: url}, 0, canary)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("probe %d: %v", i, err)
+		}
+		if results[i].Samples != 1 || results[i].Failed != 0 {
+			t.Errorf("probe %d = %+v, want one included transaction", i, results[i])
+		}
+	}
+	if overlapped.Load() {
+		t.Error("the probes had transactions of the account in flight together")
+	}
+}
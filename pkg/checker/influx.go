@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// influxMeasurement is the measurement of the results in InfluxDB
+const influxMeasurement = "nodestat"
+
+var (
+	influxTagEscaper    = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// InfluxLines returns one line protocol point per node result stamped with t,
+// tagged by node, chain, namespace and cluster
+func InfluxLines(nodes map[string]config.Node, results map[string]Result, t time.Time) []string {
+	nodeNames := make([]string, 0, len(results))
+	for nodeName := range results {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	lines := make([]string, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		res := results[nodeName]
+		node := nodes[nodeName]
+
+		// Tags are sorted by key as recommended by InfluxDB
+		tags := []string{"chain=" + influxTagEscaper.Replace(res.Chain)}
+		if res.Cluster != "" {
+			tags = append(tags, "cluster="+influxTagEscaper.Replace(res.Cluster))
+		}
+		if node.InKubernetes() && node.Namespace != "" {
+			tags = append(tags, "namespace="+influxTagEscaper.Replace(node.Namespace))
+		}
+		tags = append(tags, "node="+influxTagEscaper.Replace(nodeName))
+
+		fields := []string{
+			fmt.Sprintf(`sync_status="%s"`, influxStringEscaper.Replace(res.SyncStatus)),
+			fmt.Sprintf("synced=%t", res.SyncStatus == "synced"),
+			fmt.Sprintf("node_block=%di", res.NodeBlockNum),
+			fmt.Sprintf("reference_block=%di", res.LatestBlockNum),
+			fmt.Sprintf("diff=%di", res.Diff),
+		}
+		if res.PeersCount != nil {
+			fields = append(fields, fmt.Sprintf("peers=%di", *res.PeersCount))
+		}
+		if res.Finality != nil {
+			fields = append(fields, fmt.Sprintf("finality_lag=%di", res.Finality.Lag))
+		}
+		if res.GasPrice != nil {
+			fields = append(fields, fmt.Sprintf("gas_price=%di", res.GasPrice.GasPrice), fmt.Sprintf("base_fee=%di", res.GasPrice.BaseFee))
+		}
+		if res.TxPool != nil {
+			fields = append(fields, fmt.Sprintf("txpool_pending=%di", res.TxPool.Pending), fmt.Sprintf("txpool_queued=%di", res.TxPool.Queued))
+		}
+		if res.LogsBenchmark != nil && res.LogsBenchmark.Error == "" {
+			fields = append(fields, fmt.Sprintf("logs_latency_seconds=%.3f", res.LogsBenchmark.Duration.Seconds()),
+				fmt.Sprintf("logs_count=%di", res.LogsBenchmark.Count))
+		}
+		if res.HeadCadence != nil && res.HeadCadence.Error == "" {
+			fields = append(fields, fmt.Sprintf("new_heads=%di", res.HeadCadence.Heads),
+				fmt.Sprintf("head_gap_seconds=%.3f", res.HeadCadence.LongestGap.Seconds()))
+		}
+		if res.HeadAge > 0 {
+			fields = append(fields, fmt.Sprintf("head_age_seconds=%di", int64(res.HeadAge.Seconds())))
+		}
+
+		lines = append(lines, fmt.Sprintf("%s,%s %s %d", influxMeasurement, strings.Join(tags, ","), strings.Join(fields, ","), t.UnixNano()))
+	}
+	return lines
+}
+
+// printInflux prints the results as line protocol
+func (p printer) printInflux(nodes map[string]config.Node, results map[string]Result) {
+	for _, line := range InfluxLines(nodes, results, time.Now()) {
+		fmt.Fprintln(p.w, line)
+	}
+}
+
+// WriteInflux writes the results of a run to the InfluxDB v2 write API, sent as the JSON-RPC calls with rpc.Do.
+// Points carry their timestamp, so a retried write overwrites them instead of adding duplicates.
+func WriteInflux(ctx context.Context, conf config.InfluxDB, nodes map[string]config.Node, results map[string]Result) error {
+	lines := InfluxLines(nodes, results, time.Now())
+	if len(lines) == 0 {
+		return nil
+	}
+
+	query := url.Values{"org": {conf.Org}, "bucket": {conf.Bucket}, "precision": {"ns"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(conf.URL, "/")+"/api/v2/write?"+query.Encode(),
+		bytes.NewBufferString(strings.Join(lines, "\n")))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if conf.Token != "" {
+		req.Header.Set("Authorization", "Token "+conf.Token)
+	}
+
+	resp, err := rpc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
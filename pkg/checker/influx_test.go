@@ -0,0 +1,103 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+func TestInfluxEscapers(t *testing.T) {
+	tests := []struct {
+		name     string
+		escaper  interface{ Replace(string) string }
+		value    string
+		expected string
+	}{
+		{name: "plain tag", escaper: influxTagEscaper, value: "eth-mainnet", expected: "eth-mainnet"},
+		{name: "tag with comma", escaper: influxTagEscaper, value: "a,b", expected: `a\,b`},
+		{name: "tag with equals", escaper: influxTagEscaper, value: "k=v", expected: `k\=v`},
+		{name: "tag with space", escaper: influxTagEscaper, value: "my node", expected: `my\ node`},
+		{name: "tag with all", escaper: influxTagEscaper, value: "a b,c=d", expected: `a\ b\,c\=d`},
+		{name: "plain string", escaper: influxStringEscaper, value: "synced", expected: "synced"},
+		{name: "string with quote", escaper: influxStringEscaper, value: `say "hi"`, expected: `say \"hi\"`},
+		{name: "string with backslash", escaper: influxStringEscaper, value: `a\b`, expected: `a\\b`},
+		{name: "string with escaped quote", escaper: influxStringEscaper, value: `\"`, expected: `\\\"`},
+		// Spaces and commas are literal inside field strings
+		{name: "string with space and comma", escaper: influxStringEscaper, value: "a b,c", expected: "a b,c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.escaper.Replace(tt.value); got != tt.expected {
+				t.Errorf("Replace(%q) = %q, want %q", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInfluxLines(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	peers := int64(12)
+	tests := []struct {
+		name    string
+		nodes   map[string]config.Node
+		results map[string]Result
+		want    []string
+	}{
+		{
+			name:    "no results",
+			results: map[string]Result{},
+			want:    []string{},
+		},
+		{
+			name:  "minimal",
+			nodes: map[string]config.Node{"eth": {URL: "http://127.0.0.1:8545"}},
+			results: map[string]Result{
+				"eth": {Chain: "eth", SyncStatus: "synced", NodeBlockNum: 100, LatestBlockNum: 101, Diff: 1},
+			},
+			want: []string{
+				`nodestat,chain=eth,node=eth sync_status="synced",synced=true,node_block=100i,reference_block=101i,diff=1i 1700000000000000000`,
+			},
+		},
+		{
+			name: "escaped tags and optional fields",
+			nodes: map[string]config.Node{
+				"my node": {Service: "geth", Port: 8545, Namespace: "eth,main"},
+			},
+			results: map[string]Result{
+				"my node": {
+					Chain: "eth=1", Cluster: "prod cluster", SyncStatus: `behind "far"`,
+					NodeBlockNum: 90, LatestBlockNum: 100, Diff: 10, PeersCount: &peers,
+					Finality: &Finality{Lag: 64}, HeadAge: 90 * time.Second,
+				},
+			},
+			want: []string{
+				`nodestat,chain=eth\=1,cluster=prod\ cluster,namespace=eth\,main,node=my\ node ` +
+					`sync_status="behind \"far\"",synced=false,node_block=90i,reference_block=100i,diff=10i,peers=12i,finality_lag=64i,head_age_seconds=90i 1700000000000000000`,
+			},
+		},
+		{
+			name: "sorted by node",
+			nodes: map[string]config.Node{
+				"b": {URL: "http://b"},
+				"a": {URL: "http://a"},
+			},
+			results: map[string]Result{
+				"b": {Chain: "eth", SyncStatus: "synced"},
+				"a": {Chain: "eth", SyncStatus: "syncing"},
+			},
+			want: []string{
+				`nodestat,chain=eth,node=a sync_status="syncing",synced=false,node_block=0i,reference_block=0i,diff=0i 1700000000000000000`,
+				`nodestat,chain=eth,node=b sync_status="synced",synced=true,node_block=0i,reference_block=0i,diff=0i 1700000000000000000`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InfluxLines(tt.nodes, tt.results, at); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InfluxLines =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const (
+	defaultLogsRange       = 100
+	defaultLogsOffset      = 10
+	defaultLogsMaxDuration = 5 * time.Second
+)
+
+// LogsComparison represents the result of comparing node and reference logs for the same range
+type LogsComparison struct {
+	FromBlock      int64 `json:"from_block" yaml:"from_block"`
+	ToBlock        int64 `json:"to_block" yaml:"to_block"`
+	NodeCount      int   `json:"node_count" yaml:"node_count"`
+	ReferenceCount int   `json:"reference_count" yaml:"reference_count"`
+	Missing        int   `json:"missing" yaml:"missing"`
+	Duplicated     int   `json:"duplicated" yaml:"duplicated"`
+}
+
+type logEntry struct {
+	TransactionHash string `json:"transactionHash"`
+	LogIndex        string `json:"logIndex"`
+}
+
+// checkLogs runs the same eth_getLogs query against the node and the reference
+// and counts logs missing from or duplicated by the node
+func checkLogs(ctx context.Context, node config.Node, localPort int, apiConf config.PublicAPI, conf config.LogsCheckConfig, headBlock int64) (*LogsComparison, error) {
+	reference, err := referenceCaller(ctx, apiConf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Range == 0 {
+		conf.Range = defaultLogsRange
+	}
+	if conf.Offset == 0 {
+		conf.Offset = defaultLogsOffset
+	}
+
+	cmp := &LogsComparison{ToBlock: headBlock - conf.Offset}
+	cmp.FromBlock = cmp.ToBlock - conf.Range + 1
+	filter := logsFilter(conf, cmp.FromBlock, cmp.ToBlock)
+
+	nodeLogs, err := fetchLogs(nodeCaller(ctx, node, localPort), filter)
+	if err != nil {
+		return nil, err
+	}
+	refLogs, err := fetchLogs(reference, filter)
+	if err != nil {
+		return nil, fmt.Errorf("reference: %v", err)
+	}
+	cmp.NodeCount = len(nodeLogs)
+	cmp.ReferenceCount = len(refLogs)
+
+	seen := make(map[logEntry]int, len(nodeLogs))
+	for _, entry := range nodeLogs {
+		seen[entry]++
+		if seen[entry] == 2 {
+			cmp.Duplicated++
+		}
+	}
+	for _, entry := range refLogs {
+		if seen[entry] == 0 {
+			cmp.Missing++
+		}
+	}
+	return cmp, nil
+}
+
+// LogsBenchmark represents the outcome of a timed eth_getLogs query
+type LogsBenchmark struct {
+	FromBlock int64         `json:"from_block" yaml:"from_block"`
+	ToBlock   int64         `json:"to_block" yaml:"to_block"`
+	Count     int           `json:"count" yaml:"count"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+	Slow      bool          `json:"slow" yaml:"slow"`
+	Error     string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// benchmarkLogs runs an eth_getLogs query over the recent blocks below head and measures how long the node
+// takes to respond. The query is abandoned and reported as slow once it exceeds the configured duration.
+func benchmarkLogs(ctx context.Context, node config.Node, localPort int, conf config.LogsBenchmarkConfig, headBlock int64) *LogsBenchmark {
+	if conf.Range == 0 {
+		conf.Range = defaultLogsRange
+	}
+	if conf.Offset == 0 {
+		conf.Offset = defaultLogsOffset
+	}
+	if conf.MaxDuration == 0 {
+		conf.MaxDuration = defaultLogsMaxDuration
+	}
+
+	bench := &LogsBenchmark{ToBlock: headBlock - conf.Offset}
+	bench.FromBlock = bench.ToBlock - conf.Range + 1
+	filter := logsFilter(config.LogsCheckConfig{Address: conf.Address, Topics: conf.Topics}, bench.FromBlock, bench.ToBlock)
+
+	queryCtx, cancel := context.WithTimeout(ctx, conf.MaxDuration)
+	defer cancel()
+	start := time.Now()
+	raw, err := rpc.CallRaw(queryCtx, node, localPort, "eth_getLogs", filter)
+	bench.Duration = time.Since(start)
+	if err == nil {
+		var logs []json.RawMessage
+		if err = json.Unmarshal(raw, &logs); err == nil {
+			bench.Count = len(logs)
+		}
+	}
+	if err != nil {
+		bench.Error = err.Error()
+	}
+	bench.Slow = bench.Duration > conf.MaxDuration || queryCtx.Err() == context.DeadlineExceeded
+	return bench
+}
+
+func logsFilter(conf config.LogsCheckConfig, from, to int64) map[string]interface{} {
+	filter := map[string]interface{}{
+		"fromBlock": fmt.Sprintf("0x%x", from),
+		"toBlock":   fmt.Sprintf("0x%x", to),
+	}
+	if conf.Address != "" {
+		filter["address"] = conf.Address
+	}
+	if len(conf.Topics) > 0 {
+		filter["topics"] = conf.Topics
+	}
+	return filter
+}
+
+func fetchLogs(call rpcCaller, filter map[string]interface{}) ([]logEntry, error) {
+	raw, err := call("eth_getLogs", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []logEntry
+	if err := json.Unmarshal(raw, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,75 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/forward"
+)
+
+const defaultLogScanSince = 10 * time.Minute
+
+// defaultLogPatterns are matched when a node enables log scanning without its own patterns
+var defaultLogPatterns = []string{
+	"database corruption",
+	"Snapshot extension registration failed",
+	"OOMKilled",
+	"out of memory",
+}
+
+// LogMatch represents the number of log lines matching a pattern and the last matching line
+type LogMatch struct {
+	Pattern  string `json:"pattern" yaml:"pattern"`
+	Count    int    `json:"count" yaml:"count"`
+	LastLine string `json:"last_line" yaml:"last_line"`
+}
+
+// scanPodLogs tails the last minutes of the node pod logs and matches them against the error patterns
+func scanPodLogs(ctx context.Context, kube *forward.KubeClient, node config.Node, conf config.LogScanConfig) ([]LogMatch, error) {
+	if conf.Since == 0 {
+		conf.Since = defaultLogScanSince
+	}
+	if len(conf.Patterns) == 0 {
+		conf.Patterns = defaultLogPatterns
+	}
+
+	regexps := make([]*regexp.Regexp, 0, len(conf.Patterns))
+	for _, pattern := range conf.Patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log pattern %q: %v", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+
+	out, err := kube.Logs(ctx, node.Namespace, node.Service, conf.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]LogMatch, len(regexps))
+	for i, pattern := range conf.Patterns {
+		matches[i].Pattern = pattern
+	}
+	for _, line := range strings.Split(out, "\n") {
+		for i, re := range regexps {
+			if re.MatchString(line) {
+				matches[i].Count++
+				matches[i].LastLine = strings.TrimSpace(line)
+			}
+		}
+	}
+
+	// Only patterns that matched are reported
+	found := matches[:0]
+	for _, match := range matches {
+		if match.Count > 0 {
+			found = append(found, match)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,94 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// printMarkdown prints a one-line fleet summary and a GitHub-flavored table with one row per node
+func (p printer) printMarkdown(nodes map[string]config.Node, results map[string]Result) {
+	nodeNames := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	// Count nodes per status for the summary line, synced first
+	counts := make(map[string]int)
+	withCluster := false
+	for _, nodeName := range nodeNames {
+		res, ok := results[nodeName]
+		if !ok {
+			counts[statusUnreachable]++
+			continue
+		}
+		counts[res.SyncStatus]++
+		withCluster = withCluster || res.Cluster != ""
+	}
+	summary := []string{fmt.Sprintf("**%d of %d nodes synced**", counts["synced"], len(nodeNames))}
+	delete(counts, "synced")
+	for _, status := range sortedKeys(counts) {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[status], status))
+	}
+	fmt.Fprintln(p.w, strings.Join(summary, ", "))
+	fmt.Fprintln(p.w)
+
+	header := []string{"Node", "Chain", "Status", "Block", "Reference", "Diff", "Peers"}
+	if withCluster {
+		header = slices.Insert(header, 2, "Cluster")
+	}
+	fmt.Fprintf(p.w, "| %s |\n", strings.Join(header, " | "))
+	// The numbers of the last four columns are right aligned
+	separator := make([]string, len(header))
+	for i := range header {
+		separator[i] = "---"
+		if i >= len(header)-4 {
+			separator[i] = "---:"
+		}
+	}
+	fmt.Fprintf(p.w, "|%s|\n", strings.Join(separator, "|"))
+
+	for _, nodeName := range nodeNames {
+		row := []string{markdownEscape(nodeName), markdownEscape(nodes[nodeName].ChainName(nodeName)), "❌ " + statusUnreachable, "-", "-", "-", "-"}
+		res, ok := results[nodeName]
+		if ok {
+			row[2] = statusEmoji(res.SyncStatus) + " " + res.SyncStatus
+			row[3] = fmt.Sprint(res.NodeBlockNum)
+			row[4] = fmt.Sprint(res.LatestBlockNum)
+			row[5] = fmt.Sprint(res.Diff)
+			if res.PeersCount != nil {
+				row[6] = fmt.Sprint(*res.PeersCount)
+			}
+		}
+		if withCluster {
+			cluster := markdownEscape(res.Cluster)
+			if cluster == "" {
+				cluster = "-"
+			}
+			row = slices.Insert(row, 2, cluster)
+		}
+		fmt.Fprintf(p.w, "| %s |\n", strings.Join(row, " | "))
+	}
+	fmt.Fprintln(p.w)
+}
+
+// statusEmoji marks a sync status with the color of the table output
+func statusEmoji(status string) string {
+	switch statusColor(status) {
+	case colorGreen:
+		return "✅"
+	case colorYellow:
+		return "⏳"
+	default:
+		return "❌"
+	}
+}
+
+// markdownEscape escapes the pipes of a table cell
+func markdownEscape(cell string) string {
+	return strings.ReplaceAll(cell, "|", `\|`)
+}
@@ -0,0 +1,96 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// scrapeMetrics fetches the node's Prometheus metrics through its forwarded metrics endpoint
+// and returns the samples of the selected series keyed by name and labels
+func scrapeMetrics(ctx context.Context, node config.Node, localPort int) (map[string]float64, error) {
+	i := findEndpoint(node, config.EndpointMetrics)
+	if i < 0 {
+		return nil, errors.New("no metrics endpoint configured")
+	}
+
+	resp, err := httpGet(ctx, endpointURL(node, localPort, i))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics endpoint returned %s", resp.Status)
+	}
+
+	selected := make(map[string]bool, len(node.MetricsSeries))
+	for _, name := range node.MetricsSeries {
+		selected[name] = true
+	}
+
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		series, value, ok := parseSample(scanner.Text())
+		if !ok {
+			continue
+		}
+		name := series
+		if i := strings.Index(series, "{"); i >= 0 {
+			name = series[:i]
+		}
+		if selected[name] {
+			samples[series] = value
+		}
+	}
+	return samples, scanner.Err()
+}
+
+// parseSample parses a Prometheus text format sample line: name{labels} value [timestamp]
+func parseSample(line string) (string, float64, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", 0, false
+	}
+
+	// Label values may contain spaces, so split after the closing brace
+	rest := line
+	series := ""
+	if i := strings.LastIndex(line, "}"); i >= 0 {
+		series, rest = line[:i+1], line[i+1:]
+	} else {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return "", 0, false
+		}
+		series, rest = fields[0], strings.Join(fields[1:], " ")
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return series, value, true
+}
+
+// sortedKeys returns the keys of a metrics map in lexical order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
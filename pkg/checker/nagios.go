@@ -0,0 +1,121 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// Nagios plugin states, also the exit codes of the plugin
+const (
+	NagiosOK       = 0
+	NagiosWarning  = 1
+	NagiosCritical = 2
+	NagiosUnknown  = 3
+)
+
+var nagiosStateNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// NagiosThresholds are the warning and critical limits of the Nagios output:
+// the diff is a problem above its limits, the peers count below them
+type NagiosThresholds struct {
+	WarningDiff   int64
+	CriticalDiff  int64
+	WarningPeers  int64
+	CriticalPeers int64
+}
+
+// DefaultNagiosThresholds are the thresholds of the Nagios output when Options.Nagios is zero
+var DefaultNagiosThresholds = NagiosThresholds{WarningDiff: 50, CriticalDiff: 200, WarningPeers: 5, CriticalPeers: 2}
+
+// NagiosState returns the worst state of the nodes of the checker and the plugin output line with its perfdata.
+// Nodes whose checks failed are critical, nodes that are not synced or report problems at least warning.
+func (c *Checker) NagiosState(results map[string]Result) (int, string) {
+	return nagiosState(c.nodes(), results, c.Options.Nagios)
+}
+
+// nagiosState returns the state of the nodes as NagiosState does, with the thresholds of limits
+func nagiosState(nodes map[string]config.Node, results map[string]Result, limits NagiosThresholds) (int, string) {
+	if limits == (NagiosThresholds{}) {
+		limits = DefaultNagiosThresholds
+	}
+	if len(nodes) == 0 {
+		return NagiosUnknown, "NODESTAT UNKNOWN - no nodes to check"
+	}
+
+	nodeNames := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	state := NagiosOK
+	var summaries, perfdata []string
+	for _, nodeName := range nodeNames {
+		res, ok := results[nodeName]
+		if !ok {
+			state = max(state, NagiosCritical)
+			summaries = append(summaries, nodeName+" "+statusUnreachable)
+			continue
+		}
+
+		nodeState := NagiosOK
+		if res.SyncStatus != "synced" {
+			nodeState = NagiosWarning
+		}
+		switch {
+		case res.Diff > limits.CriticalDiff:
+			nodeState = NagiosCritical
+		case res.Diff > limits.WarningDiff:
+			nodeState = max(nodeState, NagiosWarning)
+		}
+		summary := fmt.Sprintf("%s %s, diff %d", nodeName, res.SyncStatus, res.Diff)
+
+		// Perfdata labels are prefixed with the node name when several nodes are checked
+		prefix := ""
+		if len(nodes) > 1 {
+			prefix = nodeName + "_"
+		}
+		perfdata = append(perfdata, fmt.Sprintf("%sdiff=%d;%d;%d", prefix, res.Diff, limits.WarningDiff, limits.CriticalDiff))
+		if res.PeersCount != nil {
+			peers := *res.PeersCount
+			switch {
+			case peers < limits.CriticalPeers:
+				nodeState = NagiosCritical
+			case peers < limits.WarningPeers:
+				nodeState = max(nodeState, NagiosWarning)
+			}
+			summary += fmt.Sprintf(", %d peers", peers)
+			perfdata = append(perfdata, fmt.Sprintf("%speers=%d;%d;%d", prefix, peers, limits.WarningPeers, limits.CriticalPeers))
+		}
+
+		for _, problem := range res.Problems() {
+			if problem.Severity == config.SeverityCritical {
+				nodeState = NagiosCritical
+			} else {
+				nodeState = max(nodeState, NagiosWarning)
+			}
+			summary += ", " + problem.Message
+		}
+		if bench := res.LogsBenchmark; bench != nil {
+			perfdata = append(perfdata, fmt.Sprintf("%slogs_latency=%.3fs", prefix, bench.Duration.Seconds()))
+		}
+		if cadence := res.HeadCadence; cadence != nil && cadence.Error == "" {
+			perfdata = append(perfdata, fmt.Sprintf("%shead_gap=%.3fs", prefix, cadence.LongestGap.Seconds()))
+		}
+		if res.Finality != nil {
+			perfdata = append(perfdata, fmt.Sprintf("%sfinality_lag=%d", prefix, res.Finality.Lag))
+		}
+		if res.TxPool != nil {
+			perfdata = append(perfdata, fmt.Sprintf("%stxpool_pending=%d %stxpool_queued=%d", prefix, res.TxPool.Pending, prefix, res.TxPool.Queued))
+		}
+
+		state = max(state, nodeState)
+		summaries = append(summaries, summary)
+	}
+
+	line := fmt.Sprintf("NODESTAT %s - %s | %s", nagiosStateNames[state], strings.Join(summaries, "; "), strings.Join(perfdata, " "))
+	return state, strings.TrimSuffix(line, " | ")
+}
@@ -0,0 +1,501 @@
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats
+const (
+	OutputText     = "text"
+	OutputTable    = "table"
+	OutputCSV      = "csv"
+	OutputNagios   = "nagios"
+	OutputMarkdown = "markdown"
+	OutputInflux   = "influx"
+	OutputJSON     = "json"
+	OutputYAML     = "yaml"
+)
+
+// Report represents the structured output of a single run
+type Report struct {
+	Nodes  map[string]Result `json:"nodes" yaml:"nodes"`
+	Chains []ChainGroup      `json:"chains,omitempty" yaml:"chains,omitempty"`
+	// Assertions is the aggregate verdict of the assertions of every checked node
+	Assertions *AssertionSummary `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+}
+
+func ValidOutput(format string) bool {
+	switch format {
+	case OutputText, OutputTable, OutputCSV, OutputNagios, OutputMarkdown, OutputInflux, OutputJSON, OutputYAML:
+		return true
+	}
+	return false
+}
+
+// printer writes results to w with the output options of a Checker
+type printer struct {
+	w    io.Writer
+	opts Options
+}
+
+// WriteReport writes the results of a run to w in the requested format.
+// Structured formats emit one document per run, so daemon mode produces a stream of documents.
+// The table, CSV and Markdown list the nodes of the checker without a result as unreachable.
+func (c *Checker) WriteReport(w io.Writer, format string, results map[string]Result, groups []ChainGroup) error {
+	p := printer{w: w, opts: c.Options}
+	nodes := c.nodes()
+	if p.opts.Quiet && format != OutputNagios && format != OutputInflux {
+		nodes, results, groups = problems(nodes, results, groups)
+	}
+	report := Report{Nodes: results, Chains: groups, Assertions: SummarizeAssertions(results)}
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case OutputYAML:
+		data, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", data)
+		return err
+	case OutputCSV:
+		return writeCSV(w, nodes, results)
+	case OutputNagios:
+		_, line := nagiosState(nodes, results, p.opts.Nagios)
+		_, err := fmt.Fprintln(w, line)
+		return err
+	case OutputInflux:
+		p.printInflux(nodes, results)
+		return nil
+	case OutputMarkdown:
+		p.printMarkdown(nodes, results)
+		return nil
+	case OutputTable:
+		p.printTable(nodes, results)
+		p.printFleet(groups)
+		return nil
+	default:
+		p.printResults(results)
+		p.printFleet(groups)
+		return nil
+	}
+}
+
+// WriteSummary writes what follows the streamed results of a run: the chain groups in text format,
+// other formats are not streamed and get the whole report as with WriteReport.
+func (c *Checker) WriteSummary(w io.Writer, format string, results map[string]Result, groups []ChainGroup) error {
+	if format != OutputText {
+		return c.WriteReport(w, format, results, groups)
+	}
+	p := printer{w: w, opts: c.Options}
+	if p.opts.Quiet {
+		_, _, groups = problems(c.nodes(), results, groups)
+	}
+	p.printFleet(groups)
+	if summary := SummarizeAssertions(results); summary != nil {
+		fmt.Fprintf(p.w, "Assertions: %d passed, %d failed, verdict: %s\n", summary.Passed, summary.Failed, summary.Verdict)
+	}
+	return nil
+}
+
+// healthy reports whether a node needs no attention, i.e. it is synced and reports no problems
+func healthy(res Result) bool {
+	return res.SyncStatus == "synced" && len(res.Problems()) == 0
+}
+
+// problems returns the nodes that failed their checks or are not healthy with their results,
+// and the chain groups with alerts
+func problems(nodes map[string]config.Node, results map[string]Result, groups []ChainGroup) (map[string]config.Node, map[string]Result, []ChainGroup) {
+	badNodes := make(map[string]config.Node)
+	badResults := make(map[string]Result)
+	for nodeName, node := range nodes {
+		res, ok := results[nodeName]
+		if ok && healthy(res) {
+			continue
+		}
+		badNodes[nodeName] = node
+		if ok {
+			badResults[nodeName] = res
+		}
+	}
+
+	var badGroups []ChainGroup
+	for _, group := range groups {
+		if len(group.Alerts) > 0 {
+			badGroups = append(badGroups, group)
+		}
+	}
+	return badNodes, badResults, badGroups
+}
+
+// printResults prints the results of all checked nodes grouped by cluster
+func (p printer) printResults(results map[string]Result) {
+	nodeNames := make([]string, 0, len(results))
+	clusters := make(map[string]bool)
+	for nodeName, res := range results {
+		nodeNames = append(nodeNames, nodeName)
+		clusters[res.Cluster] = true
+	}
+	sort.Slice(nodeNames, func(i, j int) bool {
+		a, b := results[nodeNames[i]], results[nodeNames[j]]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		return nodeNames[i] < nodeNames[j]
+	})
+
+	cluster := ""
+	for i, nodeName := range nodeNames {
+		res := results[nodeName]
+		if len(clusters) > 1 && (i == 0 || res.Cluster != cluster) {
+			cluster = res.Cluster
+			name := cluster
+			if name == "" {
+				name = "direct"
+			}
+			fmt.Fprintf(p.w, "=== Cluster: %s ===\n", name)
+		}
+		p.printNode(nodeName, res, false)
+	}
+}
+
+// PrintResult writes the result of a single node to w in text format, used to stream results as nodes finish.
+// Streamed results are not grouped, so the cluster of the node is printed with it.
+// Healthy nodes are skipped in quiet mode.
+func (c *Checker) PrintResult(w io.Writer, nodeName string, res Result) {
+	if c.Options.Quiet && healthy(res) {
+		return
+	}
+	printer{w: w, opts: c.Options}.printNode(nodeName, res, true)
+}
+
+// printNode prints the result of a single node, with its cluster if withCluster is set
+func (p printer) printNode(nodeName string, res Result, withCluster bool) {
+	fmt.Fprintf(p.w, "Node: %s\n", nodeName)
+	if withCluster && res.Cluster != "" {
+		fmt.Fprintf(p.w, "Cluster: %s\n", res.Cluster)
+	}
+	fmt.Fprintf(p.w, "Sync status: %s\n", res.SyncStatus)
+	if res.BlockHashMismatch != "" {
+		fmt.Fprintf(p.w, "Possible fork: %s\n", res.BlockHashMismatch)
+	}
+	if progress := res.SyncProgress; progress != nil {
+		fmt.Fprintf(p.w, "Sync progress: block %d of %d (%.1f%% since block %d)\n",
+			progress.CurrentBlock, progress.HighestBlock, progress.Ratio()*100, progress.StartingBlock)
+		if progress.KnownStates > 0 {
+			fmt.Fprintf(p.w, "State download: %d of %d known states\n", progress.PulledStates, progress.KnownStates)
+		}
+		if progress.SyncedAccounts > 0 || progress.SyncedStorage > 0 || progress.SyncedBytecodes > 0 {
+			fmt.Fprintf(p.w, "Snap sync: %d accounts, %d storage slots, %d bytecodes downloaded\n",
+				progress.SyncedAccounts, progress.SyncedStorage, progress.SyncedBytecodes)
+		}
+	}
+	if res.Healing != nil {
+		eta := "unknown"
+		if res.Healing.ETA > 0 {
+			eta = formatDays(res.Healing.ETA)
+		}
+		fmt.Fprintf(p.w, "Healing: %d trie nodes healed, %d trie nodes and %d bytecodes pending, %.0f nodes/s, ETA %s\n",
+			res.Healing.HealedTrienodes, res.Healing.PendingTrienodes, res.Healing.PendingBytecodes, res.Healing.Rate, eta)
+	}
+	if sync := res.StagedSync; sync != nil && sync.Stage != "" {
+		fmt.Fprintf(p.w, "Erigon stage: %s at block %d of %d (%.1f%%)\n", sync.Stage, sync.StageBlock, sync.HighestBlock, sync.Progress*100)
+	}
+	fmt.Fprintf(p.w, "Node block number: %d\n", res.NodeBlockNum)
+	if res.HeadAge > 0 {
+		fmt.Fprintf(p.w, "Head block age: %s behind wall clock\n", res.HeadAge)
+	}
+	var reference []string
+	if res.ReferenceSource != "" {
+		reference = append(reference, "from "+res.ReferenceSource)
+	}
+	if p.opts.RPC.Verbose > 0 && res.ReferenceLatency > 0 {
+		reference = append(reference, fmt.Sprintf("answered in %s", res.ReferenceLatency.Round(time.Millisecond)))
+	}
+	if res.ReferenceAge > 0 {
+		reference = append(reference, fmt.Sprintf("cached %s ago", res.ReferenceAge.Round(time.Second)))
+	}
+	if len(reference) > 0 {
+		fmt.Fprintf(p.w, "Scanner block number: %d (%s)\n", res.LatestBlockNum, strings.Join(reference, ", "))
+	} else {
+		fmt.Fprintf(p.w, "Scanner block number: %d\n", res.LatestBlockNum)
+	}
+	fmt.Fprintf(p.w, "Diff with mainnet: %d\n", res.Diff)
+	if p.opts.RPC.Verbose > 0 && len(res.RPCLatency) > 0 {
+		methods := make([]string, 0, len(res.RPCLatency))
+		for method := range res.RPCLatency {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		fmt.Fprintln(p.w, "RPC latency:")
+		for _, method := range methods {
+			latency := res.RPCLatency[method]
+			line := fmt.Sprintf("  %s: %d calls, mean %s, max %s", method, latency.Calls,
+				latency.Mean.Round(time.Millisecond), latency.Max.Round(time.Millisecond))
+			if latency.Errors > 0 {
+				line += fmt.Sprintf(", %d failed", latency.Errors)
+			}
+			fmt.Fprintln(p.w, line)
+		}
+	}
+	if res.SyncETA != nil {
+		eta := "not catching up"
+		if res.SyncETA.ETA > 0 {
+			eta = "~" + formatDays(res.SyncETA.ETA.Round(time.Minute))
+		}
+		fmt.Fprintf(p.w, "Sync speed: %.0f blocks/min, ETA %s\n", res.SyncETA.Rate, eta)
+	}
+	if res.PeersCount != nil {
+		fmt.Fprintf(p.w, "Peers count: %d\n", *res.PeersCount)
+	}
+	if peers := res.Peers; peers != nil {
+		note := ""
+		if peers.Inbound == 0 && peers.Outbound > 0 {
+			note = " (no inbound peers, the P2P port may be unreachable)"
+		}
+		fmt.Fprintf(p.w, "Peer connections: %d outbound, %d inbound%s\n", peers.Outbound, peers.Inbound, note)
+		// The diversity breakdown prints the clients itself
+		if len(peers.Clients) > 0 && res.PeerDiversity == nil {
+			fmt.Fprintf(p.w, "Peer clients: %s\n", formatDistribution(peers.Clients))
+		}
+		for _, peer := range peers.Peers {
+			direction := "outbound"
+			if peer.Inbound {
+				direction = "inbound"
+			}
+			if peer.Trusted {
+				direction += ", trusted"
+			}
+			if peer.Static {
+				direction += ", static"
+			}
+			fmt.Fprintf(p.w, "  Peer %.16s %s %s (%s)\n", peer.ID, peer.Address, peer.Name, direction)
+		}
+	}
+	if len(res.MissingStaticPeers) > 0 {
+		fmt.Fprintf(p.w, "Missing static peers: %s\n", strings.Join(res.MissingStaticPeers, ", "))
+	}
+	if len(res.MissingTrustedPeers) > 0 {
+		fmt.Fprintf(p.w, "Missing trusted peers: %s\n", strings.Join(res.MissingTrustedPeers, ", "))
+	}
+	for _, failure := range res.BootnodeFailures {
+		fmt.Fprintf(p.w, "Bootnode unreachable: %s\n", failure)
+	}
+	if res.P2PReachability != "" {
+		fmt.Fprintf(p.w, "P2P port: %s\n", res.P2PReachability)
+	}
+	if res.AdvertisementIssue != "" {
+		fmt.Fprintf(p.w, "Enode advertisement mismatch: %s\n", res.AdvertisementIssue)
+	}
+	if res.Discovery != nil {
+		if res.Discovery.TableSize >= 0 {
+			fmt.Fprintf(p.w, "Discovery table size: %d\n", res.Discovery.TableSize)
+		}
+		fmt.Fprintf(p.w, "Peer churn (%s): +%d/-%d\n", res.Discovery.Interval, res.Discovery.PeersAdded, res.Discovery.PeersDropped)
+	}
+	if res.PeerDiversity != nil {
+		fmt.Fprintf(p.w, "Peer clients: %s\n", formatDistribution(res.PeerDiversity.Clients))
+		if len(res.PeerDiversity.Countries) > 0 {
+			fmt.Fprintf(p.w, "Peer countries: %s\n", formatDistribution(res.PeerDiversity.Countries))
+			fmt.Fprintf(p.w, "Peer ASNs: %s\n", formatDistribution(res.PeerDiversity.ASNs))
+		}
+		for _, warning := range res.PeerDiversity.Warnings {
+			fmt.Fprintf(p.w, "Eclipse risk: %s\n", warning)
+		}
+	}
+	for _, fork := range res.ForkReadiness {
+		readiness := "ready"
+		if !fork.Ready {
+			readiness = "NOT READY"
+		}
+		fmt.Fprintf(p.w, "Fork %s: %s, %s\n", fork.Fork, readiness, fork.Detail)
+	}
+	for _, advisory := range res.ForkAdvisories {
+		fmt.Fprintf(p.w, "Fork %s activates %s\n", advisory.Fork, advisory.Countdown)
+		if advisory.Warning != "" {
+			fmt.Fprintf(p.w, "Fork %s warning: %s\n", advisory.Fork, advisory.Warning)
+		}
+	}
+	if res.FeeTrend != nil {
+		fmt.Fprintf(p.w, "Base fee: %d wei, gas limit: %d (block %d)\n", res.FeeTrend.BaseFeePerGas, res.FeeTrend.GasLimit, res.FeeTrend.Block)
+		for _, divergence := range res.FeeTrend.Divergences {
+			fmt.Fprintf(p.w, "Fee divergence: %s\n", divergence)
+		}
+	}
+	if res.TxGossip != nil {
+		fmt.Fprintf(p.w, "Pending transactions received (%s): %d\n", res.TxGossip.Window, res.TxGossip.Received)
+		if res.TxGossip.Received == 0 {
+			fmt.Fprintln(p.w, "Warning: node receives no transaction gossip, mempool is stale")
+		}
+	}
+	if res.InclusionLatency != nil {
+		fmt.Fprintf(p.w, "Inclusion latency: p50 %s, p95 %s (%d samples, %d failed)\n",
+			res.InclusionLatency.P50.Round(time.Millisecond), res.InclusionLatency.P95.Round(time.Millisecond),
+			res.InclusionLatency.Samples, res.InclusionLatency.Failed)
+		if res.InclusionLatency.LastError != "" {
+			fmt.Fprintf(p.w, "Inclusion last error: %s\n", res.InclusionLatency.LastError)
+		}
+	}
+	for _, problem := range res.FeeHistoryProblems {
+		fmt.Fprintf(p.w, "Fee history problem: %s\n", problem)
+	}
+	for _, assertion := range res.Assertions {
+		if assertion.Passed {
+			fmt.Fprintf(p.w, "Assertion %s: passed\n", assertion.Assertion)
+		} else {
+			fmt.Fprintf(p.w, "Assertion %s: failed, %s\n", assertion.Assertion, assertion.Error)
+		}
+	}
+	for _, check := range res.Checks {
+		if check.Passed {
+			fmt.Fprintf(p.w, "Check %s: passed\n", check.Name)
+		} else {
+			fmt.Fprintf(p.w, "Check %s failed (%s): %s\n", check.Name, check.Severity, check.Error)
+		}
+	}
+	if archive := res.Archive; archive != nil {
+		if archive.Available {
+			fmt.Fprintf(p.w, "Archive state at block %d: available\n", archive.Block)
+		} else {
+			fmt.Fprintf(p.w, "Archive state at block %d: missing (%s)\n", archive.Block, archive.Error)
+		}
+	}
+	if bench := res.TraceBenchmark; bench != nil {
+		switch {
+		case !bench.Enabled:
+			fmt.Fprintf(p.w, "Trace API disabled: %s\n", bench.Error)
+		case bench.Error != "":
+			fmt.Fprintf(p.w, "Trace %s of block %d failed after %s: %s\n", bench.Method, bench.Block, bench.Duration.Round(time.Millisecond), bench.Error)
+		case bench.Slow:
+			fmt.Fprintf(p.w, "Trace %s of block %d is slow: %s\n", bench.Method, bench.Block, bench.Duration.Round(time.Millisecond))
+		default:
+			fmt.Fprintf(p.w, "Trace %s of block %d: %s\n", bench.Method, bench.Block, bench.Duration.Round(time.Millisecond))
+		}
+	}
+	if finality := res.Finality; finality != nil {
+		fmt.Fprintf(p.w, "Finalized block: %d (%d behind head), safe block: %d\n", finality.Finalized, finality.Lag, finality.Safe)
+		if finality.Stalled {
+			fmt.Fprintf(p.w, "Finality stalled: finalized block %d blocks behind head\n", finality.Lag)
+		}
+	}
+	if fees := res.GasPrice; fees != nil {
+		if fees.ReferenceGasPrice > 0 {
+			fmt.Fprintf(p.w, "Gas price: %s (reference %s), base fee %s (reference %s)\n",
+				formatGwei(fees.GasPrice), formatGwei(fees.ReferenceGasPrice), formatGwei(fees.BaseFee), formatGwei(fees.ReferenceBaseFee))
+		} else {
+			fmt.Fprintf(p.w, "Gas price: %s, base fee %s\n", formatGwei(fees.GasPrice), formatGwei(fees.BaseFee))
+		}
+		for _, divergence := range fees.Divergences {
+			fmt.Fprintf(p.w, "Fee divergence: %s\n", divergence)
+		}
+	}
+	if res.TxPool != nil {
+		fmt.Fprintf(p.w, "Txpool: %d pending, %d queued\n", res.TxPool.Pending, res.TxPool.Queued)
+		for _, warning := range res.TxPool.Warnings {
+			fmt.Fprintf(p.w, "Txpool warning: %s\n", warning)
+		}
+	}
+	if bench := res.LogsBenchmark; bench != nil {
+		switch {
+		case bench.Slow:
+			fmt.Fprintf(p.w, "Logs query %d-%d is slow: %s\n", bench.FromBlock, bench.ToBlock, bench.Duration.Round(time.Millisecond))
+		case bench.Error != "":
+			fmt.Fprintf(p.w, "Logs query %d-%d failed after %s: %s\n", bench.FromBlock, bench.ToBlock, bench.Duration.Round(time.Millisecond), bench.Error)
+		default:
+			fmt.Fprintf(p.w, "Logs query %d-%d: %d logs in %s\n", bench.FromBlock, bench.ToBlock, bench.Count, bench.Duration.Round(time.Millisecond))
+		}
+	}
+	if cadence := res.HeadCadence; cadence != nil {
+		switch {
+		case cadence.Error != "":
+			fmt.Fprintf(p.w, "Head cadence error: %s\n", cadence.Error)
+		case cadence.Stalled:
+			fmt.Fprintf(p.w, "Head cadence stalled: no new head for %s, %d new heads in %s\n",
+				cadence.LongestGap.Round(time.Second), cadence.Heads, cadence.Window)
+		default:
+			fmt.Fprintf(p.w, "Head cadence: %d new heads in %s, every %s (expected %s), longest gap %s\n", cadence.Heads, cadence.Window,
+				cadence.MeanInterval.Round(time.Millisecond), cadence.BlockTime, cadence.LongestGap.Round(time.Millisecond))
+		}
+	}
+	if res.Logs != nil {
+		fmt.Fprintf(p.w, "Logs %d-%d: node %d, reference %d, missing %d, duplicated %d\n",
+			res.Logs.FromBlock, res.Logs.ToBlock, res.Logs.NodeCount, res.Logs.ReferenceCount, res.Logs.Missing, res.Logs.Duplicated)
+	}
+	if res.Receipts != nil {
+		fmt.Fprintf(p.w, "Receipts in last %d blocks: %d of %d missing\n", res.Receipts.Blocks, res.Receipts.Missing, res.Receipts.Transactions)
+	}
+	for _, endpoint := range res.Endpoints {
+		if endpoint.Healthy {
+			fmt.Fprintf(p.w, "Endpoint %s (%s): ok, %s\n", endpoint.Name, endpoint.Type, endpoint.Latency.Round(time.Millisecond))
+		} else {
+			fmt.Fprintf(p.w, "Endpoint %s (%s): failed, %s\n", endpoint.Name, endpoint.Type, endpoint.Error)
+		}
+	}
+	if cl := res.Consensus; cl != nil {
+		state := "synced"
+		switch {
+		case cl.ELOffline:
+			state = "execution client offline"
+		case cl.IsSyncing:
+			state = fmt.Sprintf("syncing, %d slots behind", cl.SyncDistance)
+		case cl.IsOptimistic:
+			state = "optimistic"
+		}
+		fmt.Fprintf(p.w, "Consensus client: %s, head slot %d, %d peers, %s\n", cl.Version, cl.HeadSlot, cl.PeersCount, state)
+	}
+	for _, series := range sortedKeys(res.Metrics) {
+		fmt.Fprintf(p.w, "Metric %s: %g\n", series, res.Metrics[series])
+	}
+	for _, match := range res.LogMatches {
+		fmt.Fprintf(p.w, "Log pattern %q matched %d times, last: %s\n", match.Pattern, match.Count, match.LastLine)
+	}
+	if rel := res.Release; rel != nil {
+		if rel.Version == versionUnknown {
+			fmt.Fprintf(p.w, "Client: %s, version unknown, latest %s\n", rel.Client, rel.Latest)
+		} else {
+			fmt.Fprintf(p.w, "Client: %s %s, latest %s, %d releases behind\n", rel.Client, rel.Version, rel.Latest, rel.Behind)
+		}
+		if len(rel.SecurityBehind) > 0 {
+			fmt.Fprintf(p.w, "Warning: unapplied security releases: %s\n", strings.Join(rel.SecurityBehind, ", "))
+		}
+	}
+	for _, advisory := range res.SecurityAdvisories {
+		label := "Warning"
+		if advisory.Critical() {
+			label = "CRITICAL"
+		}
+		fmt.Fprintf(p.w, "%s: affected by %s (%s severity, fixed in %s) %s\n", label, advisory.ID, advisory.Severity, advisory.Fixed, advisory.URL)
+	}
+	for _, canary := range res.Canaries {
+		fmt.Fprintf(p.w, "Canary %s (%s): %.1f%% success over %d runs, p50 %s, p95 %s\n", canary.Name, canary.Type,
+			canary.SuccessRate*100, canary.Runs, canary.P50.Round(time.Millisecond), canary.P95.Round(time.Millisecond))
+		if canary.LastError != "" {
+			fmt.Fprintf(p.w, "Canary %s last error: %s\n", canary.Name, canary.LastError)
+		}
+	}
+	if res.WSStability != nil {
+		fmt.Fprintf(p.w, "WebSocket (%s): %d notifications, %d disconnects, %d resubscribes, %d missed blocks\n",
+			res.WSStability.Window, res.WSStability.Notifications, res.WSStability.Disconnects, res.WSStability.Resubscribes, res.WSStability.MissedBlocks)
+		if res.WSStability.Error != "" {
+			fmt.Fprintf(p.w, "WebSocket error: %s\n", res.WSStability.Error)
+		}
+	}
+	if res.SubscriptionDrops != nil {
+		fmt.Fprintf(p.w, "Subscription drops over %s: %.2f/h dropped, %.2f/h missed blocks\n",
+			res.SubscriptionDrops.Observed, res.SubscriptionDrops.DropsPerHour, res.SubscriptionDrops.MissedPerHour)
+	}
+	for _, err := range res.Errors {
+		fmt.Fprintf(p.w, "Error %s\n", err)
+	}
+	fmt.Fprintln(p.w)
+}
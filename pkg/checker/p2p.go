@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const p2pDialTimeout = 5 * time.Second
+
+// p2pAddress returns the P2P address the node should be reachable at: its external address,
+// or the address of the enode it advertises when none is configured
+func p2pAddress(ctx context.Context, node config.Node, localPort int) (string, error) {
+	if node.ExternalAddress != "" {
+		return node.ExternalAddress, nil
+	}
+	info, err := fetchNodeInfo(ctx, node, localPort)
+	if err != nil {
+		return "", err
+	}
+	advertised, err := parseBootnode(info.Enode)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(advertised.Host, advertised.TCPPort), nil
+}
+
+// checkP2PReachability verifies that the P2P address addr accepts TCP connections.
+// Without a probe URL the address is dialed directly, otherwise the probe service is asked
+// to dial it with GET <probeURL>?host=<host>&port=<port> and must answer with a 2xx status.
+func checkP2PReachability(ctx context.Context, addr string, probeURL string) error {
+	if probeURL == "" {
+		dialer := &net.Dialer{Timeout: p2pDialTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"host": {host}, "port": {port}}
+
+	resp, err := httpGet(ctx, probeURL+"?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("probe returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// NodeInfo represents the relevant part of the admin_nodeInfo response
+type NodeInfo struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Enode string `json:"enode"`
+	ENR   string `json:"enr"`
+	IP    string `json:"ip"`
+	Ports struct {
+		Discovery int `json:"discovery"`
+		Listener  int `json:"listener"`
+	} `json:"ports"`
+	Protocols struct {
+		Eth struct {
+			Config map[string]interface{} `json:"config"`
+		} `json:"eth"`
+	} `json:"protocols"`
+}
+
+func fetchNodeInfo(ctx context.Context, node config.Node, localPort int) (NodeInfo, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "admin_nodeInfo")
+	if err != nil {
+		return NodeInfo{}, err
+	}
+
+	var info NodeInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return NodeInfo{}, err
+	}
+	return info, nil
+}
+
+// checkAdvertisement compares the address advertised in the node's enode with the configured
+// external address and returns a description of the mismatch, if any
+func checkAdvertisement(ctx context.Context, node config.Node, localPort int) (string, error) {
+	info, err := fetchNodeInfo(ctx, node, localPort)
+	if err != nil {
+		return "", err
+	}
+
+	advertised, err := parseBootnode(info.Enode)
+	if err != nil {
+		return "", err
+	}
+
+	host, port, err := net.SplitHostPort(node.ExternalAddress)
+	if err != nil {
+		return "", err
+	}
+	expectedIPs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return "", err
+	}
+
+	ipMatches := false
+	for _, ip := range expectedIPs {
+		if ip == advertised.Host {
+			ipMatches = true
+			break
+		}
+	}
+
+	var problems []string
+	if !ipMatches {
+		problems = append(problems, fmt.Sprintf("advertised IP %s, expected %s", advertised.Host, host))
+	}
+	if advertised.TCPPort != port {
+		problems = append(problems, fmt.Sprintf("advertised port %s, expected %s", advertised.TCPPort, port))
+	}
+	return strings.Join(problems, "; "), nil
+}
@@ -0,0 +1,261 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// PeerInfo represents a single entry of the admin_peers response
+type PeerInfo struct {
+	ID      string `json:"id"`
+	Enode   string `json:"enode"`
+	Name    string `json:"name"`
+	Network struct {
+		LocalAddress  string `json:"localAddress"`
+		RemoteAddress string `json:"remoteAddress"`
+		Inbound       bool   `json:"inbound"`
+		Trusted       bool   `json:"trusted"`
+		Static        bool   `json:"static"`
+	} `json:"network"`
+}
+
+// fetchPeers returns the peers the node is currently connected to
+func fetchPeers(ctx context.Context, node config.Node, localPort int) ([]PeerInfo, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "admin_peers")
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []PeerInfo
+	if err := json.Unmarshal(raw, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// PeerSummary represents a node's connected peers by direction and client, from admin_peers
+type PeerSummary struct {
+	Inbound  int            `json:"inbound" yaml:"inbound"`
+	Outbound int            `json:"outbound" yaml:"outbound"`
+	Clients  map[string]int `json:"clients" yaml:"clients"`
+	// Peers is set with Options.PeerDetails
+	Peers []Peer `json:"peers,omitempty" yaml:"peers,omitempty"`
+}
+
+// Peer represents a connected peer of the detailed listing
+type Peer struct {
+	ID      string `json:"id" yaml:"id"`
+	Name    string `json:"name" yaml:"name"`
+	Address string `json:"address" yaml:"address"`
+	Inbound bool   `json:"inbound" yaml:"inbound"`
+	Trusted bool   `json:"trusted,omitempty" yaml:"trusted,omitempty"`
+	Static  bool   `json:"static,omitempty" yaml:"static,omitempty"`
+}
+
+// checkPeerSummary counts the node's inbound and outbound peers and their clients.
+// A node without inbound peers is usually not reachable from the internet. details lists every peer.
+func checkPeerSummary(ctx context.Context, node config.Node, localPort int, details bool) (*PeerSummary, error) {
+	peers, err := fetchPeers(ctx, node, localPort)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &PeerSummary{Clients: make(map[string]int)}
+	for _, peer := range peers {
+		if peer.Network.Inbound {
+			summary.Inbound++
+		} else {
+			summary.Outbound++
+		}
+		summary.Clients[peerClient(peer.Name)]++
+		if details {
+			summary.Peers = append(summary.Peers, Peer{
+				ID:      peer.ID,
+				Name:    peer.Name,
+				Address: peer.Network.RemoteAddress,
+				Inbound: peer.Network.Inbound,
+				Trusted: peer.Network.Trusted,
+				Static:  peer.Network.Static,
+			})
+		}
+	}
+	return summary, nil
+}
+
+// checkPeering verifies that the node is connected to every configured static and trusted peer
+// and returns the enode URLs of the missing ones
+func checkPeering(ctx context.Context, node config.Node, localPort int) ([]string, []string, error) {
+	peers, err := fetchPeers(ctx, node, localPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	connected := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		connected[enodeID(peer.Enode)] = true
+	}
+
+	return missingPeers(node.StaticPeers, connected), missingPeers(node.TrustedPeers, connected), nil
+}
+
+func missingPeers(expected []string, connected map[string]bool) []string {
+	var missing []string
+	for _, enode := range expected {
+		if !connected[enodeID(enode)] {
+			missing = append(missing, enode)
+		}
+	}
+	return missing
+}
+
+// enodeID extracts the public key part of an enode URL, so peers are matched
+// regardless of the IP address they are currently reachable at
+func enodeID(enode string) string {
+	id := strings.TrimPrefix(enode, "enode://")
+	if i := strings.Index(id, "@"); i >= 0 {
+		id = id[:i]
+	}
+	return strings.ToLower(id)
+}
+
+// PeerDiversity represents the distribution of a node's peers by client, country and ASN
+type PeerDiversity struct {
+	Clients map[string]int `json:"clients" yaml:"clients"`
+	// Countries and ASNs are only reported with a geoip_url
+	Countries map[string]int `json:"countries,omitempty" yaml:"countries,omitempty"`
+	ASNs      map[string]int `json:"asns,omitempty" yaml:"asns,omitempty"`
+	Warnings  []string       `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// geoIPEntry represents a single entry of the ip-api compatible batch response
+type geoIPEntry struct {
+	Query       string `json:"query"`
+	CountryCode string `json:"countryCode"`
+	AS          string `json:"as"`
+}
+
+// checkPeerDiversity groups the node's peers by client implementation, and by country and ASN when
+// geoIPURL is set, flagging peer sets dominated by a single client or hosting provider.
+// The peer IPs are sent to geoIPURL, so the geography is only looked up when one is configured.
+func checkPeerDiversity(ctx context.Context, node config.Node, localPort int, geoIPURL string) (*PeerDiversity, error) {
+	peers, err := fetchPeers(ctx, node, localPort)
+	if err != nil {
+		return nil, err
+	}
+
+	diversity := &PeerDiversity{
+		Clients:   make(map[string]int),
+		Countries: make(map[string]int),
+		ASNs:      make(map[string]int),
+	}
+	ips := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		diversity.Clients[peerClient(peer.Name)]++
+		if host, _, err := net.SplitHostPort(peer.Network.RemoteAddress); err == nil {
+			ips = append(ips, host)
+		}
+	}
+
+	if geoIPURL != "" {
+		geo, err := lookupGeoIP(ctx, geoIPURL, ips)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range geo {
+			diversity.Countries[orUnknown(entry.CountryCode)]++
+			diversity.ASNs[orUnknown(entry.AS)]++
+		}
+	}
+
+	// A single peer is not a meaningful sample
+	if len(peers) > 1 {
+		if len(diversity.Clients) == 1 {
+			diversity.Warnings = append(diversity.Warnings, "all peers run the same client")
+		}
+		if geoIPURL != "" && len(diversity.ASNs) == 1 {
+			diversity.Warnings = append(diversity.Warnings, "all peers are hosted in the same ASN")
+		}
+	}
+	return diversity, nil
+}
+
+// peerClient extracts the client implementation from a devp2p name such as "Geth/v1.13.5-stable/linux-amd64/go1.21.4"
+func peerClient(name string) string {
+	client := strings.ToLower(strings.SplitN(name, "/", 2)[0])
+	return orUnknown(client)
+}
+
+func orUnknown(val string) string {
+	if val == "" {
+		return "unknown"
+	}
+	return val
+}
+
+// lookupGeoIP resolves the country and ASN of the given IPs using an ip-api compatible batch endpoint
+func lookupGeoIP(ctx context.Context, geoIPURL string, ips []string) ([]geoIPEntry, error) {
+	// ip-api limits batch requests to 100 entries
+	const batchSize = 100
+
+	var entries []geoIPEntry
+	for start := 0; start < len(ips); start += batchSize {
+		end := start + batchSize
+		if end > len(ips) {
+			end = len(ips)
+		}
+
+		payload, err := json.Marshal(ips[start:end])
+		if err != nil {
+			return nil, err
+		}
+		resp, err := httpDo(ctx, http.MethodPost, geoIPURL, "application/json", bytes.NewBuffer(payload))
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("geoip lookup returned %s", resp.Status)
+		}
+
+		var batch []geoIPEntry
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		entries = append(entries, batch...)
+	}
+	return entries, nil
+}
+
+// formatDistribution renders a count map as "key=count" pairs sorted by descending count
+func formatDistribution(dist map[string]int) string {
+	keys := make([]string, 0, len(dist))
+	for key := range dist {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if dist[keys[i]] != dist[keys[j]] {
+			return dist[keys[i]] > dist[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", key, dist[key]))
+	}
+	return strings.Join(parts, ", ")
+}
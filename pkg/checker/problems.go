@@ -0,0 +1,150 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// Problem is a finding of the optional checks of a node that needs attention
+type Problem struct {
+	// Severity is the Nagios state the problem puts the node in, config.SeverityWarning or config.SeverityCritical
+	Severity string `json:"severity" yaml:"severity"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// Problems lists what needs attention on the node besides its sync status, in the order of the text output.
+// Quiet mode, the exit code and the Nagios output all go by it.
+func (res Result) Problems() []Problem {
+	var problems []Problem
+	warn := func(format string, args ...interface{}) {
+		problems = append(problems, Problem{Severity: config.SeverityWarning, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(res.MissingStaticPeers) > 0 {
+		warn("%d static peers missing", len(res.MissingStaticPeers))
+	}
+	if len(res.MissingTrustedPeers) > 0 {
+		warn("%d trusted peers missing", len(res.MissingTrustedPeers))
+	}
+	if len(res.BootnodeFailures) > 0 {
+		warn("%d bootnodes unreachable", len(res.BootnodeFailures))
+	}
+	if strings.HasPrefix(res.P2PReachability, "unreachable") {
+		warn("P2P port unreachable")
+	}
+	if res.AdvertisementIssue != "" {
+		warn("enode advertisement mismatch")
+	}
+	if res.PeerDiversity != nil {
+		for _, warning := range res.PeerDiversity.Warnings {
+			warn("eclipse risk, %s", warning)
+		}
+	}
+	for _, fork := range res.ForkReadiness {
+		if !fork.Ready {
+			warn("fork %s not ready", fork.Fork)
+		}
+	}
+	for _, advisory := range res.ForkAdvisories {
+		if advisory.Warning != "" {
+			warn("fork %s: %s", advisory.Fork, advisory.Warning)
+		}
+	}
+	if res.FeeTrend != nil && len(res.FeeTrend.Divergences) > 0 {
+		warn("fee trend diverges from the reference")
+	}
+	if res.TxGossip != nil && res.TxGossip.Received == 0 {
+		warn("no transaction gossip")
+	}
+	if len(res.FeeHistoryProblems) > 0 {
+		warn("eth_feeHistory inconsistent")
+	}
+	for _, assertion := range res.Assertions {
+		if !assertion.Passed {
+			warn("assertion %s failed", assertion.Assertion)
+		}
+	}
+	for _, check := range res.Checks {
+		if !check.Passed {
+			severity := config.SeverityWarning
+			if check.Severity == config.SeverityCritical {
+				severity = config.SeverityCritical
+			}
+			problems = append(problems, Problem{Severity: severity, Message: fmt.Sprintf("check %s failed", check.Name)})
+		}
+	}
+	if res.Archive != nil && !res.Archive.Available {
+		warn("archive state at block %d missing", res.Archive.Block)
+	}
+	if bench := res.TraceBenchmark; bench != nil {
+		switch {
+		case !bench.Enabled:
+			warn("trace API disabled")
+		case bench.Error != "":
+			warn("trace API failing")
+		case bench.Slow:
+			warn("trace API slow (%s)", bench.Duration.Round(time.Millisecond))
+		}
+	}
+	if res.Finality != nil && res.Finality.Stalled {
+		warn("finality stalled %d blocks behind", res.Finality.Lag)
+	}
+	if res.GasPrice != nil && len(res.GasPrice.Divergences) > 0 {
+		warn("gas price diverges from the reference")
+	}
+	if res.TxPool != nil && len(res.TxPool.Warnings) > 0 {
+		warn("txpool %s", strings.Join(res.TxPool.Warnings, ", "))
+	}
+	if bench := res.LogsBenchmark; bench != nil {
+		switch {
+		case bench.Slow:
+			warn("logs query slow (%s)", bench.Duration.Round(time.Millisecond))
+		case bench.Error != "":
+			warn("logs query failing")
+		}
+	}
+	if cadence := res.HeadCadence; cadence != nil {
+		switch {
+		case cadence.Error != "":
+			warn("newHeads subscription failing")
+		case cadence.Stalled:
+			warn("head stalled for %s", cadence.LongestGap.Round(time.Second))
+		}
+	}
+	if res.Logs != nil && (res.Logs.Missing > 0 || res.Logs.Duplicated > 0) {
+		warn("%d logs missing, %d duplicated", res.Logs.Missing, res.Logs.Duplicated)
+	}
+	if res.Receipts != nil && res.Receipts.Missing > 0 {
+		warn("%d receipts missing", res.Receipts.Missing)
+	}
+	for _, endpoint := range res.Endpoints {
+		if !endpoint.Healthy {
+			warn("endpoint %s failed", endpoint.Name)
+		}
+	}
+	if cl := res.Consensus; cl != nil {
+		switch {
+		case cl.ELOffline:
+			warn("consensus client reports the execution client offline")
+		case cl.IsSyncing:
+			warn("consensus client syncing")
+		}
+	}
+	for _, match := range res.LogMatches {
+		warn("log pattern %q matched %d times", match.Pattern, match.Count)
+	}
+	if res.Release != nil && len(res.Release.SecurityBehind) > 0 {
+		warn("unapplied security releases %s", strings.Join(res.Release.SecurityBehind, ", "))
+	}
+	for _, advisory := range res.SecurityAdvisories {
+		severity := config.SeverityWarning
+		if advisory.Critical() {
+			severity = config.SeverityCritical
+		}
+		problems = append(problems, Problem{Severity: severity, Message: fmt.Sprintf("affected by %s", advisory.ID)})
+	}
+	return problems
+}
@@ -0,0 +1,41 @@
+package checker
+
+// SyncProgress represents the sync progress reported by an eth_syncing object
+type SyncProgress struct {
+	StartingBlock int64 `json:"starting_block" yaml:"starting_block"`
+	CurrentBlock  int64 `json:"current_block" yaml:"current_block"`
+	HighestBlock  int64 `json:"highest_block" yaml:"highest_block"`
+	// PulledStates and KnownStates are the state trie entries downloaded and discovered by fast sync
+	PulledStates int64 `json:"pulled_states,omitempty" yaml:"pulled_states,omitempty"`
+	KnownStates  int64 `json:"known_states,omitempty" yaml:"known_states,omitempty"`
+	// SyncedAccounts, SyncedStorage and SyncedBytecodes are the state downloaded by geth snap sync
+	SyncedAccounts  int64 `json:"synced_accounts,omitempty" yaml:"synced_accounts,omitempty"`
+	SyncedStorage   int64 `json:"synced_storage,omitempty" yaml:"synced_storage,omitempty"`
+	SyncedBytecodes int64 `json:"synced_bytecodes,omitempty" yaml:"synced_bytecodes,omitempty"`
+}
+
+// syncProgress returns the progress of an eth_syncing answer, nil when the node is not syncing
+func syncProgress(status interface{}) *SyncProgress {
+	val, ok := status.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return &SyncProgress{
+		StartingBlock:   hexField(val, "startingBlock"),
+		CurrentBlock:    hexField(val, "currentBlock"),
+		HighestBlock:    hexField(val, "highestBlock"),
+		PulledStates:    hexField(val, "pulledStates"),
+		KnownStates:     hexField(val, "knownStates"),
+		SyncedAccounts:  hexField(val, "syncedAccounts"),
+		SyncedStorage:   hexField(val, "syncedStorage"),
+		SyncedBytecodes: hexField(val, "syncedBytecodes"),
+	}
+}
+
+// Ratio returns the share of the blocks between the starting and the highest block already synced
+func (p SyncProgress) Ratio() float64 {
+	if p.HighestBlock <= p.StartingBlock {
+		return 0
+	}
+	return float64(p.CurrentBlock-p.StartingBlock) / float64(p.HighestBlock-p.StartingBlock)
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// Default request rates of the reference providers, per second
+const (
+	etherscanRate        = 5
+	etherscanKeylessRate = 0.2
+	blockscoutRate       = 10
+)
+
+var (
+	rateLimitersMu sync.Mutex
+	// rateLimiters pace the requests to every reference provider host, shared by all chains and runs
+	rateLimiters = make(map[string]*rateLimiter)
+)
+
+// rateLimiter hands out request slots at a fixed interval, queuing the callers in order
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request slot or until ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	slot := time.Now()
+	if l.next.After(slot) {
+		slot = l.next
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitRateLimit paces a request to the reference source according to its rate limit
+func waitRateLimit(ctx context.Context, apiConf config.PublicAPI) error {
+	rate := referenceRate(apiConf)
+	if rate <= 0 {
+		return nil
+	}
+	host := referenceName(apiConf)
+
+	rateLimitersMu.Lock()
+	limiter, ok := rateLimiters[host]
+	if !ok {
+		limiter = &rateLimiter{}
+		rateLimiters[host] = limiter
+	}
+	rateLimitersMu.Unlock()
+
+	limiter.mu.Lock()
+	limiter.interval = time.Duration(float64(time.Second) / rate)
+	limiter.mu.Unlock()
+	return limiter.wait(ctx)
+}
+
+// referenceRate returns the requests per second allowed to the reference source, 0 for unlimited
+func referenceRate(apiConf config.PublicAPI) float64 {
+	switch {
+	case apiConf.RateLimit > 0:
+		return apiConf.RateLimit
+	case apiConf.Provider == config.ProviderRPC || apiConf.URL == "":
+		return 0
+	case apiConf.Provider == config.ProviderBlockscout:
+		return blockscoutRate
+	case apiConf.Key() == "":
+		return etherscanKeylessRate
+	}
+	return etherscanRate
+}
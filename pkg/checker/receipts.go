@@ -0,0 +1,68 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// receiptsPerBlockSample limits eth_getTransactionReceipt calls per block when eth_getBlockReceipts is unavailable
+const receiptsPerBlockSample = 5
+
+// ReceiptsAvailability represents how many transactions in recent blocks have no receipt on the node
+type ReceiptsAvailability struct {
+	Blocks       int `json:"blocks" yaml:"blocks"`
+	Transactions int `json:"transactions" yaml:"transactions"`
+	Missing      int `json:"missing" yaml:"missing"`
+}
+
+// checkReceipts verifies that receipts are served for transactions in the last n blocks
+func checkReceipts(ctx context.Context, node config.Node, localPort int, headBlock int64, n int) (*ReceiptsAvailability, error) {
+	availability := &ReceiptsAvailability{Blocks: n}
+	for num := headBlock - int64(n) + 1; num <= headBlock; num++ {
+		raw, err := rpc.CallRaw(ctx, node, localPort, "eth_getBlockByNumber", fmt.Sprintf("0x%x", num), false)
+		if err != nil {
+			return nil, err
+		}
+		var block struct {
+			Transactions []string `json:"transactions"`
+		}
+		if err := json.Unmarshal(raw, &block); err != nil {
+			return nil, err
+		}
+		if len(block.Transactions) == 0 {
+			continue
+		}
+
+		// Prefer the single-call block receipts method, fall back to sampling per-transaction receipts
+		if receipts, err := rpc.CallRaw(ctx, node, localPort, "eth_getBlockReceipts", fmt.Sprintf("0x%x", num)); err == nil {
+			var list []json.RawMessage
+			if err := json.Unmarshal(receipts, &list); err == nil {
+				availability.Transactions += len(block.Transactions)
+				if len(list) < len(block.Transactions) {
+					availability.Missing += len(block.Transactions) - len(list)
+				}
+				continue
+			}
+		}
+
+		sample := block.Transactions
+		if len(sample) > receiptsPerBlockSample {
+			sample = sample[:receiptsPerBlockSample]
+		}
+		for _, txHash := range sample {
+			availability.Transactions++
+			receipt, err := rpc.Call(ctx, node, localPort, "eth_getTransactionReceipt", txHash)
+			if err != nil {
+				return nil, err
+			}
+			if receipt == nil {
+				availability.Missing++
+			}
+		}
+	}
+	return availability, nil
+}
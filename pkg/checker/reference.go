@@ -0,0 +1,186 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// DefaultReferenceTTL is how long a reference head is reused when reference_ttl is not set
+const DefaultReferenceTTL = 15 * time.Second
+
+// ReferenceCache keeps the reference head of each chain for a TTL across runs,
+// so frequent runs don't burn the quota of the scanner APIs
+type ReferenceCache struct {
+	TTL time.Duration
+
+	mu    sync.Mutex
+	heads map[string]cachedReference
+}
+
+// cachedReference is a reference head, the fallback source it came from if any,
+// how long the source took to answer and the time it was fetched
+type cachedReference struct {
+	head    int64
+	source  string
+	latency time.Duration
+	fetched time.Time
+}
+
+// NewReferenceCache returns a cache keeping reference heads for ttl, 0 disables caching across runs
+func NewReferenceCache(ttl time.Duration) *ReferenceCache {
+	return &ReferenceCache{TTL: ttl, heads: make(map[string]cachedReference)}
+}
+
+// lookup returns the reference of chain if it was fetched within the TTL
+func (c *ReferenceCache) lookup(chain string, now time.Time) (cachedReference, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ref, ok := c.heads[chain]
+	if !ok || now.Sub(ref.fetched) >= c.TTL {
+		return cachedReference{}, false
+	}
+	return ref, true
+}
+
+// store records the reference of chain
+func (c *ReferenceCache) store(chain string, ref cachedReference) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heads[chain] = ref
+}
+
+// referenceHeads fetches the reference head of each chain once per run, shared by the nodes of the chain
+// so replicas don't multiply the calls to rate-limited scanner APIs
+type referenceHeads struct {
+	cache *ReferenceCache
+	// proxyErrors are the errors configuring the proxies of the reference sources of each chain
+	proxyErrors map[string][]string
+
+	mu    sync.Mutex
+	heads map[string]*referenceHead
+}
+
+// referenceHead is the reference head of a chain, its fallback source if any, the latency of the source
+// and its age, available once done is closed. skipped are the errors of the sources tried before it.
+type referenceHead struct {
+	done    chan struct{}
+	head    int64
+	source  string
+	latency time.Duration
+	age     time.Duration
+	skipped []string
+	err     error
+}
+
+func newReferenceHeads(cache *ReferenceCache) *referenceHeads {
+	return &referenceHeads{cache: cache, heads: make(map[string]*referenceHead)}
+}
+
+// get returns the reference head of chain, taken from the cache or fetched with adapter
+// by the first caller while the others wait for it
+func (r *referenceHeads) get(ctx context.Context, chain string, adapter ChainAdapter, apiConf config.PublicAPI) (*referenceHead, error) {
+	r.mu.Lock()
+	ref, ok := r.heads[chain]
+	if !ok {
+		ref = &referenceHead{done: make(chan struct{})}
+		r.heads[chain] = ref
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		ref.fetch(ctx, r.cache, chain, adapter, apiConf)
+		return ref, ref.err
+	}
+	select {
+	case <-ref.done:
+		return ref, ref.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch resolves the reference head from cache if fresh, otherwise from the reference sources
+func (ref *referenceHead) fetch(ctx context.Context, cache *ReferenceCache, chain string, adapter ChainAdapter, apiConf config.PublicAPI) {
+	defer close(ref.done)
+	now := time.Now()
+	if cache != nil {
+		if cached, ok := cache.lookup(chain, now); ok {
+			ref.head, ref.source, ref.latency, ref.age = cached.head, cached.source, cached.latency, now.Sub(cached.fetched)
+			return
+		}
+	}
+	ref.head, ref.source, ref.latency, ref.skipped, ref.err = fetchReference(ctx, adapter, apiConf)
+	if ref.err == nil && cache != nil {
+		cache.store(chain, cachedReference{head: ref.head, source: ref.source, latency: ref.latency, fetched: now})
+	}
+}
+
+// fetchReference returns the reference head from the first source answering among apiConf and its fallbacks,
+// with the name of the fallback it came from, empty when apiConf answered, how long it took to answer
+// and the errors of the sources tried before it
+func fetchReference(ctx context.Context, adapter ChainAdapter, apiConf config.PublicAPI) (int64, string, time.Duration, []string, error) {
+	sources := append([]config.PublicAPI{apiConf}, apiConf.Fallbacks...)
+	var errs []string
+	for i, source := range sources {
+		start := time.Now()
+		head, err := adapter.ReferenceHead(ctx, source)
+		if err == nil {
+			if i == 0 {
+				return head, "", time.Since(start), nil, nil
+			}
+			return head, referenceName(source), time.Since(start), errs, nil
+		}
+		if ctx.Err() != nil {
+			return 0, "", 0, nil, ctx.Err()
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", referenceName(source), err))
+	}
+	return 0, "", 0, nil, errors.New(strings.Join(errs, "; "))
+}
+
+// configureReferenceProxies routes the calls to the reference sources with a proxy through it,
+// fallbacks without their own proxy inherit the one of their public_apis entry.
+// It returns the errors of the sources whose proxy could not be configured by chain.
+func configureReferenceProxies(apis map[string]config.PublicAPI) map[string][]string {
+	errs := make(map[string][]string)
+	for chain, apiConf := range apis {
+		for _, source := range append([]config.PublicAPI{apiConf}, apiConf.Fallbacks...) {
+			proxy := source.Proxy
+			if proxy == "" {
+				proxy = apiConf.Proxy
+			}
+			if proxy == "" {
+				continue
+			}
+			for _, raw := range []string{source.URL, source.RPCURL} {
+				if raw == "" {
+					continue
+				}
+				if err := rpc.ConfigureHost(raw, rpc.HostSettings{Proxy: proxy}); err != nil {
+					errs[chain] = append(errs[chain], fmt.Sprintf("configuring proxy of reference %s: %v", referenceName(source), err))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// referenceName names a reference source by the host of its API, or of its RPC endpoint
+func referenceName(source config.PublicAPI) string {
+	raw := source.URL
+	if raw == "" {
+		raw = source.RPCURL
+	}
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return raw
+}
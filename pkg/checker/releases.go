@@ -0,0 +1,179 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const releaseCacheTTL = 6 * time.Hour
+
+// versionUnknown is the version reported for clients whose version can't be parsed
+const versionUnknown = "unknown"
+
+// securityPattern matches release notes announcing security fixes, rather than any mention of security
+var securityPattern = regexp.MustCompile(`(?i)\bsecurity (fix|release|patch|update|issue|advisory|vulnerabilit(y|ies))|\bvulnerabilit(y|ies)\b|\bCVE-\d{4}-\d+|\bGHSA(-[0-9a-z]{4}){3}\b`)
+
+// clientRepos maps web3_clientVersion client names to their GitHub repositories
+var clientRepos = map[string]string{
+	"geth":       "ethereum/go-ethereum",
+	"erigon":     "erigontech/erigon",
+	"nethermind": "NethermindEth/nethermind",
+	"besu":       "hyperledger/besu",
+	"reth":       "paradigmxyz/reth",
+	"bor":        "maticnetwork/bor",
+}
+
+// ReleaseAdvisory represents how far behind the latest client release a node is.
+// Version is unknown when the client version can't be parsed, nothing is counted as behind then.
+type ReleaseAdvisory struct {
+	Client         string   `json:"client" yaml:"client"`
+	Version        string   `json:"version" yaml:"version"`
+	Latest         string   `json:"latest" yaml:"latest"`
+	Behind         int      `json:"behind" yaml:"behind"`
+	SecurityBehind []string `json:"security_behind,omitempty" yaml:"security_behind,omitempty"`
+}
+
+// release represents the cached part of a GitHub release
+type release struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Security   bool   `json:"security"`
+}
+
+type releaseCacheEntry struct {
+	Fetched  time.Time `json:"fetched"`
+	Releases []release `json:"releases"`
+}
+
+var releaseCacheMu sync.Mutex
+
+// checkReleases compares the node's client version against the client's GitHub releases
+func checkReleases(ctx context.Context, node config.Node, client ClientVersion) (*ReleaseAdvisory, error) {
+	repo := node.ReleaseRepo
+	if repo == "" {
+		repo = clientRepos[client.Client]
+	}
+	if repo == "" {
+		return nil, fmt.Errorf("no release repository known for client %q", client.Client)
+	}
+
+	releases, err := cachedReleases(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	advisory := &ReleaseAdvisory{Client: client.Client, Version: client.Version}
+	if client.Version == "" {
+		advisory.Version = versionUnknown
+	}
+	for _, rel := range releases {
+		// Nightly and other tags without a version can't be ordered
+		if rel.Prerelease || len(versionParts(rel.TagName)) == 0 {
+			continue
+		}
+		if advisory.Latest == "" || compareVersions(rel.TagName, advisory.Latest) > 0 {
+			advisory.Latest = rel.TagName
+		}
+		if client.Version != "" && compareVersions(rel.TagName, client.Version) > 0 {
+			advisory.Behind++
+			if rel.Security {
+				advisory.SecurityBehind = append(advisory.SecurityBehind, rel.TagName)
+			}
+		}
+	}
+	return advisory, nil
+}
+
+// cachedReleases returns the repository releases from the on-disk cache, refreshing it once the TTL expired.
+// GitHub allows 60 unauthenticated requests per hour, GITHUB_TOKEN raises the limit.
+func cachedReleases(ctx context.Context, repo string) ([]release, error) {
+	releaseCacheMu.Lock()
+	defer releaseCacheMu.Unlock()
+
+	cache := make(map[string]releaseCacheEntry)
+	cachePath, err := releaseCachePath()
+	if err != nil {
+		return nil, err
+	}
+	if data, err := ioutil.ReadFile(cachePath); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	if entry, ok := cache[repo]; ok && time.Since(entry.Fetched) < releaseCacheTTL {
+		return entry.Releases, nil
+	}
+
+	releases, err := fetchReleases(ctx, repo)
+	if err != nil {
+		// Serve stale data rather than failing when rate limited
+		if entry, ok := cache[repo]; ok {
+			return entry.Releases, nil
+		}
+		return nil, err
+	}
+
+	cache[repo] = releaseCacheEntry{Fetched: time.Now(), Releases: releases}
+	if data, err := json.Marshal(cache); err == nil {
+		os.MkdirAll(filepath.Dir(cachePath), 0755)
+		ioutil.WriteFile(cachePath, data, 0644)
+	}
+	return releases, nil
+}
+
+func releaseCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "nodestat", "releases.json"), nil
+}
+
+func fetchReleases(ctx context.Context, repo string) ([]release, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=50", repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := rpc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github releases of %s returned %s", repo, resp.Status)
+	}
+
+	var ghReleases []struct {
+		TagName    string `json:"tag_name"`
+		Name       string `json:"name"`
+		Body       string `json:"body"`
+		Prerelease bool   `json:"prerelease"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ghReleases); err != nil {
+		return nil, err
+	}
+
+	releases := make([]release, 0, len(ghReleases))
+	for _, rel := range ghReleases {
+		releases = append(releases, release{
+			TagName:    rel.TagName,
+			Prerelease: rel.Prerelease,
+			Security:   securityPattern.MatchString(rel.Name + "\n" + rel.Body),
+		})
+	}
+	return releases, nil
+}
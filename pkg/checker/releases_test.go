@@ -0,0 +1,28 @@
+package checker
+
+import "testing"
+
+func TestSecurityPattern(t *testing.T) {
+	tests := []struct {
+		notes string
+		want  bool
+	}{
+		{notes: "This release contains a security fix for the p2p layer", want: true},
+		{notes: "Security Release: please upgrade", want: true},
+		{notes: "Fixes a vulnerability in the RPC server", want: true},
+		{notes: "Addresses vulnerabilities reported by auditors", want: true},
+		{notes: "Fixes CVE-2024-12345", want: true},
+		{notes: "See GHSA-abcd-1234-ef56 for details", want: true},
+		{notes: "Improved security of the keystore docs", want: false},
+		{notes: "Adds a security section to the README", want: false},
+		{notes: "Bug fixes and performance improvements", want: false},
+		{notes: "Bumps CVE-scanner dependency", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notes, func(t *testing.T) {
+			if got := securityPattern.MatchString(tt.notes); got != tt.want {
+				t.Errorf("securityPattern.MatchString(%q) = %t, want %t", tt.notes, got, tt.want)
+			}
+		})
+	}
+}
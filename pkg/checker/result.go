@@ -0,0 +1,185 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// Result represents the structure of a node result
+type Result struct {
+	Chain          string `json:"chain" yaml:"chain"`
+	Cluster        string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	SyncStatus     string `json:"sync_status" yaml:"sync_status"`
+	NodeBlockNum   int64  `json:"node_block_num" yaml:"node_block_num"`
+	LatestBlockNum int64  `json:"latest_block_num" yaml:"latest_block_num"`
+	// HeadAge is how far the timestamp of the node's latest block is behind the wall clock
+	HeadAge time.Duration `json:"head_age,omitempty" yaml:"head_age,omitempty"`
+	// ReferenceAge is the age of the cached reference head, 0 when it was fetched for this run
+	ReferenceAge time.Duration `json:"reference_age,omitempty" yaml:"reference_age,omitempty"`
+	// ReferenceSource is the fallback reference source the reference head came from, empty for the primary one
+	ReferenceSource string `json:"reference_source,omitempty" yaml:"reference_source,omitempty"`
+	// ReferenceLatency is how long the reference source took to answer the reference head
+	ReferenceLatency time.Duration `json:"reference_latency,omitempty" yaml:"reference_latency,omitempty"`
+	// RPCLatency times the round trips of the node by JSON-RPC method
+	RPCLatency map[string]rpc.MethodLatency `json:"rpc_latency,omitempty" yaml:"rpc_latency,omitempty"`
+	Diff       int64                        `json:"diff" yaml:"diff"`
+	PeersCount *int64                       `json:"peers_count,omitempty" yaml:"peers_count,omitempty"`
+	// Peers breaks the peers down when the node exposes admin_peers
+	Peers *PeerSummary `json:"peers,omitempty" yaml:"peers,omitempty"`
+
+	MissingStaticPeers  []string              `json:"missing_static_peers,omitempty" yaml:"missing_static_peers,omitempty"`
+	MissingTrustedPeers []string              `json:"missing_trusted_peers,omitempty" yaml:"missing_trusted_peers,omitempty"`
+	BootnodeFailures    []string              `json:"bootnode_failures,omitempty" yaml:"bootnode_failures,omitempty"`
+	P2PReachability     string                `json:"p2p_reachability,omitempty" yaml:"p2p_reachability,omitempty"`
+	AdvertisementIssue  string                `json:"advertisement_issue,omitempty" yaml:"advertisement_issue,omitempty"`
+	Discovery           *DiscoveryStats       `json:"discovery,omitempty" yaml:"discovery,omitempty"`
+	PeerDiversity       *PeerDiversity        `json:"peer_diversity,omitempty" yaml:"peer_diversity,omitempty"`
+	ForkReadiness       []ForkReadiness       `json:"fork_readiness,omitempty" yaml:"fork_readiness,omitempty"`
+	ForkAdvisories      []ForkAdvisory        `json:"fork_advisories,omitempty" yaml:"fork_advisories,omitempty"`
+	FeeTrend            *FeeTrend             `json:"fee_trend,omitempty" yaml:"fee_trend,omitempty"`
+	TxGossip            *TxGossip             `json:"tx_gossip,omitempty" yaml:"tx_gossip,omitempty"`
+	InclusionLatency    *InclusionLatency     `json:"inclusion_latency,omitempty" yaml:"inclusion_latency,omitempty"`
+	FeeHistoryProblems  []string              `json:"fee_history_problems,omitempty" yaml:"fee_history_problems,omitempty"`
+	Archive             *ArchiveState         `json:"archive,omitempty" yaml:"archive,omitempty"`
+	Checks              []CustomCheckResult   `json:"checks,omitempty" yaml:"checks,omitempty"`
+	Assertions          []AssertionResult     `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+	TraceBenchmark      *TraceBenchmark       `json:"trace_benchmark,omitempty" yaml:"trace_benchmark,omitempty"`
+	TxPool              *TxPoolStatus         `json:"txpool,omitempty" yaml:"txpool,omitempty"`
+	GasPrice            *GasPrice             `json:"gas_price,omitempty" yaml:"gas_price,omitempty"`
+	Finality            *Finality             `json:"finality,omitempty" yaml:"finality,omitempty"`
+	Logs                *LogsComparison       `json:"logs,omitempty" yaml:"logs,omitempty"`
+	LogsBenchmark       *LogsBenchmark        `json:"logs_benchmark,omitempty" yaml:"logs_benchmark,omitempty"`
+	HeadCadence         *HeadCadence          `json:"head_cadence,omitempty" yaml:"head_cadence,omitempty"`
+	BlockHashMismatch   string                `json:"block_hash_mismatch,omitempty" yaml:"block_hash_mismatch,omitempty"`
+	Receipts            *ReceiptsAvailability `json:"receipts,omitempty" yaml:"receipts,omitempty"`
+	SyncProgress        *SyncProgress         `json:"sync_progress,omitempty" yaml:"sync_progress,omitempty"`
+	Healing             *HealProgress         `json:"healing,omitempty" yaml:"healing,omitempty"`
+	StagedSync          *StagedSync           `json:"staged_sync,omitempty" yaml:"staged_sync,omitempty"`
+	SyncETA             *SyncETA              `json:"sync_eta,omitempty" yaml:"sync_eta,omitempty"`
+	WSStability         *WSStability          `json:"ws_stability,omitempty" yaml:"ws_stability,omitempty"`
+	SubscriptionDrops   *SubscriptionDropRate `json:"subscription_drops,omitempty" yaml:"subscription_drops,omitempty"`
+	Endpoints           []EndpointStatus      `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	Consensus           *BeaconStatus         `json:"consensus,omitempty" yaml:"consensus,omitempty"`
+	Metrics             map[string]float64    `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+	LogMatches          []LogMatch            `json:"log_matches,omitempty" yaml:"log_matches,omitempty"`
+	Release             *ReleaseAdvisory      `json:"release,omitempty" yaml:"release,omitempty"`
+	SecurityAdvisories  []Advisory            `json:"security_advisories,omitempty" yaml:"security_advisories,omitempty"`
+	Canaries            []CanarySLI           `json:"canaries,omitempty" yaml:"canaries,omitempty"`
+	// Errors are the errors of the checks that failed without failing the node, their results are missing
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+func fetchLatestBlock(ctx context.Context, apiConf config.PublicAPI) (int64, error) {
+	// Make HTTP GET request to the Etherscan (or Blockscout) API, keeping query parameters of the URL such as chainid
+	apiURL, err := url.Parse(apiConf.URL)
+	if err != nil {
+		return 0, err
+	}
+	query := apiURL.Query()
+	if apiConf.Provider == config.ProviderBlockscout {
+		query.Set("module", "block")
+		query.Set("action", "eth_block_number")
+	} else {
+		query.Set("module", "proxy")
+		query.Set("action", "eth_blockNumber")
+	}
+	if key := apiConf.Key(); key != "" {
+		query.Set("apikey", key)
+	}
+	apiURL.RawQuery = query.Encode()
+	resp, err := httpGet(ctx, apiURL.String())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return 0, fmt.Errorf("%w (%s)", rpc.ErrRateLimited, resp.Status)
+	}
+
+	// Read response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	// Unmarshal response JSON
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	// The proxy module passes JSON-RPC errors of the backing node through
+	if rpcErr, ok := result["error"].(map[string]interface{}); ok {
+		code, _ := rpcErr["code"].(float64)
+		message, _ := rpcErr["message"].(string)
+		return 0, &rpc.RPCError{Code: int(code), Message: message}
+	}
+
+	// Check if result contains a valid block number
+	if result["result"] == nil {
+		return 0, errors.New("no block number found in response")
+	}
+	head, ok := result["result"].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected block number %v in response", result["result"])
+	}
+	// Etherscan answers failures with a message in place of the result, e.g. "Max rate limit reached"
+	if !strings.HasPrefix(head, "0x") {
+		if strings.Contains(strings.ToLower(head), "rate limit") {
+			return 0, fmt.Errorf("%w (%s)", rpc.ErrRateLimited, head)
+		}
+		return 0, fmt.Errorf("scanner error: %s", head)
+	}
+
+	// Return the latest block number
+	return rpc.ParseHex(head)
+}
+
+// fetchRPCHead returns the head of a public JSON-RPC endpoint (Infura, Alchemy, llamarpc) via eth_blockNumber
+func fetchRPCHead(ctx context.Context, rpcURL string) (int64, error) {
+	raw, err := rpc.CallURL(ctx, rpcURL, "eth_blockNumber")
+	if err != nil {
+		return 0, err
+	}
+	var head string
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return 0, fmt.Errorf("unexpected eth_blockNumber result %s", raw)
+	}
+	return rpc.ParseHex(head)
+}
+
+func getSyncStatus(statusObject interface{}, latestBlock int64, maxStartLag int64) (string, error) {
+	switch val := statusObject.(type) {
+	case bool:
+		return "synced", nil
+	case map[string]interface{}:
+		startingBlock, ok := val["startingBlock"].(string)
+		if !ok {
+			return "unknown", nil
+		}
+
+		startingBlockNum, err := rpc.ParseHex(startingBlock)
+		if err != nil {
+			return "unknown", err
+		}
+
+		// If the difference between the current block and the starting block is more than maxStartLag,
+		// consider it as syncing.
+		if latestBlock-startingBlockNum > maxStartLag {
+			return "syncing", nil
+		}
+		return "synced", nil
+	default:
+		return "unknown", nil
+	}
+}
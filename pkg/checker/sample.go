@@ -0,0 +1,27 @@
+package checker
+
+import (
+	"context"
+	"time"
+)
+
+// sampleWait waits up to interval between two samples of a node, shortened to half the time left before
+// the deadline of ctx so that the second sample still fits in the run. It returns the time waited,
+// which rates must be computed over, or the error of ctx when the run is cancelled meanwhile.
+func sampleWait(ctx context.Context, interval time.Duration) (time.Duration, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		interval = min(interval, time.Until(deadline)/2)
+	}
+	if interval <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+	start := time.Now()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
@@ -0,0 +1,140 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// substrateHealth is the system_health response
+type substrateHealth struct {
+	Peers           int64 `json:"peers"`
+	IsSyncing       bool  `json:"isSyncing"`
+	ShouldHavePeers bool  `json:"shouldHavePeers"`
+}
+
+// substrateAdapter serves Polkadot/Substrate nodes.
+// The reference head comes from a public RPC (rpc_url) or the Subscan API (url and apikey).
+type substrateAdapter struct{}
+
+func (substrateAdapter) SyncStatus(ctx context.Context, node config.Node, localPort int, referenceHead int64) (string, error) {
+	health, err := fetchSubstrateHealth(ctx, node, localPort)
+	if err != nil {
+		return "", err
+	}
+	if health.IsSyncing {
+		return "syncing", nil
+	}
+
+	// A node without peers reports isSyncing false, system_syncState tells whether it is behind
+	raw, err := rpc.CallRaw(ctx, node, localPort, "system_syncState")
+	if err != nil {
+		return "unknown", err
+	}
+	var state struct {
+		CurrentBlock int64 `json:"currentBlock"`
+		HighestBlock int64 `json:"highestBlock"`
+	}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return "unknown", err
+	}
+	if state.HighestBlock-state.CurrentBlock > node.StartLag() {
+		return "syncing", nil
+	}
+	return "synced", nil
+}
+
+func (substrateAdapter) Head(ctx context.Context, node config.Node, localPort int) (int64, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "chain_getHeader")
+	if err != nil {
+		return 0, err
+	}
+	return parseSubstrateHeader(raw)
+}
+
+func (substrateAdapter) PeerCount(ctx context.Context, node config.Node, localPort int) (int64, bool, error) {
+	health, err := fetchSubstrateHealth(ctx, node, localPort)
+	if err != nil {
+		return 0, false, err
+	}
+	return health.Peers, true, nil
+}
+
+func (substrateAdapter) batchMethods() []string {
+	return []string{"system_health", "chain_getHeader", "system_syncState"}
+}
+
+func (substrateAdapter) ReferenceHead(ctx context.Context, apiConf config.PublicAPI) (int64, error) {
+	if apiConf.RPCURL != "" {
+		raw, err := rpc.CallURL(ctx, apiConf.RPCURL, "chain_getHeader")
+		if err != nil {
+			return 0, err
+		}
+		return parseSubstrateHeader(raw)
+	}
+
+	// Subscan API, e.g. https://polkadot.api.subscan.io
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(apiConf.URL, "/")+"/api/scan/metadata", bytes.NewBufferString("{}"))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := apiConf.Key(); key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	resp, err := rpc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var metadata struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			BlockNum string `json:"blockNum"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return 0, err
+	}
+	if metadata.Code != 0 {
+		return 0, fmt.Errorf("subscan error %d: %s", metadata.Code, metadata.Message)
+	}
+	return strconv.ParseInt(metadata.Data.BlockNum, 10, 64)
+}
+
+func fetchSubstrateHealth(ctx context.Context, node config.Node, localPort int) (*substrateHealth, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "system_health")
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, fmt.Errorf("empty system_health response")
+	}
+	var health substrateHealth
+	if err := json.Unmarshal(raw, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+func parseSubstrateHeader(raw json.RawMessage) (int64, error) {
+	var header struct {
+		Number string `json:"number"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return 0, err
+	}
+	return rpc.ParseHex(header.Number)
+}
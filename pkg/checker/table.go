@@ -0,0 +1,117 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// ANSI colors of the sync statuses
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// useColor reports whether the table is colored, only when written to a terminal
+func (p printer) useColor() bool {
+	if p.opts.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := p.w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// statusColor returns the color of a sync status: green for synced, yellow while catching up or short of peers,
+// red otherwise, e.g. for vulnerable nodes
+func statusColor(status string) string {
+	switch status {
+	case "synced":
+		return colorGreen
+	case "syncing", "healing", "degraded":
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// printTable prints one aligned row per configured node, nodes whose checks failed are shown as unreachable
+func (p printer) printTable(nodes map[string]config.Node, results map[string]Result) {
+	nodeNames := make([]string, 0, len(nodes))
+	clusters := make(map[string]bool)
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	for _, res := range results {
+		clusters[res.Cluster] = true
+	}
+	sort.Slice(nodeNames, func(i, j int) bool {
+		a, b := results[nodeNames[i]], results[nodeNames[j]]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		return nodeNames[i] < nodeNames[j]
+	})
+	withCluster := len(clusters) > 1
+
+	header := []string{"NODE", "CHAIN", "STATUS", "BLOCK", "REFERENCE", "DIFF", "PEERS"}
+	if withCluster {
+		header = append([]string{"CLUSTER"}, header...)
+	}
+	rows := [][]string{header}
+	for _, nodeName := range nodeNames {
+		res, ok := results[nodeName]
+		row := []string{nodeName, nodes[nodeName].ChainName(nodeName), statusUnreachable, "-", "-", "-", "-"}
+		if ok {
+			row[2] = res.SyncStatus
+			row[3] = strconv.FormatInt(res.NodeBlockNum, 10)
+			row[4] = strconv.FormatInt(res.LatestBlockNum, 10)
+			if res.ReferenceAge > 0 {
+				row[4] += fmt.Sprintf(" (%s old)", res.ReferenceAge.Round(time.Second))
+			}
+			row[5] = strconv.FormatInt(res.Diff, 10)
+			if res.PeersCount != nil {
+				row[6] = strconv.FormatInt(*res.PeersCount, 10)
+			}
+		}
+		if withCluster {
+			cluster := res.Cluster
+			if cluster == "" {
+				cluster = "-"
+			}
+			row = append([]string{cluster}, row...)
+		}
+		rows = append(rows, row)
+	}
+
+	// Pad before coloring so the escape codes don't count towards the column widths
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	statusColumn := len(header) - 5
+	color := p.useColor()
+	for r, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			if i == statusColumn && r > 0 && color {
+				cells[i] = statusColor(cell) + cells[i] + colorReset
+			}
+		}
+		fmt.Fprintln(p.w, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+	fmt.Fprintln(p.w)
+}
@@ -0,0 +1,77 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const (
+	defaultTraceMethod      = "debug_traceBlockByNumber"
+	traceTransactionMethod  = "debug_traceTransaction"
+	defaultTraceBlockOffset = 5
+	defaultTraceMaxDuration = 30 * time.Second
+)
+
+// TraceBenchmark represents the outcome of a timed block trace
+type TraceBenchmark struct {
+	Method string `json:"method" yaml:"method"`
+	Block  int64  `json:"block" yaml:"block"`
+	// Enabled is false when the node doesn't expose the tracing namespace
+	Enabled  bool          `json:"enabled" yaml:"enabled"`
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	Slow     bool          `json:"slow" yaml:"slow"`
+	Error    string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// benchmarkTrace traces a recent block, or its first transaction, and measures how long the node takes to respond.
+// The trace is abandoned and reported as slow once it exceeds the configured duration.
+func benchmarkTrace(ctx context.Context, node config.Node, localPort int, conf config.TraceConfig, headBlock int64) *TraceBenchmark {
+	if conf.Method == "" {
+		conf.Method = defaultTraceMethod
+	}
+	if conf.BlockOffset == 0 {
+		conf.BlockOffset = defaultTraceBlockOffset
+	}
+	if conf.MaxDuration == 0 {
+		conf.MaxDuration = defaultTraceMaxDuration
+	}
+
+	block := headBlock - conf.BlockOffset
+	bench := &TraceBenchmark{Method: conf.Method, Block: block, Enabled: true}
+	params := []interface{}{fmt.Sprintf("0x%x", block)}
+	if conf.Method == traceTransactionMethod {
+		header, err := fetchBlock(nodeCaller(ctx, node, localPort), block)
+		if err != nil {
+			bench.Error = err.Error()
+			return bench
+		}
+		if len(header.Transactions) == 0 {
+			bench.Error = fmt.Sprintf("no transaction in block %d to trace", block)
+			return bench
+		}
+		params = []interface{}{header.Transactions[0]}
+	}
+	if conf.Method == defaultTraceMethod || conf.Method == traceTransactionMethod {
+		params = append(params, map[string]interface{}{"tracer": "callTracer"})
+	}
+
+	traceCtx, cancel := context.WithTimeout(ctx, conf.MaxDuration)
+	defer cancel()
+	start := time.Now()
+	_, err := rpc.CallRaw(traceCtx, node, localPort, conf.Method, params...)
+	bench.Duration = time.Since(start)
+	var rpcErr *rpc.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == rpc.MethodNotFound {
+		bench.Enabled = false
+	}
+	if err != nil {
+		bench.Error = err.Error()
+	}
+	bench.Slow = bench.Duration > conf.MaxDuration || traceCtx.Err() == context.DeadlineExceeded
+	return bench
+}
@@ -0,0 +1,24 @@
+package checker
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces the checks of each node, spans are dropped unless a tracer provider is registered
+var tracer = otel.Tracer("github.com/morzhanov/nodestat/pkg/checker")
+
+// recordError marks span as failed with err, if any
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// endSpan records err on span and ends it
+func endSpan(span trace.Span, err error) {
+	recordError(span, err)
+	span.End()
+}
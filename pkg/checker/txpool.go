@@ -0,0 +1,89 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// TxGossip represents the number of pending transactions the node received during the sample window
+type TxGossip struct {
+	Received int           `json:"received" yaml:"received"`
+	Window   time.Duration `json:"window" yaml:"window"`
+}
+
+// checkTxGossip installs a pending transaction filter and counts transaction hashes delivered
+// to it during the window; RPC nodes with broken peering receive none
+func checkTxGossip(ctx context.Context, node config.Node, localPort int, window time.Duration) (*TxGossip, error) {
+	filterID, err := rpc.Call(ctx, node, localPort, "eth_newPendingTransactionFilter")
+	if err != nil {
+		return nil, err
+	}
+	defer rpc.Call(ctx, node, localPort, "eth_uninstallFilter", filterID)
+
+	if window, err = sampleWait(ctx, window); err != nil {
+		return nil, err
+	}
+
+	raw, err := rpc.CallRaw(ctx, node, localPort, "eth_getFilterChanges", filterID)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	if err := json.Unmarshal(raw, &hashes); err != nil {
+		return nil, err
+	}
+	return &TxGossip{Received: len(hashes), Window: window}, nil
+}
+
+// TxPoolStatus represents the transaction pool size of a node, a ballooning pool often precedes a stall
+type TxPoolStatus struct {
+	Pending  int64    `json:"pending" yaml:"pending"`
+	Queued   int64    `json:"queued" yaml:"queued"`
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// checkTxPool reads the pending and queued transaction counts and warns about those above the configured limits
+func checkTxPool(ctx context.Context, node config.Node, localPort int, conf config.TxPoolConfig) (*TxPoolStatus, error) {
+	raw, err := rpc.CallRaw(ctx, node, localPort, "txpool_status")
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("unexpected txpool_status result %s", raw)
+	}
+
+	status := &TxPoolStatus{}
+	if status.Pending, err = quantity(fields["pending"]); err != nil {
+		return nil, fmt.Errorf("txpool_status pending: %v", err)
+	}
+	if status.Queued, err = quantity(fields["queued"]); err != nil {
+		return nil, fmt.Errorf("txpool_status queued: %v", err)
+	}
+
+	if conf.MaxPending > 0 && status.Pending > conf.MaxPending {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("%d pending transactions, above %d", status.Pending, conf.MaxPending))
+	}
+	if conf.MaxQueued > 0 && status.Queued > conf.MaxQueued {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("%d queued transactions, above %d", status.Queued, conf.MaxQueued))
+	}
+	return status, nil
+}
+
+// quantity decodes a hex quantity, or a plain number as returned by some clients
+func quantity(raw json.RawMessage) (int64, error) {
+	var hex string
+	if err := json.Unmarshal(raw, &hex); err == nil {
+		return rpc.ParseHex(hex)
+	}
+	var num int64
+	if err := json.Unmarshal(raw, &num); err != nil {
+		return 0, fmt.Errorf("invalid quantity %s", raw)
+	}
+	return num, nil
+}
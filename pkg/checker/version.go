@@ -0,0 +1,85 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// ClientVersion represents a parsed web3_clientVersion string such as "Geth/v1.13.5-stable-916d6a44/linux-amd64/go1.21.4"
+// or "erigon/2.55.1/linux-amd64/go1.21.5"
+type ClientVersion struct {
+	Raw    string
+	Client string
+	// Version is empty when the string has no dotted version
+	Version string
+}
+
+func fetchClientVersion(ctx context.Context, node config.Node, localPort int) (ClientVersion, error) {
+	raw, err := rpc.Call(ctx, node, localPort, "web3_clientVersion")
+	if err != nil {
+		return ClientVersion{}, err
+	}
+	str, ok := raw.(string)
+	if !ok {
+		return ClientVersion{}, fmt.Errorf("unexpected web3_clientVersion result %v", raw)
+	}
+	return parseClientVersion(str), nil
+}
+
+// parseClientVersion takes the first part after the client name holding a dotted version, with or without a v prefix
+func parseClientVersion(raw string) ClientVersion {
+	parts := strings.Split(raw, "/")
+	cv := ClientVersion{Raw: raw, Client: strings.ToLower(parts[0])}
+	for _, part := range parts[1:] {
+		if len(versionParts(part)) >= 2 {
+			cv.Version = part
+			break
+		}
+	}
+	return cv
+}
+
+// compareVersions compares two dotted versions, with or without a v prefix ("v1.13.5-stable" style suffixes are ignored),
+// and returns -1, 0 or 1
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the leading numbers of a dotted version, none when it doesn't start with one
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		num, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, num)
+	}
+	return parts
+}
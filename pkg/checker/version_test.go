@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClientVersion(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want ClientVersion
+	}{
+		{
+			raw:  "Geth/v1.13.5-stable-916d6a44/linux-amd64/go1.21.4",
+			want: ClientVersion{Client: "geth", Version: "v1.13.5-stable-916d6a44"},
+		},
+		{
+			raw:  "erigon/2.55.1/linux-amd64/go1.21.5",
+			want: ClientVersion{Client: "erigon", Version: "2.55.1"},
+		},
+		{
+			raw:  "Nethermind/v1.25.4+20b10b35/linux-x64/dotnet8.0.2",
+			want: ClientVersion{Client: "nethermind", Version: "v1.25.4+20b10b35"},
+		},
+		{
+			raw:  "besu/v24.1.2/linux-x86_64/openjdk-java-17",
+			want: ClientVersion{Client: "besu", Version: "v24.1.2"},
+		},
+		{
+			// The node name before the version is skipped
+			raw:  "Geth/mynode/v1.14.0-stable/linux-amd64/go1.22.1",
+			want: ClientVersion{Client: "geth", Version: "v1.14.0-stable"},
+		},
+		{
+			raw:  "reth/v1.0.0-1d1b7d0/x86_64-unknown-linux-gnu",
+			want: ClientVersion{Client: "reth", Version: "v1.0.0-1d1b7d0"},
+		},
+		{raw: "Geth", want: ClientVersion{Client: "geth"}},
+		{raw: "Geth/stable/linux", want: ClientVersion{Client: "geth"}},
+		{raw: "", want: ClientVersion{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			tt.want.Raw = tt.raw
+			if got := parseClientVersion(tt.raw); got != tt.want {
+				t.Errorf("parseClientVersion(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionParts(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []int
+	}{
+		{version: "1.13.5", want: []int{1, 13, 5}},
+		{version: "v1.13.5", want: []int{1, 13, 5}},
+		{version: "v1.13.5-stable-916d6a44", want: []int{1, 13, 5}},
+		{version: "1.25.4+20b10b35", want: []int{1, 25, 4}},
+		{version: "2.55", want: []int{2, 55}},
+		{version: "1.2.x", want: []int{1, 2}},
+		{version: "stable", want: nil},
+		{version: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := versionParts(tt.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versionParts(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.13.5", b: "1.13.5", want: 0},
+		{a: "v1.13.5", b: "1.13.5", want: 0},
+		{a: "v1.13.5-stable", b: "1.13.5-unstable", want: 0},
+		{a: "1.13.4", b: "1.13.5", want: -1},
+		{a: "1.14.0", b: "1.13.15", want: 1},
+		{a: "1.9", b: "1.10", want: -1},
+		{a: "2.0", b: "1.99.99", want: 1},
+		// Missing parts count as 0
+		{a: "1.13", b: "1.13.0", want: 0},
+		{a: "1.13", b: "1.13.1", want: -1},
+		{a: "1.13.0.1", b: "1.13", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := compareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// statusUnreachable is the status reported for a node whose checks failed
+const statusUnreachable = "unreachable"
+
+// StatusChange is the webhook payload sent when a node's status changes
+type StatusChange struct {
+	Node      string    `json:"node"`
+	Chain     string    `json:"chain"`
+	OldStatus string    `json:"old_status"`
+	NewStatus string    `json:"new_status"`
+	Time      time.Time `json:"time"`
+	// Result is omitted for unreachable nodes
+	Result *Result `json:"result,omitempty"`
+}
+
+// NotifyStatusChanges posts a StatusChange to the webhook for every node whose status differs from the previous run,
+// all failed notifications are reported. Statuses are kept in the user cache dir, so changes are detected across
+// one-shot runs too.
+// A state file that can't be read or written is reported along with the failed notifications,
+// an unreadable one is replaced so that detection starts over from this run.
+func NotifyStatusChanges(ctx context.Context, webhookURL string, nodes map[string]config.Node, results map[string]Result) error {
+	statePath, err := statusStatePath()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	previous := make(map[string]string)
+	if data, err := ioutil.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &previous); err != nil {
+			errs = append(errs, fmt.Errorf("reading status state %s, starting over: %v", statePath, err))
+			previous = make(map[string]string)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("reading status state: %v", err))
+	}
+
+	// A node's new status is saved once its change was delivered, so a failed notification is sent again next run
+	var changes []StatusChange
+	for _, nodeName := range sortedKeys(nodes) {
+		node := nodes[nodeName]
+		change := StatusChange{Node: nodeName, Chain: node.ChainName(nodeName), NewStatus: statusUnreachable, Time: time.Now()}
+		if res, ok := results[nodeName]; ok {
+			change.NewStatus = res.SyncStatus
+			change.Result = &res
+		}
+		old, known := previous[nodeName]
+		if !known || old == change.NewStatus {
+			previous[nodeName] = change.NewStatus
+			continue
+		}
+		change.OldStatus = old
+		changes = append(changes, change)
+	}
+
+	for _, change := range changes {
+		if err := postWebhook(ctx, webhookURL, change); err != nil {
+			errs = append(errs, fmt.Errorf("notifying %s status change: %v", change.Node, err))
+			continue
+		}
+		previous[change.Node] = change.NewStatus
+	}
+
+	if err := writeStatusState(statePath, previous); err != nil {
+		errs = append(errs, fmt.Errorf("writing status state: %v", err))
+	}
+	return errors.Join(errs...)
+}
+
+func writeStatusState(statePath string, statuses map[string]string) error {
+	data, err := json.Marshal(statuses)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(statePath, data, 0644)
+}
+
+// postWebhook sends change once, a retried notification could be delivered twice
+func postWebhook(ctx context.Context, webhookURL string, change StatusChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	resp, err := httpDo(rpc.WithoutRetry(ctx), http.MethodPost, webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func statusStatePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "nodestat", "status.json"), nil
+}
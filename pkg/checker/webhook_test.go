@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+func TestNotifyStatusChanges(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var mu sync.Mutex
+	var delivered []string
+	var failing map[string]bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var change StatusChange
+		json.NewDecoder(r.Body).Decode(&change)
+		mu.Lock()
+		defer mu.Unlock()
+		if failing[change.Node] {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		delivered = append(delivered, change.Node+":"+change.OldStatus+"->"+change.NewStatus)
+	}))
+	defer srv.Close()
+	nodes := map[string]config.Node{"a": {}, "b": {}, "c": {}}
+	results := func(statuses ...string) map[string]Result {
+		res := make(map[string]Result)
+		for i, status := range statuses {
+			res[string(rune('a'+i))] = Result{SyncStatus: status}
+		}
+		return res
+	}
+
+	tests := []struct {
+		name    string
+		results map[string]Result
+		// failing are the nodes whose notifications the webhook rejects
+		failing       []string
+		wantDelivered []string
+		wantErr       string
+	}{
+		{name: "first run only records", results: results("synced", "synced", "synced")},
+		{
+			name:          "every change is posted",
+			results:       results("behind", "behind", "behind"),
+			failing:       []string{"b"},
+			wantDelivered: []string{"a:synced->behind", "c:synced->behind"},
+			wantErr:       "notifying b status change",
+		},
+		{
+			name:          "undelivered change is sent again",
+			results:       results("behind", "behind", "behind"),
+			wantDelivered: []string{"b:synced->behind"},
+		},
+		{name: "no change", results: results("behind", "behind", "behind")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			delivered = nil
+			failing = make(map[string]bool)
+			for _, node := range tt.failing {
+				failing[node] = true
+			}
+			mu.Unlock()
+
+			err := NotifyStatusChanges(context.Background(), srv.URL, nodes, tt.results)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("NotifyStatusChanges: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("NotifyStatusChanges = %v, want %q in it", err, tt.wantErr)
+			}
+			sort.Strings(delivered)
+			if strings.Join(delivered, ",") != strings.Join(tt.wantDelivered, ",") {
+				t.Errorf("delivered %v, want %v", delivered, tt.wantDelivered)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/morzhanov/nodestat/pkg/config"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+const wsReconnectDelay = time.Second
+
+// WSStability represents how the subscriptions held open for the whole window behaved
+type WSStability struct {
+	Window        time.Duration `json:"window" yaml:"window"`
+	Notifications int           `json:"notifications" yaml:"notifications"`
+	Disconnects   int           `json:"disconnects" yaml:"disconnects"`
+	Resubscribes  int           `json:"resubscribes" yaml:"resubscribes"`
+	// Drops counts subscriptions lost with a disconnect, MissedBlocks gaps in newHeads block numbers
+	Drops        int    `json:"drops" yaml:"drops"`
+	MissedBlocks int64  `json:"missed_blocks" yaml:"missed_blocks"`
+	Error        string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// SubscriptionDropRate represents subscription drops and missed block notifications
+// accumulated over all daemon iterations
+type SubscriptionDropRate struct {
+	Observed      time.Duration `json:"observed" yaml:"observed"`
+	Drops         int           `json:"drops" yaml:"drops"`
+	MissedBlocks  int64         `json:"missed_blocks" yaml:"missed_blocks"`
+	DropsPerHour  float64       `json:"drops_per_hour" yaml:"drops_per_hour"`
+	MissedPerHour float64       `json:"missed_per_hour" yaml:"missed_per_hour"`
+}
+
+var (
+	subscriptionTotalsMu sync.Mutex
+	subscriptionTotals   = make(map[string]*SubscriptionDropRate)
+)
+
+// recordSubscriptionDrops adds a monitoring window to the node's running totals and returns the updated rates
+func recordSubscriptionDrops(nodeName string, stability *WSStability) SubscriptionDropRate {
+	subscriptionTotalsMu.Lock()
+	defer subscriptionTotalsMu.Unlock()
+
+	totals, ok := subscriptionTotals[nodeName]
+	if !ok {
+		totals = &SubscriptionDropRate{}
+		subscriptionTotals[nodeName] = totals
+	}
+	totals.Observed += stability.Window
+	totals.Drops += stability.Drops
+	totals.MissedBlocks += stability.MissedBlocks
+	if hours := totals.Observed.Hours(); hours > 0 {
+		totals.DropsPerHour = float64(totals.Drops) / hours
+		totals.MissedPerHour = float64(totals.MissedBlocks) / hours
+	}
+	return *totals
+}
+
+// wsNotification represents an eth_subscription message
+type wsNotification struct {
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// monitorWSStability keeps a newHeads subscription (and a logs subscription when the node has
+// a logs_check address) open until the window ends, reconnecting and resubscribing whenever
+// the connection drops. Cancelling ctx ends the window early.
+func monitorWSStability(ctx context.Context, node config.Node, localPort int, window time.Duration) *WSStability {
+	stability := &WSStability{Window: window}
+	deadline := time.Now().Add(window)
+
+	subscriptions := []interface{}{[]interface{}{"newHeads"}}
+	if node.LogsCheck != nil && node.LogsCheck.Address != "" {
+		subscriptions = append(subscriptions, []interface{}{"logs", map[string]interface{}{"address": node.LogsCheck.Address}})
+	}
+
+	i := findEndpoint(node, config.EndpointWS)
+	url := endpointURL(node, localPort, i)
+
+	var lastBlock int64
+	for subscribed := false; time.Now().Before(deadline); {
+		conn, ids, err := subscribe(ctx, url, node.Endpoints[i].Auth, subscriptions)
+		if err != nil {
+			stability.Error = err.Error()
+			if !reconnectWait(ctx) {
+				break
+			}
+			continue
+		}
+		if subscribed {
+			stability.Resubscribes++
+		}
+		subscribed = true
+
+		conn.SetReadDeadline(deadline)
+		stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+		for {
+			var msg wsNotification
+			if err = conn.ReadJSON(&msg); err != nil {
+				break
+			}
+			stability.Notifications++
+
+			if msg.Params.Subscription != ids[0] {
+				continue
+			}
+			var head struct {
+				Number string `json:"number"`
+			}
+			if json.Unmarshal(msg.Params.Result, &head) != nil {
+				continue
+			}
+			if num, err := rpc.ParseHex(head.Number); err == nil {
+				if lastBlock > 0 && num > lastBlock+1 {
+					stability.MissedBlocks += num - lastBlock - 1
+				}
+				lastBlock = num
+			}
+		}
+		stop()
+		conn.Close()
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Reaching the deadline is the expected way to leave the read loop
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() && !time.Now().Before(deadline) {
+			break
+		}
+		stability.Disconnects++
+		stability.Drops += len(ids)
+		stability.Error = err.Error()
+		if !reconnectWait(ctx) {
+			break
+		}
+	}
+	return stability
+}
+
+// reconnectWait waits wsReconnectDelay before the next connection attempt, it returns false when ctx ends first
+func reconnectWait(ctx context.Context) bool {
+	timer := time.NewTimer(wsReconnectDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// subscribe dials the endpoint, creates the subscriptions and returns their IDs in the same order.
+// Each answer is awaited until the deadline of ctx or the RPC timeout, and canceling ctx closes the connection.
+func subscribe(ctx context.Context, url string, auth *config.NodeAuth, subscriptions []interface{}) (*websocket.Conn, []string, error) {
+	conn, err := rpc.DialWebSocket(ctx, url, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	fail := func(err error) (*websocket.Conn, []string, error) {
+		stop()
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, err
+	}
+
+	ids := make([]string, 0, len(subscriptions))
+	for i, params := range subscriptions {
+		err = conn.WriteJSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "eth_subscribe",
+			"params":  params,
+			"id":      i + 1,
+		})
+		if err != nil {
+			return fail(err)
+		}
+
+		// Notifications of the subscriptions made so far may arrive before the answer
+		for {
+			deadline := time.Now().Add(rpc.OptionsFrom(ctx).Timeout)
+			if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+				deadline = ctxDeadline
+			}
+			conn.SetReadDeadline(deadline)
+
+			var resp struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+				Result string          `json:"result"`
+				Error  *rpc.RPCError   `json:"error"`
+			}
+			if err := conn.ReadJSON(&resp); err != nil {
+				return fail(err)
+			}
+			if resp.Method == "eth_subscription" || string(resp.ID) != strconv.Itoa(i+1) {
+				continue
+			}
+			if resp.Error != nil {
+				return fail(fmt.Errorf("eth_subscribe failed: %v", resp.Error))
+			}
+			ids = append(ids, resp.Result)
+			break
+		}
+	}
+	if !stop() {
+		// ctx ended after the last answer and closed the connection
+		return nil, nil, ctx.Err()
+	}
+	return conn, ids, nil
+}
@@ -0,0 +1,114 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/morzhanov/nodestat/pkg/rpc"
+)
+
+// subscribeServer serves WebSocket connections, calling answer for every request it reads
+func subscribeServer(t *testing.T, answer func(conn *websocket.Conn, id json.RawMessage)) string {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var req struct {
+				ID json.RawMessage `json:"id"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			answer(conn, req.ID)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestSubscribe(t *testing.T) {
+	subscriptions := []interface{}{[]interface{}{"newHeads"}, []interface{}{"logs"}}
+	tests := []struct {
+		name    string
+		answer  func(conn *websocket.Conn, id json.RawMessage)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "answers",
+			answer: func(conn *websocket.Conn, id json.RawMessage) {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":`+string(id)+`,"result":"0xs`+string(id)+`"}`))
+			},
+			want: []string{"0xs1", "0xs2"},
+		},
+		{
+			name: "notifications and stray answers first",
+			answer: func(conn *websocket.Conn, id json.RawMessage) {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xs1","result":{"number":"0x64"}}}`))
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":99,"result":"0xother"}`))
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":`+string(id)+`,"result":"0xs`+string(id)+`"}`))
+			},
+			want: []string{"0xs1", "0xs2"},
+		},
+		{
+			name: "error answer",
+			answer: func(conn *websocket.Conn, id json.RawMessage) {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":`+string(id)+`,"error":{"code":-32601,"message":"not supported"}}`))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no answer",
+			answer:  func(conn *websocket.Conn, id json.RawMessage) {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := subscribeServer(t, tt.answer)
+			ctx := rpc.WithOptions(context.Background(), rpc.Options{Timeout: 200 * time.Millisecond})
+			start := time.Now()
+			conn, ids, err := subscribe(ctx, url, nil, subscriptions)
+			if time.Since(start) > 2*time.Second {
+				t.Errorf("subscribe returned after %s, past the RPC timeout", time.Since(start))
+			}
+			if tt.wantErr {
+				if err == nil {
+					conn.Close()
+					t.Fatalf("subscribe = %v, want an error", ids)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+			conn.Close()
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("subscribe = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeCanceled(t *testing.T) {
+	url := subscribeServer(t, func(conn *websocket.Conn, id json.RawMessage) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	if _, _, err := subscribe(ctx, url, nil, []interface{}{[]interface{}{"newHeads"}}); err != context.Canceled {
+		t.Errorf("subscribe = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("subscribe returned %s after its context was canceled", elapsed)
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// assertionPattern matches "<metric> <operator> <value>", e.g. "diff < 50" or "sync_status == synced"
+var assertionPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_.]*)\s*(==|!=|<=|>=|<|>)\s*(.+?)\s*$`)
+
+// Assertion is a declarative condition on a metric of the node results
+type Assertion struct {
+	// Metric is the JSON field of the result, dotted for nested ones, e.g. finality.lag
+	Metric   string
+	Operator string
+	// Value is the compared value as written, unquoted
+	Value string
+}
+
+// ParseAssertion parses an assertion written as "<metric> <operator> <value>"
+func ParseAssertion(expr string) (Assertion, error) {
+	match := assertionPattern.FindStringSubmatch(expr)
+	if match == nil {
+		return Assertion{}, fmt.Errorf("invalid assertion %q, expected <metric> <operator> <value> with ==, !=, <, <=, > or >=", expr)
+	}
+	value := match[3]
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return Assertion{Metric: match[1], Operator: match[2], Value: value}, nil
+}
+
+// String returns the assertion as written in the config
+func (a Assertion) String() string {
+	return strings.Join([]string{a.Metric, a.Operator, a.Value}, " ")
+}
@@ -0,0 +1,65 @@
+package config
+
+import "testing"
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    Assertion
+		wantErr bool
+	}{
+		{expr: "diff < 50", want: Assertion{Metric: "diff", Operator: "<", Value: "50"}},
+		{expr: "  peers>=3  ", want: Assertion{Metric: "peers", Operator: ">=", Value: "3"}},
+		{expr: "sync_status == synced", want: Assertion{Metric: "sync_status", Operator: "==", Value: "synced"}},
+		{expr: `status != "behind"`, want: Assertion{Metric: "status", Operator: "!=", Value: "behind"}},
+		{expr: "status == 'forked'", want: Assertion{Metric: "status", Operator: "==", Value: "forked"}},
+		{expr: "finality.lag <= 64", want: Assertion{Metric: "finality.lag", Operator: "<=", Value: "64"}},
+		{expr: "head_age > 30s", want: Assertion{Metric: "head_age", Operator: ">", Value: "30s"}},
+		{expr: `label == "a b"`, want: Assertion{Metric: "label", Operator: "==", Value: "a b"}},
+		// Mismatched quotes are kept as written
+		{expr: `label == "a'`, want: Assertion{Metric: "label", Operator: "==", Value: `"a'`}},
+		{expr: "diff", wantErr: true},
+		{expr: "diff <", wantErr: true},
+		{expr: "diff = 5", wantErr: true},
+		{expr: "diff ~ 5", wantErr: true},
+		{expr: "1diff < 5", wantErr: true},
+		{expr: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseAssertion(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseAssertion(%q) = %+v, want an error", tt.expr, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAssertion(%q): %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAssertion(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssertionString(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "diff<50", want: "diff < 50"},
+		{expr: `status == "synced"`, want: "status == synced"},
+		{expr: "  finality.lag  >=  64 ", want: "finality.lag >= 64"},
+	}
+	for _, tt := range tests {
+		assertion, err := ParseAssertion(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseAssertion(%q): %v", tt.expr, err)
+		}
+		if got := assertion.String(); got != tt.want {
+			t.Errorf("ParseAssertion(%q).String() = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
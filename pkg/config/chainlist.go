@@ -0,0 +1,122 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ChainlistURL is the public chainlist dataset, downloaded to refresh the embedded one
+const ChainlistURL = "https://chainid.network/chains.json"
+
+// chainlistSources is the number of public RPC endpoints of a chain used as reference sources
+const chainlistSources = 3
+
+//go:embed chainlist.json
+var embeddedChainlist []byte
+
+// chainlistChain is the used part of a chainlist entry
+type chainlistChain struct {
+	Name    string   `json:"name"`
+	ChainID int64    `json:"chainId"`
+	RPC     []string `json:"rpc"`
+}
+
+var (
+	chainlistOnce sync.Once
+	chainlist     map[int64][]string
+)
+
+// ChainlistPath returns the location of the refreshed chainlist dataset
+func ChainlistPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "nodestat", "chainlist.json"), nil
+}
+
+// ChainlistRPCs returns the public HTTP JSON-RPC endpoints of the EVM chain ID, read from the refreshed
+// dataset if any and the embedded one otherwise. Endpoints needing an API key are left out.
+func ChainlistRPCs(chainID int64) []string {
+	chainlistOnce.Do(func() {
+		if path, err := ChainlistPath(); err == nil {
+			if data, err := os.ReadFile(path); err == nil {
+				chainlist, _ = parseChainlist(data)
+			}
+		}
+		if chainlist == nil {
+			chainlist, _ = parseChainlist(embeddedChainlist)
+		}
+	})
+	return chainlist[chainID]
+}
+
+// SaveChainlist stores a downloaded chainlist dataset as the refreshed one and returns the number of chains in it
+func SaveChainlist(data []byte) (int, error) {
+	chains, err := parseChainlist(data)
+	if err != nil {
+		return 0, err
+	}
+	if len(chains) == 0 {
+		return 0, fmt.Errorf("no chains with public RPC endpoints in the chainlist dataset")
+	}
+	path, err := ChainlistPath()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	return len(chains), os.WriteFile(path, data, 0644)
+}
+
+func parseChainlist(data []byte) (map[int64][]string, error) {
+	var entries []chainlistChain
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid chainlist dataset: %v", err)
+	}
+	chains := make(map[int64][]string, len(entries))
+	for _, entry := range entries {
+		var urls []string
+		for _, url := range entry.RPC {
+			isHTTP := strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")
+			if isHTTP && !strings.Contains(url, "${") {
+				urls = append(urls, url)
+			}
+		}
+		if len(urls) > 0 {
+			chains[entry.ChainID] = urls
+		}
+	}
+	return chains, nil
+}
+
+// withChainlist fills the reference endpoints of the public API from the chainlist entry of its chain ID.
+// The first public RPC endpoint becomes rpc_url when unset and every other one a fallback,
+// after a configured url and fallbacks.
+func (api PublicAPI) withChainlist() PublicAPI {
+	urls := ChainlistRPCs(api.ChainID)
+	if len(urls) > chainlistSources {
+		urls = urls[:chainlistSources]
+	}
+	if len(urls) == 0 {
+		return api
+	}
+	if api.RPCURL == "" {
+		api.RPCURL = urls[0]
+	}
+	fallbacks := append([]PublicAPI(nil), api.Fallbacks...)
+	for _, url := range urls {
+		if api.URL == "" && url == api.RPCURL {
+			continue
+		}
+		fallbacks = append(fallbacks, PublicAPI{RPCURL: url})
+	}
+	api.Fallbacks = fallbacks
+	return api
+}
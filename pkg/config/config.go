@@ -0,0 +1,598 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Severities of failed custom checks
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Chain types selectable with the node's type option
+const (
+	ChainTypeEVM       = "evm"
+	ChainTypeArbitrum  = "arbitrum"
+	ChainTypeCosmos    = "cosmos"
+	ChainTypeBitcoin   = "bitcoin"
+	ChainTypeSubstrate = "substrate"
+)
+
+// ChainTypes lists the supported chain types
+var ChainTypes = []string{ChainTypeEVM, ChainTypeArbitrum, ChainTypeCosmos, ChainTypeBitcoin, ChainTypeSubstrate}
+
+// Reference providers of EVM chains selectable with the public API's provider option
+const (
+	ProviderEtherscan  = "etherscan"
+	ProviderBlockscout = "blockscout"
+	ProviderRPC        = "rpc"
+)
+
+// Providers lists the supported reference providers
+var Providers = []string{ProviderEtherscan, ProviderBlockscout, ProviderRPC}
+
+// Canary represents a recurring synthetic operation executed against every node of a chain in daemon mode
+type Canary struct {
+	Name  string        `json:"name" yaml:"name"`
+	Type  string        `json:"type" yaml:"type"`
+	Every time.Duration `json:"every" yaml:"every"`
+	// Address is the account read by balance canaries and the log emitter queried by logs canaries
+	Address string `json:"address" yaml:"address"`
+	// Range is the number of recent blocks queried by logs canaries
+	Range int64 `json:"range" yaml:"range"`
+}
+
+// DiscoveryConfig enables peer discovery health metrics for a node
+type DiscoveryConfig struct {
+	// TableMetric is the debug_metrics key prefix whose gauges are summed into the table size
+	TableMetric string `json:"table_metric" yaml:"table_metric"`
+	// ChurnInterval is the time between the two admin_peers samples used to measure churn
+	ChurnInterval time.Duration `json:"churn_interval" yaml:"churn_interval"`
+}
+
+// Endpoint types
+const (
+	EndpointHTTP    = "http"
+	EndpointWS      = "ws"
+	EndpointMetrics = "metrics"
+	EndpointBeacon  = "beacon"
+	EndpointEngine  = "engine"
+	EndpointGraphQL = "graphql"
+)
+
+// Endpoint represents an additional endpoint of a node (ws, metrics port, beacon API, authenticated Engine API, geth GraphQL)
+type Endpoint struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+	Port int    `json:"port" yaml:"port"`
+	Path string `json:"path" yaml:"path"`
+	// URL is used directly by nodes that are not port-forwarded
+	URL string `json:"url" yaml:"url"`
+	// Auth holds the credentials of the endpoint, e.g. the JWT secret of an engine endpoint
+	Auth *NodeAuth `json:"auth" yaml:"auth"`
+}
+
+// Fork represents a scheduled network upgrade of a chain, activated either at a block or at a timestamp
+type Fork struct {
+	Name  string `json:"name" yaml:"name"`
+	Block int64  `json:"block" yaml:"block"`
+	Time  int64  `json:"time" yaml:"time"`
+	// ConfigKey is the chain config field holding the activation, e.g. "pragueTime"
+	ConfigKey string `json:"config_key" yaml:"config_key"`
+	// MinClientVersions maps client names (geth, erigon, ...) to their first fork-ready release
+	MinClientVersions map[string]string `json:"min_client_versions" yaml:"min_client_versions"`
+}
+
+// CanaryAccount configures the account used to send self-transfers for inclusion latency probes.
+// Transactions are signed by an external signer (clef, geth or web3signer eth_signTransaction),
+// so nodestat never handles private keys.
+type CanaryAccount struct {
+	From      string        `json:"from" yaml:"from"`
+	To        string        `json:"to" yaml:"to"`
+	SignerURL string        `json:"signer_url" yaml:"signer_url"`
+	Samples   int           `json:"samples" yaml:"samples"`
+	Timeout   time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// LogsCheckConfig configures the bounded eth_getLogs query compared with the reference
+type LogsCheckConfig struct {
+	Address string   `json:"address" yaml:"address"`
+	Topics  []string `json:"topics" yaml:"topics"`
+	// Range is the number of blocks queried, Offset the distance of the range end from head
+	Range  int64 `json:"range" yaml:"range"`
+	Offset int64 `json:"offset" yaml:"offset"`
+}
+
+// LogsBenchmarkConfig configures the timed eth_getLogs query over a sliding range of recent blocks
+type LogsBenchmarkConfig struct {
+	Address string   `json:"address" yaml:"address"`
+	Topics  []string `json:"topics" yaml:"topics"`
+	// Range is the number of blocks queried, Offset the distance of the range end from head
+	Range  int64 `json:"range" yaml:"range"`
+	Offset int64 `json:"offset" yaml:"offset"`
+	// MaxDuration is the deadline of the query, log queries are reported as slow above it
+	MaxDuration time.Duration `json:"max_duration" yaml:"max_duration"`
+}
+
+// IPCConfig configures the JSON-RPC calls piped through the IPC socket of a node by exec-ing into its pod
+type IPCConfig struct {
+	// Path is the IPC socket in the pod, e.g. /data/geth.ipc
+	Path string `json:"path" yaml:"path"`
+	// Command reads a request on stdin, writes the answer to stdout and exits by itself,
+	// defaults to nc -U with an idle timeout
+	Command []string `json:"command" yaml:"command"`
+	// Container is the container of the pod sharing the socket, defaults to the first one
+	Container string `json:"container" yaml:"container"`
+}
+
+// SSHConfig configures the SSH tunnel forwarding the ports of a node, as ssh -L does
+type SSHConfig struct {
+	// Host is the SSH server as host or host:port, port 22 by default
+	Host string `json:"host" yaml:"host"`
+	User string `json:"user" yaml:"user"`
+	// KeyFile is the unencrypted private key authenticating User
+	KeyFile string `json:"key_file" yaml:"key_file"`
+	// Target is the address the node ports are reached at from Host, defaults to localhost
+	Target string `json:"target" yaml:"target"`
+	// KnownHosts verifies the host key of Host, defaults to ~/.ssh/known_hosts.
+	// InsecureIgnoreHostKey accepts any host key, for test setups only.
+	KnownHosts            string `json:"known_hosts" yaml:"known_hosts"`
+	InsecureIgnoreHostKey bool   `json:"insecure_ignore_host_key" yaml:"insecure_ignore_host_key"`
+}
+
+// DockerConfig selects the container of a node on the Docker daemon of DOCKER_HOST, the local one by default
+type DockerConfig struct {
+	// Container is the name of the container, e.g. geth
+	Container string `json:"container" yaml:"container"`
+	// Label selects the first running container with the label instead, as key=value or key,
+	// e.g. com.docker.compose.service=geth
+	Label string `json:"label" yaml:"label"`
+}
+
+// TeleportConfig reaches the Kubernetes cluster of a node through Teleport with tsh proxy kube,
+// which needs a prior tsh login to the proxy
+type TeleportConfig struct {
+	// Proxy is the Teleport proxy address, defaults to the one of the tsh profile
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// Cluster is the Teleport cluster, e.g. a leaf cluster, defaults to the root one of the profile
+	Cluster string `json:"cluster" yaml:"cluster"`
+	// KubeCluster is the Kubernetes cluster registered in Teleport, defaults to the node context
+	KubeCluster string `json:"kube_cluster" yaml:"kube_cluster"`
+	// Tsh is the tsh binary, defaults to tsh in PATH
+	Tsh string `json:"tsh" yaml:"tsh"`
+}
+
+// HeadCadenceConfig configures the newHeads subscription watching blocks arrive over WebSocket
+type HeadCadenceConfig struct {
+	// Window is how long the subscription is held, defaults to 1m
+	Window time.Duration `json:"window" yaml:"window"`
+	// BlockTime is the expected interval between blocks, defaults to 12s
+	BlockTime time.Duration `json:"block_time" yaml:"block_time"`
+	// MaxGap is the longest time without a new head before the node is reported as stalled,
+	// defaults to three block times
+	MaxGap time.Duration `json:"max_gap" yaml:"max_gap"`
+}
+
+// LogScanConfig configures error-pattern scanning of the node pod logs
+type LogScanConfig struct {
+	Since    time.Duration `json:"since" yaml:"since"`
+	Patterns []string      `json:"patterns" yaml:"patterns"`
+}
+
+// NodeConfig represents the structure of nodes configuration
+type NodeConfig struct {
+	Nodes      map[string]Node      `json:"nodes" yaml:"nodes"`
+	PublicApis map[string]PublicAPI `json:"public_apis" yaml:"public_apis"`
+	// Namespace is the default Kubernetes namespace of nodes without their own, defaults to blockchains
+	Namespace string `json:"namespace" yaml:"namespace"`
+	// Teleport is the default Teleport access of nodes in Kubernetes without their own
+	Teleport *TeleportConfig `json:"teleport" yaml:"teleport"`
+	// P2PProbeURL is an optional external service used to test P2P reachability from the internet
+	P2PProbeURL string `json:"p2p_probe_url" yaml:"p2p_probe_url"`
+	// GeoIPURL is an ip-api compatible batch endpoint used to locate peers, which receives their IPs.
+	// Peers are only located when it is set.
+	GeoIPURL string `json:"geoip_url" yaml:"geoip_url"`
+	// Forks lists scheduled network upgrades per chain
+	Forks map[string][]Fork `json:"forks" yaml:"forks"`
+	// CanaryAccounts configures transaction inclusion latency probes per chain
+	CanaryAccounts map[string]CanaryAccount `json:"canary_accounts" yaml:"canary_accounts"`
+	// AdvisoryFeed is a file path or URL of the security advisory feed matched against client versions
+	AdvisoryFeed string `json:"advisory_feed" yaml:"advisory_feed"`
+	// MaxGroupDivergence is the block height spread tolerated between nodes of the same chain
+	MaxGroupDivergence int64 `json:"max_group_divergence" yaml:"max_group_divergence"`
+	// Assertions are conditions on the results of every node, e.g. "diff < 50"
+	Assertions []string `json:"assertions" yaml:"assertions"`
+	// Canaries lists recurring synthetic operations per chain executed in daemon mode
+	Canaries map[string][]Canary `json:"canaries" yaml:"canaries"`
+	// Webhook receives a JSON payload whenever a node's status changes
+	Webhook string `json:"webhook" yaml:"webhook"`
+	// InfluxDB receives the results of every run as line protocol
+	InfluxDB *InfluxDB `json:"influxdb" yaml:"influxdb"`
+	// RPCTimeout bounds every JSON-RPC and HTTP API call, defaults to 10s
+	RPCTimeout time.Duration `json:"rpc_timeout" yaml:"rpc_timeout"`
+	// ReferenceTTL is how long the reference head of a chain is reused across runs, defaults to 15s
+	ReferenceTTL time.Duration `json:"reference_ttl" yaml:"reference_ttl"`
+	// Retry configures the retries of transient failures of JSON-RPC and HTTP API calls
+	Retry *Retry `json:"retry" yaml:"retry"`
+}
+
+// Retry configures exponential backoff: the n-th retry waits Backoff * 2^(n-1), capped at MaxBackoff
+// and randomized by up to Jitter, e.g. 0.2 for ±20%
+type Retry struct {
+	// Count is the number of retries after a failed call, 0 disables retries
+	Count      int           `json:"count" yaml:"count"`
+	Backoff    time.Duration `json:"backoff" yaml:"backoff"`
+	MaxBackoff time.Duration `json:"max_backoff" yaml:"max_backoff"`
+	Jitter     float64       `json:"jitter" yaml:"jitter"`
+}
+
+// InfluxDB represents an InfluxDB v2 write endpoint
+type InfluxDB struct {
+	URL    string `json:"url" yaml:"url"`
+	Token  string `json:"token" yaml:"token"`
+	Org    string `json:"org" yaml:"org"`
+	Bucket string `json:"bucket" yaml:"bucket"`
+}
+
+type PublicAPI struct {
+	URL    string `json:"url" yaml:"url"`
+	APIKey string `json:"apikey" yaml:"apikey"`
+	// APIKeyEnv names the environment variable holding the API key when apikey is not set
+	APIKeyEnv string `json:"apikey_env" yaml:"apikey_env"`
+	// Provider selects the API flavor of url for EVM chains: etherscan (default), blockscout
+	// or rpc for a plain JSON-RPC endpoint. Without url, the head is read from rpc_url.
+	Provider string `json:"provider" yaml:"provider"`
+	// RateLimit is the number of requests per second sent to the reference source, 0 uses the provider's
+	// default: 5 for Etherscan with an API key, 0.2 without, 10 for Blockscout and unlimited for JSON-RPC
+	RateLimit float64 `json:"rate_limit" yaml:"rate_limit"`
+	// Fallbacks are the reference sources tried in order when this one fails or rate-limits
+	Fallbacks []PublicAPI `json:"fallbacks" yaml:"fallbacks"`
+	// RPCURL is a public JSON-RPC endpoint of the chain used for cross-checks against the reference
+	RPCURL string `json:"rpc_url" yaml:"rpc_url"`
+	// ChainID fills rpc_url and further fallbacks with the public RPC endpoints of the EVM chain from chainlist
+	ChainID int64 `json:"chain_id" yaml:"chain_id"`
+	// Proxy is the http, https or socks5 proxy URL reaching url and rpc_url instead of the proxy
+	// environment variables, fallbacks without their own use it too
+	Proxy string `json:"proxy" yaml:"proxy"`
+}
+
+// Node represents the structure of a node configuration
+type Node struct {
+	// Chain groups several nodes of the same chain (eth-1, eth-2, eth-archive), defaults to the node name
+	Chain string `json:"chain" yaml:"chain"`
+	// Type selects the chain adapter (evm, arbitrum, cosmos, bitcoin, substrate), defaults to evm
+	Type string `json:"type" yaml:"type"`
+	// URL is queried directly instead of port-forwarding to Service, e.g. for nodes behind an ingress
+	URL string `json:"url" yaml:"url"`
+	// Context and Kubeconfig select the cluster of the node, default to the current context of KUBECONFIG or ~/.kube/config
+	Context    string `json:"context" yaml:"context"`
+	Kubeconfig string `json:"kubeconfig" yaml:"kubeconfig"`
+	// Auth holds RPC credentials, e.g. bitcoind rpcuser/rpcpassword
+	Auth      *NodeAuth `json:"auth" yaml:"auth"`
+	Service   string    `json:"service" yaml:"service"`
+	Port      int       `json:"port" yaml:"port"`
+	RPCPath   string    `json:"rpc_path" yaml:"rpc_path"`
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	// TLS configures https connections to URL, or to the port forward when set on a port-forwarded node
+	TLS *TLSConfig `json:"tls" yaml:"tls"`
+	// Proxy is the http, https or socks5 proxy URL reaching URL instead of the proxy environment variables
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// WSPath performs the JSON-RPC calls over WebSocket at this path of Port instead of over HTTP at RPCPath,
+	// "/" when served at the root. Nodes with a URL use WebSocket when it is a ws:// or wss:// URL.
+	WSPath string `json:"ws_path" yaml:"ws_path"`
+	// IPC checks a node whose HTTP RPC is disabled over its IPC socket instead of port-forwarding to Port
+	IPC *IPCConfig `json:"ipc" yaml:"ipc"`
+	// SSH reaches a node outside Kubernetes through an SSH tunnel to Port instead of port-forwarding to Service
+	SSH *SSHConfig `json:"ssh" yaml:"ssh"`
+	// Docker reaches a node running in a container of the local Docker daemon on the host port published for Port
+	Docker *DockerConfig `json:"docker" yaml:"docker"`
+	// Teleport reaches the cluster of the node through tsh proxy kube instead of Kubeconfig
+	Teleport *TeleportConfig `json:"teleport" yaml:"teleport"`
+
+	// StaticPeers and TrustedPeers are enode URLs the node is expected to be connected to
+	StaticPeers  []string `json:"static_peers" yaml:"static_peers"`
+	TrustedPeers []string `json:"trusted_peers" yaml:"trusted_peers"`
+	// Bootnodes are enode URLs or host:port pairs probed from inside the node pod
+	Bootnodes []string `json:"bootnodes" yaml:"bootnodes"`
+	// ExternalAddress is the public host:port the node's P2P listener should be reachable at,
+	// compared with the address the node advertises
+	ExternalAddress string `json:"external_address" yaml:"external_address"`
+	// P2PReachability dials ExternalAddress, or the advertised enode address without one, for inbound reachability
+	P2PReachability bool `json:"p2p_reachability" yaml:"p2p_reachability"`
+	// Discovery enables discovery table size and peer churn reporting
+	Discovery *DiscoveryConfig `json:"discovery" yaml:"discovery"`
+	// PeerDiversity enables peer geography and client-diversity breakdown
+	PeerDiversity bool `json:"peer_diversity" yaml:"peer_diversity"`
+	// FeeTrendBlocks is the number of recent blocks whose base fee and gas limit are compared with the reference
+	FeeTrendBlocks int `json:"fee_trend_blocks" yaml:"fee_trend_blocks"`
+	// TxGossipWindow is how long pending transaction gossip is sampled for
+	TxGossipWindow time.Duration `json:"tx_gossip_window" yaml:"tx_gossip_window"`
+	// FeeHistoryCheck enables eth_feeHistory structure and recency validation
+	FeeHistoryCheck bool `json:"fee_history_check" yaml:"fee_history_check"`
+	// GasPriceCheck reports eth_gasPrice and the latest base fee, compared with the reference rpc_url if any.
+	// MaxFeeDivergence is the relative difference above which they are reported as diverged, defaults to 0.5
+	GasPriceCheck    bool    `json:"gas_price_check" yaml:"gas_price_check"`
+	MaxFeeDivergence float64 `json:"max_fee_divergence" yaml:"max_fee_divergence"`
+	// Checks are extra JSON-RPC checks of the node
+	Checks []CustomCheck `json:"checks" yaml:"checks"`
+	// Assertions are conditions on the results of the node, checked after the global ones
+	Assertions []string `json:"assertions" yaml:"assertions"`
+	// Archive verifies the node serves the state of an old block, as archive nodes do
+	Archive *ArchiveConfig `json:"archive" yaml:"archive"`
+	// Trace marks the node as an archive/trace provider and enables the trace benchmark
+	Trace *TraceConfig `json:"trace" yaml:"trace"`
+	// FinalityCheck reports the finalized and safe blocks of a PoS chain, MaxFinalityLag is the distance
+	// of the finalized block from head above which finality is reported as stalled, defaults to 128
+	FinalityCheck  bool  `json:"finality_check" yaml:"finality_check"`
+	MaxFinalityLag int64 `json:"max_finality_lag" yaml:"max_finality_lag"`
+	// TxPool enables pending and queued transaction counts from txpool_status
+	TxPool *TxPoolConfig `json:"txpool" yaml:"txpool"`
+	// LogsCheck enables the eth_getLogs cross-check against the reference
+	LogsCheck *LogsCheckConfig `json:"logs_check" yaml:"logs_check"`
+	// LogsBenchmark enables the eth_getLogs latency benchmark
+	LogsBenchmark *LogsBenchmarkConfig `json:"logs_benchmark" yaml:"logs_benchmark"`
+	// HeadCadence watches newHeads over the ws endpoint, or the node URL when served over WebSocket
+	HeadCadence *HeadCadenceConfig `json:"head_cadence" yaml:"head_cadence"`
+	// HashCheckDepth is the distance from head of the block whose hash is compared with the reference
+	HashCheckDepth int64 `json:"hash_check_depth" yaml:"hash_check_depth"`
+	// ReceiptsCheckBlocks is the number of recent blocks whose receipts must be available
+	ReceiptsCheckBlocks int `json:"receipts_check_blocks" yaml:"receipts_check_blocks"`
+	// Endpoints are additional endpoints (ws, metrics, beacon API) forwarded and checked alongside the RPC port
+	Endpoints []Endpoint `json:"endpoints" yaml:"endpoints"`
+	// MetricsSeries are the Prometheus metric names scraped from the metrics endpoint into the result
+	MetricsSeries []string `json:"metrics_series" yaml:"metrics_series"`
+	// LogScan enables error-pattern scanning of the node pod logs
+	LogScan *LogScanConfig `json:"log_scan" yaml:"log_scan"`
+	// ReleaseCheck compares the client version with the latest GitHub release of ReleaseRepo
+	// (derived from web3_clientVersion when empty)
+	ReleaseCheck bool   `json:"release_check" yaml:"release_check"`
+	ReleaseRepo  string `json:"release_repo" yaml:"release_repo"`
+	// MaxStartLag is the number of blocks a syncing node may have started behind the reference
+	// and still count as synced, defaults to 20
+	MaxStartLag int64 `json:"max_start_lag" yaml:"max_start_lag"`
+	// MaxBlockLag marks a node that reports being synced as "behind" when it trails the reference by more blocks
+	MaxBlockLag int64 `json:"max_block_lag" yaml:"max_block_lag"`
+	// MinPeers marks a synced node with fewer peers as "degraded", peers dropping usually come before a failure
+	MinPeers int64 `json:"min_peers" yaml:"min_peers"`
+}
+
+// Key returns the API key of the reference API, read from APIKeyEnv when APIKey is not set
+func (api PublicAPI) Key() string {
+	return valueOrEnv(api.APIKey, api.APIKeyEnv)
+}
+
+// DefaultNamespace is the Kubernetes namespace of nodes when none is configured
+const DefaultNamespace = "blockchains"
+
+// defaultMaxStartLag is the start lag a node may have when max_start_lag is not set
+const defaultMaxStartLag = 20
+
+// StartLag returns the configured or default maximum start lag
+func (n Node) StartLag() int64 {
+	if n.MaxStartLag > 0 {
+		return n.MaxStartLag
+	}
+	return defaultMaxStartLag
+}
+
+// ChainName returns the chain the node belongs to
+func (n Node) ChainName(nodeName string) string {
+	if n.Chain != "" {
+		return n.Chain
+	}
+	return nodeName
+}
+
+// InKubernetes reports whether the node is reached through a Kubernetes service rather than a url, an SSH tunnel or Docker
+func (n Node) InKubernetes() bool {
+	return n.URL == "" && n.SSH == nil && n.Docker == nil
+}
+
+// IsEVM reports whether the node speaks Ethereum JSON-RPC, which the EVM specific checks require
+func (n Node) IsEVM() bool {
+	return n.Type == "" || n.Type == ChainTypeEVM || n.Type == ChainTypeArbitrum
+}
+
+// TLSConfig configures the TLS connections to a node RPC endpoint served over https
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the CAs trusted in addition to the system ones
+	CAFile string `json:"ca_file" yaml:"ca_file"`
+	// CertFile and KeyFile are the PEM client certificate and key presented to the node
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+	// ServerName overrides the name the certificate of the node is verified against
+	ServerName string `json:"server_name" yaml:"server_name"`
+	// InsecureSkipVerify accepts any certificate of the node, for self-signed test setups only
+	InsecureSkipVerify bool `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+}
+
+// Auth types of node RPC endpoints
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	// AuthJWT signs an HS256 token of the shared secret for every request, as the Engine API requires
+	AuthJWT = "jwt"
+)
+
+// NodeAuth represents the credentials of a node RPC endpoint, basic auth by default, a bearer token
+// or a JWT signed with the secret file shared with the consensus client.
+// Every value but the secret can be read from the environment variable named by its _env field instead.
+type NodeAuth struct {
+	Type        string `json:"type" yaml:"type"`
+	Username    string `json:"username" yaml:"username"`
+	UsernameEnv string `json:"username_env" yaml:"username_env"`
+	Password    string `json:"password" yaml:"password"`
+	PasswordEnv string `json:"password_env" yaml:"password_env"`
+	Token       string `json:"token" yaml:"token"`
+	TokenEnv    string `json:"token_env" yaml:"token_env"`
+	// JWTSecret is the path of the hex-encoded secret of jwt auth, e.g. /secrets/jwt.hex
+	JWTSecret string `json:"jwt_secret" yaml:"jwt_secret"`
+}
+
+// User returns the basic auth username, read from UsernameEnv when Username is not set
+func (auth NodeAuth) User() string {
+	return valueOrEnv(auth.Username, auth.UsernameEnv)
+}
+
+// Pass returns the basic auth password, read from PasswordEnv when Password is not set
+func (auth NodeAuth) Pass() string {
+	return valueOrEnv(auth.Password, auth.PasswordEnv)
+}
+
+// BearerToken returns the bearer token, read from TokenEnv when Token is not set
+func (auth NodeAuth) BearerToken() string {
+	return valueOrEnv(auth.Token, auth.TokenEnv)
+}
+
+// ReadJWTSecret reads the hex-encoded 32-byte secret shared by the execution and consensus clients,
+// e.g. the jwt.hex file of geth
+func ReadJWTSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(ExpandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT secret %s: %v", path, err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("invalid JWT secret %s: %d bytes instead of 32", path, len(secret))
+	}
+	return secret, nil
+}
+
+// valueOrEnv returns value, or the environment variable env when value is not set
+func valueOrEnv(value string, env string) string {
+	if value == "" && env != "" {
+		return os.Getenv(env)
+	}
+	return value
+}
+
+// Load reads the configuration from path, NODESTAT_CONFIG or the first existing default location
+func Load(path string) (NodeConfig, error) {
+	configPath, err := ResolvePath(path)
+	if err != nil {
+		return NodeConfig{}, err
+	}
+
+	// Read config file
+	configFile, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return NodeConfig{}, err
+	}
+
+	// Unmarshal config
+	var config NodeConfig
+	err = yaml.Unmarshal(configFile, &config)
+	if err != nil {
+		return NodeConfig{}, err
+	}
+
+	// Nodes without a namespace or Teleport access use the global ones
+	if config.Namespace == "" {
+		config.Namespace = DefaultNamespace
+	}
+	for nodeName, node := range config.Nodes {
+		if node.Namespace == "" {
+			node.Namespace = config.Namespace
+		}
+		if node.Teleport == nil && config.Teleport != nil && node.InKubernetes() {
+			node.Teleport = config.Teleport
+		}
+		config.Nodes[nodeName] = node
+	}
+
+	// Reference endpoints of chains given by chain ID
+	for chain, apiConf := range config.PublicApis {
+		if apiConf.ChainID != 0 {
+			config.PublicApis[chain] = apiConf.withChainlist()
+		}
+	}
+
+	return config, nil
+}
+
+// ExpandHome replaces a leading ~/ of path with the user's home directory
+func ExpandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, path[2:])
+		}
+	}
+	return path
+}
+
+// ResolvePath returns the explicit config path if set, otherwise NODESTAT_CONFIG,
+// otherwise the first existing file of ./nodestat.yaml, ~/.config/nodestat/config.yaml and ~/bin/nodes_conf.yaml
+func ResolvePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := os.Getenv("NODESTAT_CONFIG"); env != "" {
+		return env, nil
+	}
+
+	candidates := []string{"nodestat.yaml"}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates,
+			filepath.Join(homeDir, ".config", "nodestat", "config.yaml"),
+			filepath.Join(homeDir, "bin", "nodes_conf.yaml"),
+		)
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no config file found, tried %s", strings.Join(candidates, ", "))
+}
+
+// TxPoolConfig enables txpool_status collection and sets the pool sizes reported as ballooning
+type TxPoolConfig struct {
+	// MaxPending and MaxQueued are the transaction counts above which the pool is reported, 0 never reports
+	MaxPending int64 `json:"max_pending" yaml:"max_pending"`
+	MaxQueued  int64 `json:"max_queued" yaml:"max_queued"`
+}
+
+// CustomCheck is an extra JSON-RPC call of a node declared in the config, e.g. net_version on a
+// given chain or an eth_call of a contract, that fails when the call fails or its result is unexpected
+type CustomCheck struct {
+	Name   string        `json:"name" yaml:"name"`
+	Method string        `json:"method" yaml:"method"`
+	Params []interface{} `json:"params" yaml:"params"`
+	// Path is a JSONPath expression selecting the compared value from the result, e.g. {.number}
+	Path string `json:"path" yaml:"path"`
+	// Expect is the value the result, or the value selected by Path, must be equal to as JSON.
+	// Unset, the call only has to succeed and Path to match.
+	Expect interface{} `json:"expect" yaml:"expect"`
+	// Severity is the nagios state of a failed check, warning by default or critical
+	Severity string `json:"severity" yaml:"severity"`
+}
+
+// ArchiveConfig configures the historical state check of an archive node
+type ArchiveConfig struct {
+	// Block is the historical block whose state is queried, defaults to 1
+	Block int64 `json:"block" yaml:"block"`
+	// Address is the account whose balance is queried, defaults to the zero address
+	Address string `json:"address" yaml:"address"`
+}
+
+// TraceConfig marks a node as an archive/trace provider and configures the trace benchmark
+type TraceConfig struct {
+	// Method is debug_traceBlockByNumber or debug_traceTransaction (geth style) or trace_block (erigon/nethermind style)
+	Method string `json:"method" yaml:"method"`
+	// BlockOffset is the distance from head of the traced block
+	BlockOffset int64 `json:"block_offset" yaml:"block_offset"`
+	// MaxDuration is the deadline of the trace, the trace backend is reported as slow above it
+	MaxDuration time.Duration `json:"max_duration" yaml:"max_duration"`
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// starterConfig is written by config init, see example_nodes_conf.yaml for every option
+const starterConfig = `# nodestat configuration, see example_nodes_conf.yaml in the repository for every option
+#
+# nodes are keyed by name, each one is port-forwarded from its Kubernetes service:
+#   service:   name of the Kubernetes service in front of the node
+#   port:      service port of the JSON-RPC endpoint
+#   rpc_path:  HTTP path of the JSON-RPC endpoint ("" when served at the root)
+#   namespace: Kubernetes namespace of the service (default: the global namespace below)
+#   type:      chain adapter: evm (default), arbitrum, cosmos, bitcoin or substrate
+namespace: blockchains
+nodes:
+  eth:
+    service: eth
+    port: 80
+    rpc_path: /rpc
+  bsc:
+    service: bsc
+    port: 80
+    rpc_path: /rpc
+  arb:
+    type: arbitrum
+    service: arb
+    port: 80
+    rpc_path: /rpc
+  poly:
+    service: polygon
+    port: 80
+    rpc_path: /rpc
+  # nodes outside the cluster are queried directly
+  # eth-external:
+  #   chain: eth
+  #   url: https://rpc.internal:8545
+
+# public_apis provide the reference chain head per chain (Etherscan compatible APIs)
+public_apis:
+  eth:
+    url: https://api.etherscan.io/api
+    apikey: <your key>
+  bsc:
+    url: https://api.bscscan.com/api
+    apikey: <your key>
+  arb:
+    url: https://api.arbiscan.io/api
+    apikey: <your key>
+  poly:
+    url: https://api.polygonscan.com/api
+    apikey: <your key>
+`
+
+// DefaultPath is where config init writes without --config: NODESTAT_CONFIG or ~/.config/nodestat/config.yaml
+func DefaultPath() (string, error) {
+	if env := os.Getenv("NODESTAT_CONFIG"); env != "" {
+		return env, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "nodestat", "config.yaml"), nil
+}
+
+// Init writes the starter configuration to path, an existing file is only replaced with force
+func Init(path string, force bool) (string, error) {
+	if path == "" {
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return "", err
+		}
+	}
+	path = ExpandHome(path)
+
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(starterConfig), 0644)
+}
@@ -0,0 +1,422 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigProblem represents a single configuration error, Line is zero when unknown
+type ConfigProblem struct {
+	Line    int
+	Message string
+}
+
+// yamlErrorLine matches the line prefix of yaml.v2 errors
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// Validate loads the configuration at path strictly and reports every problem found in it
+func Validate(path string) (string, []ConfigProblem, error) {
+	configPath, err := ResolvePath(path)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Unknown fields and type mismatches, usually typos
+	var problems []ConfigProblem
+	var config NodeConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			return configPath, []ConfigProblem{yamlProblem(err.Error())}, nil
+		}
+		for _, msg := range typeErr.Errors {
+			problems = append(problems, yamlProblem(msg))
+		}
+	}
+
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil {
+		return configPath, append(problems, ConfigProblem{Message: err.Error()}), nil
+	}
+	lines := configLines{root: &root}
+
+	chains := make(map[string]bool)
+	services := make(map[string]string)
+	for _, nodeName := range slices.Sorted(maps.Keys(config.Nodes)) {
+		node := config.Nodes[nodeName]
+		chain := node.ChainName(nodeName)
+		chains[chain] = true
+		report := func(field string, format string, args ...interface{}) {
+			path := []string{"nodes", nodeName}
+			prefix := "nodes." + nodeName
+			if field != "" {
+				path = append(path, field)
+				prefix += "." + field
+			}
+			problems = append(problems, ConfigProblem{Line: lines.find(path...), Message: prefix + ": " + fmt.Sprintf(format, args...)})
+		}
+
+		if node.Type != "" && !slices.Contains(ChainTypes, node.Type) {
+			report("type", "unknown chain type %q", node.Type)
+		}
+		if node.URL == "" {
+			if node.Service == "" && node.SSH == nil && node.Docker == nil {
+				report("", "service is required unless url, ssh or docker is set")
+			}
+			if node.Port == 0 && node.IPC == nil {
+				report("", "port is required unless url or ipc is set")
+			}
+		}
+		if node.Port != 0 && !validPort(node.Port) {
+			report("port", "invalid port %d", node.Port)
+		}
+		if node.MinPeers < 0 {
+			report("min_peers", "must not be negative")
+		} else if node.MinPeers > 0 && node.Type == ChainTypeArbitrum {
+			report("min_peers", "arbitrum nodes have no peers to count")
+		}
+		if node.ExternalAddress != "" {
+			if _, _, err := net.SplitHostPort(node.ExternalAddress); err != nil {
+				report("external_address", "%v", err)
+			}
+		} else if node.P2PReachability && !node.IsEVM() {
+			report("p2p_reachability", "external_address is required, only evm nodes advertise an enode")
+		}
+		if node.Archive != nil && node.Archive.Block < 0 {
+			report("archive", "block must not be negative")
+		}
+		if node.MaxFinalityLag < 0 {
+			report("max_finality_lag", "must not be negative")
+		}
+		if node.MaxFeeDivergence < 0 {
+			report("max_fee_divergence", "must not be negative")
+		}
+		if node.Auth != nil {
+			if problem := authProblem(*node.Auth); problem != "" {
+				report("auth", "%s", problem)
+			}
+		}
+		if tls := node.TLS; tls != nil {
+			if node.URL != "" && !strings.HasPrefix(node.URL, "https://") && !strings.HasPrefix(node.URL, "wss://") {
+				report("tls", "url %s is not https or wss", node.URL)
+			}
+			if (tls.CertFile == "") != (tls.KeyFile == "") {
+				report("tls", "cert_file and key_file go together")
+			}
+			for _, file := range []string{tls.CAFile, tls.CertFile, tls.KeyFile} {
+				if _, err := os.Stat(ExpandHome(file)); file != "" && err != nil {
+					report("tls", "%v", err)
+				}
+			}
+		}
+		websocket := node.WSPath != "" || strings.HasPrefix(node.URL, "ws://") || strings.HasPrefix(node.URL, "wss://")
+		switch {
+		case node.WSPath != "" && node.URL != "":
+			report("ws_path", "only applies to port-forwarded nodes, use a ws:// or wss:// url")
+		case node.WSPath != "" && !strings.HasPrefix(node.WSPath, "/"):
+			report("ws_path", "%s does not start with /", node.WSPath)
+		case websocket && (node.Type == ChainTypeCosmos || node.Type == ChainTypeBitcoin):
+			report("ws_path", "%s nodes are only checked over HTTP", node.Type)
+		}
+		if ipc := node.IPC; ipc != nil {
+			if ipc.Path == "" && len(ipc.Command) == 0 {
+				report("ipc", "path or command is required")
+			}
+			if node.URL != "" || node.WSPath != "" || node.TLS != nil {
+				report("ipc", "doesn't go with url, ws_path or tls, the calls are piped through the socket")
+			}
+			if !node.IsEVM() {
+				report("ipc", "only evm nodes serve JSON-RPC over IPC")
+			}
+		}
+		if ssh := node.SSH; ssh != nil {
+			if ssh.Host == "" || ssh.User == "" || ssh.KeyFile == "" {
+				report("ssh", "host, user and key_file are required")
+			}
+			for _, file := range []string{ssh.KeyFile, ssh.KnownHosts} {
+				if _, err := os.Stat(ExpandHome(file)); file != "" && err != nil {
+					report("ssh", "%v", err)
+				}
+			}
+			if node.URL != "" || node.IPC != nil {
+				report("ssh", "doesn't go with url or ipc")
+			}
+		}
+		if docker := node.Docker; docker != nil {
+			if (docker.Container == "") == (docker.Label == "") {
+				report("docker", "exactly one of container and label is required")
+			}
+			if node.URL != "" || node.SSH != nil || node.IPC != nil {
+				report("docker", "doesn't go with url, ssh or ipc")
+			}
+		}
+		teleport := node.Teleport
+		if teleport == nil && node.InKubernetes() {
+			teleport = config.Teleport
+		}
+		if teleport != nil {
+			if !node.InKubernetes() {
+				report("teleport", "only applies to nodes in Kubernetes, not with url, ssh or docker")
+			} else if teleport.KubeCluster == "" && node.Context == "" {
+				report("teleport", "kube_cluster or the node context is required to select the Kubernetes cluster")
+			}
+			if node.Kubeconfig != "" {
+				report("kubeconfig", "doesn't go with teleport, tsh generates the kubeconfig")
+			}
+		}
+		if node.Proxy != "" {
+			if node.URL == "" {
+				report("proxy", "only applies to nodes with a url, port forwards are local")
+			} else if problem := proxyProblem(node.Proxy); problem != "" {
+				report("proxy", "%s", problem)
+			}
+		}
+		for _, expr := range node.Assertions {
+			if _, err := ParseAssertion(expr); err != nil {
+				report("assertions", "%v", err)
+			}
+		}
+		for i, check := range node.Checks {
+			if check.Name == "" || check.Method == "" {
+				report("checks", "check %d needs a name and a method", i)
+			}
+			switch check.Severity {
+			case "", SeverityWarning, SeverityCritical:
+			default:
+				report("checks", "check %d has unknown severity %q, expected warning or critical", i, check.Severity)
+			}
+		}
+		if bench := node.LogsBenchmark; bench != nil && (bench.Range < 0 || bench.Offset < 0 || bench.MaxDuration < 0) {
+			report("logs_benchmark", "range, offset and max_duration must not be negative")
+		}
+		if cadence := node.HeadCadence; cadence != nil {
+			if cadence.Window < 0 || cadence.BlockTime < 0 || cadence.MaxGap < 0 {
+				report("head_cadence", "window, block_time and max_gap must not be negative")
+			}
+			if !websocket && !slices.ContainsFunc(node.Endpoints, func(e Endpoint) bool { return e.Type == EndpointWS }) {
+				report("head_cadence", "needs a ws endpoint, a ws_path or a ws:// url")
+			}
+		}
+		if node.TxPool != nil && (node.TxPool.MaxPending < 0 || node.TxPool.MaxQueued < 0) {
+			report("txpool", "max_pending and max_queued must not be negative")
+		}
+		for i, endpoint := range node.Endpoints {
+			switch endpoint.Type {
+			case EndpointHTTP, EndpointWS, EndpointMetrics, EndpointBeacon, EndpointGraphQL:
+			case EndpointEngine:
+				if endpoint.Auth == nil || endpoint.Auth.Type != AuthJWT {
+					report("endpoints", "engine endpoint %d needs jwt auth", i)
+				}
+			default:
+				report("endpoints", "endpoint %d has unknown type %q", i, endpoint.Type)
+			}
+			if endpoint.Auth != nil {
+				if problem := authProblem(*endpoint.Auth); problem != "" {
+					report("endpoints", "endpoint %d: %s", i, problem)
+				}
+			}
+			if endpoint.URL == "" && !validPort(endpoint.Port) {
+				report("endpoints", "endpoint %d has invalid port %d", i, endpoint.Port)
+			}
+		}
+
+		if node.URL == "" && node.Service != "" {
+			namespace := node.Namespace
+			if namespace == "" {
+				namespace = config.Namespace
+			}
+			if namespace == "" {
+				namespace = DefaultNamespace
+			}
+			key := strings.Join([]string{node.Kubeconfig, node.Context, namespace, node.Service, strconv.Itoa(node.Port)}, "/")
+			if other, ok := services[key]; ok {
+				report("service", "same service and port as nodes.%s", other)
+			} else {
+				services[key] = nodeName
+			}
+		}
+
+		if _, ok := config.PublicApis[chain]; !ok {
+			report("", "no public_apis entry for chain %s", chain)
+		}
+	}
+
+	for _, chain := range slices.Sorted(maps.Keys(config.PublicApis)) {
+		apiConf := config.PublicApis[chain]
+		if !chains[chain] {
+			problems = append(problems, ConfigProblem{Line: lines.find("public_apis", chain), Message: fmt.Sprintf("public_apis.%s: unknown chain, no node uses it", chain)})
+		}
+		sources := append([]PublicAPI{apiConf}, apiConf.Fallbacks...)
+		for i, source := range sources {
+			name := "public_apis." + chain
+			if i > 0 {
+				name += fmt.Sprintf(".fallbacks[%d]", i-1)
+			}
+			if source.URL == "" && source.RPCURL == "" && (source.ChainID == 0 || i > 0) {
+				problems = append(problems, ConfigProblem{Line: lines.find("public_apis", chain), Message: fmt.Sprintf("%s: url or rpc_url is required", name)})
+			}
+			if source.Provider != "" && !slices.Contains(Providers, source.Provider) {
+				problems = append(problems, ConfigProblem{Line: lines.find("public_apis", chain), Message: fmt.Sprintf("%s: unknown provider %q", name, source.Provider)})
+			}
+			if source.RateLimit < 0 {
+				problems = append(problems, ConfigProblem{Line: lines.find("public_apis", chain), Message: fmt.Sprintf("%s: rate_limit must not be negative", name)})
+			}
+			if problem := proxyProblem(source.Proxy); source.Proxy != "" && problem != "" {
+				problems = append(problems, ConfigProblem{Line: lines.find("public_apis", chain), Message: fmt.Sprintf("%s: proxy %s", name, problem)})
+			}
+			if i > 0 && source.ChainID != 0 {
+				problems = append(problems, ConfigProblem{Line: lines.find("public_apis", chain), Message: fmt.Sprintf("%s: chain_id is only used on the public_apis entry itself", name)})
+			} else if source.ChainID != 0 && len(ChainlistRPCs(source.ChainID)) == 0 {
+				problems = append(problems, ConfigProblem{Line: lines.find("public_apis", chain), Message: fmt.Sprintf("%s: no public RPC endpoints of chain_id %d in chainlist, run nodestat chainlist update", name, source.ChainID)})
+			}
+		}
+	}
+	for section, keys := range map[string][]string{
+		"forks":           slices.Sorted(maps.Keys(config.Forks)),
+		"canary_accounts": slices.Sorted(maps.Keys(config.CanaryAccounts)),
+		"canaries":        slices.Sorted(maps.Keys(config.Canaries)),
+	} {
+		for _, chain := range keys {
+			if !chains[chain] {
+				problems = append(problems, ConfigProblem{Line: lines.find(section, chain), Message: fmt.Sprintf("%s.%s: unknown chain, no node uses it", section, chain)})
+			}
+		}
+	}
+
+	for _, expr := range config.Assertions {
+		if _, err := ParseAssertion(expr); err != nil {
+			problems = append(problems, ConfigProblem{Line: lines.find("assertions"), Message: fmt.Sprintf("assertions: %v", err)})
+		}
+	}
+
+	if influx := config.InfluxDB; influx != nil {
+		for _, field := range []struct{ key, value string }{{"url", influx.URL}, {"org", influx.Org}, {"bucket", influx.Bucket}} {
+			if field.value == "" {
+				problems = append(problems, ConfigProblem{Line: lines.find("influxdb"), Message: fmt.Sprintf("influxdb: %s is required", field.key)})
+			}
+		}
+	}
+
+	if config.GeoIPURL != "" {
+		if u, err := url.Parse(config.GeoIPURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, ConfigProblem{Line: lines.find("geoip_url"), Message: fmt.Sprintf("geoip_url: %s is not an http or https URL", config.GeoIPURL)})
+		}
+	}
+
+	if config.RPCTimeout < 0 {
+		problems = append(problems, ConfigProblem{Line: lines.find("rpc_timeout"), Message: fmt.Sprintf("rpc_timeout: invalid timeout %s", config.RPCTimeout)})
+	}
+
+	if config.ReferenceTTL < 0 {
+		problems = append(problems, ConfigProblem{Line: lines.find("reference_ttl"), Message: fmt.Sprintf("reference_ttl: invalid ttl %s", config.ReferenceTTL)})
+	}
+	if retry := config.Retry; retry != nil {
+		if retry.Count < 0 {
+			problems = append(problems, ConfigProblem{Line: lines.find("retry", "count"), Message: fmt.Sprintf("retry.count: invalid count %d", retry.Count)})
+		}
+		if retry.Backoff < 0 || retry.MaxBackoff < 0 {
+			problems = append(problems, ConfigProblem{Line: lines.find("retry"), Message: "retry: backoff must not be negative"})
+		}
+		if retry.Jitter < 0 || retry.Jitter > 1 {
+			problems = append(problems, ConfigProblem{Line: lines.find("retry", "jitter"), Message: fmt.Sprintf("retry.jitter: %g is not between 0 and 1", retry.Jitter)})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return configPath, problems, nil
+}
+
+// proxyProblem returns what is wrong with a proxy URL, empty when it is usable
+func proxyProblem(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return err.Error()
+	}
+	if !slices.Contains([]string{"http", "https", "socks5", "socks5h"}, u.Scheme) || u.Host == "" {
+		return fmt.Sprintf("%s is not an http, https or socks5 URL with a host", proxy)
+	}
+	return ""
+}
+
+// authProblem returns what is wrong with auth credentials, empty when they are usable
+func authProblem(auth NodeAuth) string {
+	switch auth.Type {
+	case "", AuthBasic:
+		if auth.User() == "" && auth.Pass() == "" {
+			return "basic auth needs a username and password, or their environment variables set"
+		}
+	case AuthBearer:
+		if auth.BearerToken() == "" {
+			return "bearer auth needs a token, or its environment variable set"
+		}
+	case AuthJWT:
+		if auth.JWTSecret == "" {
+			return "jwt auth needs the jwt_secret file"
+		}
+		if _, err := ReadJWTSecret(auth.JWTSecret); err != nil {
+			return err.Error()
+		}
+	default:
+		return fmt.Sprintf("unknown auth type %q, expected basic, bearer or jwt", auth.Type)
+	}
+	return ""
+}
+
+func yamlProblem(msg string) ConfigProblem {
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return ConfigProblem{Line: line, Message: m[2]}
+	}
+	return ConfigProblem{Message: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// configLines looks up the line of mapping keys in the parsed YAML document
+type configLines struct {
+	root *yamlv3.Node
+}
+
+// find returns the line of the deepest key of path that exists
+func (c configLines) find(path ...string) int {
+	if c.root == nil || len(c.root.Content) == 0 {
+		return 0
+	}
+	node := c.root.Content[0]
+	line := 0
+	for _, key := range path {
+		if node.Kind != yamlv3.MappingNode {
+			break
+		}
+		found := false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				line = node.Content[i].Line
+				node = node.Content[i+1]
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return line
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		// want are the expected problems in order, by line and a part of their message
+		want []ConfigProblem
+	}{
+		{
+			name: "valid",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+    assertions: ["diff < 50"]
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+		},
+		{
+			name: "unknown field",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+    prot: 8545
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+			want: []ConfigProblem{{Line: 4, Message: "field prot not found"}},
+		},
+		{
+			name: "port-forwarded node without service and port",
+			config: `nodes:
+  eth:
+    namespace: eth
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+			want: []ConfigProblem{
+				{Line: 2, Message: "nodes.eth: service is required unless url"},
+				{Line: 2, Message: "nodes.eth: port is required unless url"},
+			},
+		},
+		{
+			name: "invalid port",
+			config: `nodes:
+  eth:
+    service: geth
+    port: 70000
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+			want: []ConfigProblem{{Line: 4, Message: "nodes.eth.port: invalid port 70000"}},
+		},
+		{
+			name: "same service twice",
+			config: `nodes:
+  eth:
+    service: geth
+    port: 8545
+  eth2:
+    chain: eth
+    service: geth
+    port: 8545
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+			want: []ConfigProblem{{Line: 7, Message: "nodes.eth2.service: same service and port as nodes.eth"}},
+		},
+		{
+			name: "invalid assertions",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+    assertions: ["diff"]
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+assertions: ["peers = 3"]
+`,
+			want: []ConfigProblem{
+				{Line: 4, Message: `nodes.eth.assertions: invalid assertion "diff"`},
+				{Line: 8, Message: `assertions: invalid assertion "peers = 3"`},
+			},
+		},
+		{
+			name: "invalid custom checks",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+    checks:
+      - name: chain id
+      - name: block
+        method: eth_blockNumber
+        severity: fatal
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+			want: []ConfigProblem{
+				{Line: 4, Message: "nodes.eth.checks: check 0 needs a name and a method"},
+				{Line: 4, Message: `nodes.eth.checks: check 1 has unknown severity "fatal"`},
+			},
+		},
+		{
+			name: "invalid proxy",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+    proxy: ftp://proxy.example.com
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+			want: []ConfigProblem{{Line: 4, Message: "nodes.eth.proxy: ftp://proxy.example.com is not an http, https or socks5 URL"}},
+		},
+		{
+			name: "engine endpoint without jwt",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+    endpoints:
+      - type: engine
+        port: 8551
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+`,
+			want: []ConfigProblem{{Line: 4, Message: "nodes.eth.endpoints: engine endpoint 0 needs jwt auth"}},
+		},
+		{
+			name: "public_apis mismatch",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+public_apis:
+  btc:
+    url: https://api.example.com
+    provider: mempool
+`,
+			want: []ConfigProblem{
+				{Line: 2, Message: "nodes.eth: no public_apis entry for chain eth"},
+				{Line: 5, Message: "public_apis.btc: unknown chain, no node uses it"},
+				{Line: 5, Message: `public_apis.btc: unknown provider "mempool"`},
+			},
+		},
+		{
+			name: "fallback without url",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+    fallbacks:
+      - apikey_env: ETHERSCAN_KEY
+`,
+			want: []ConfigProblem{{Line: 5, Message: "public_apis.eth.fallbacks[0]: url or rpc_url is required"}},
+		},
+		{
+			name: "invalid retry and timeouts",
+			config: `nodes:
+  eth:
+    url: http://127.0.0.1:8545
+public_apis:
+  eth:
+    rpc_url: https://rpc.example.com
+rpc_timeout: -1s
+retry:
+  count: -1
+  jitter: 2
+`,
+			want: []ConfigProblem{
+				{Line: 7, Message: "rpc_timeout: invalid timeout -1s"},
+				{Line: 9, Message: "retry.count: invalid count -1"},
+				{Line: 10, Message: "retry.jitter: 2 is not between 0 and 1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nodestat.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			configPath, problems, err := Validate(path)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if configPath != path {
+				t.Errorf("Validate path = %s, want %s", configPath, path)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Validate problems = %+v, want %d problems", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := problems[i]
+				if got.Line != want.Line || !strings.Contains(got.Message, want.Message) {
+					t.Errorf("problem %d = %+v, want line %d with %q", i, got, want.Line, want.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMissingFile(t *testing.T) {
+	if _, _, err := Validate(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("Validate of a missing file succeeded")
+	}
+}
@@ -0,0 +1,132 @@
+package forward
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// defaultDockerHost is the socket of the local Docker daemon, used when DOCKER_HOST is not set
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// dockerContainer is an entry of the container list of the Docker Engine API
+type dockerContainer struct {
+	Names []string `json:"Names"`
+	Ports []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+// DockerNode points the node and its endpoints without a URL at the host ports published
+// for their container ports by the container of node.Docker
+func DockerNode(ctx context.Context, node config.Node) (config.Node, error) {
+	client, apiURL, host, err := dockerClient()
+	if err != nil {
+		return node, err
+	}
+	container, err := findContainer(ctx, client, apiURL, *node.Docker)
+	if err != nil {
+		return node, err
+	}
+
+	// IPv4 bindings are preferred, the same port is usually published on both
+	published := make(map[int]int)
+	for _, port := range container.Ports {
+		if _, ok := published[port.PrivatePort]; port.Type == "tcp" && port.PublicPort != 0 && (!ok || !strings.Contains(port.IP, ":")) {
+			published[port.PrivatePort] = port.PublicPort
+		}
+	}
+	ports := []int{node.Port}
+	for _, endpoint := range node.Endpoints {
+		if endpoint.URL == "" {
+			ports = append(ports, endpoint.Port)
+		}
+	}
+	for _, port := range ports {
+		if _, ok := published[port]; !ok {
+			return node, fmt.Errorf("port %d of container %s is not published", port, strings.TrimPrefix(container.Names[0], "/"))
+		}
+	}
+	return directNode(node, host, func(port int) int { return published[port] }), nil
+}
+
+// dockerClient returns a client of the Docker daemon of DOCKER_HOST with the base URL of its API
+// and the host its published ports are reached at
+func dockerClient() (*http.Client, string, string, error) {
+	dockerHost := os.Getenv("DOCKER_HOST")
+	if dockerHost == "" {
+		dockerHost = defaultDockerHost
+	}
+	u, err := url.Parse(dockerHost)
+	if err != nil {
+		return nil, "", "", err
+	}
+	switch u.Scheme {
+	case "unix":
+		transport := &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", u.Path)
+		}}
+		return &http.Client{Transport: transport, Timeout: portForwardTimeout}, "http://docker", "127.0.0.1", nil
+	case "tcp":
+		return &http.Client{Timeout: portForwardTimeout}, "http://" + u.Host, u.Hostname(), nil
+	default:
+		return nil, "", "", fmt.Errorf("unsupported DOCKER_HOST %s, expected unix:// or tcp://", dockerHost)
+	}
+}
+
+// findContainer returns the running container named conf.Container, or the first one with the label conf.Label
+func findContainer(ctx context.Context, client *http.Client, apiURL string, conf config.DockerConfig) (*dockerContainer, error) {
+	filter := map[string][]string{"label": {conf.Label}}
+	if conf.Container != "" {
+		filter = map[string][]string{"name": {conf.Container}}
+	}
+	filters, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"/containers/json?filters="+url.QueryEscape(string(filters)), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("listing containers: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var containers []dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("listing containers: %v", err)
+	}
+	for i, container := range containers {
+		if conf.Container == "" && len(container.Names) > 0 {
+			return &containers[i], nil
+		}
+		// The name filter matches substrings
+		for _, name := range container.Names {
+			if strings.TrimPrefix(name, "/") == conf.Container {
+				return &containers[i], nil
+			}
+		}
+	}
+	if conf.Container != "" {
+		return nil, fmt.Errorf("no running container %s", conf.Container)
+	}
+	return nil, fmt.Errorf("no running container with label %s", conf.Label)
+}
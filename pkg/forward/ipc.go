@@ -0,0 +1,98 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ipcIdleTimeout is the number of seconds nc waits for more of the answer before closing the socket
+const ipcIdleTimeout = 10
+
+// IPCBridge serves the JSON-RPC calls posted to a local port by piping each of them through
+// the IPC socket of a node, exec-ing into a pod of its service
+type IPCBridge struct {
+	server *http.Server
+}
+
+// Close stops serving the local port
+func (b *IPCBridge) Close() {
+	b.server.Close()
+}
+
+// ServeIPC serves the JSON-RPC calls to 127.0.0.1:localPort over the IPC socket of conf in a pod of the service,
+// one exec per call. Failed calls are answered with a JSON-RPC internal error.
+func (k *KubeClient) ServeIPC(ctx context.Context, namespace string, service string, conf config.IPCConfig, localPort int) (*IPCBridge, error) {
+	pod, err := k.ServicePod(ctx, namespace, service)
+	if err != nil {
+		return nil, err
+	}
+	container := conf.Container
+	if container == "" {
+		container = pod.Spec.Containers[0].Name
+	}
+	command := conf.Command
+	if len(command) == 0 {
+		command = []string{"nc", "-U", "-w", fmt.Sprint(ipcIdleTimeout), conf.Path}
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", localPort))
+	if err != nil {
+		return nil, err
+	}
+	bridge := &IPCBridge{server: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		answer, err := k.execIPC(r.Context(), pod, container, command, r.Body)
+		if err != nil {
+			answer, _ = json.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      nil,
+				"error":   map[string]interface{}{"code": -32603, "message": fmt.Sprintf("IPC call in pod %s: %v", pod.Name, err)},
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(answer)
+	})}}
+	go bridge.server.Serve(listener)
+	return bridge, nil
+}
+
+// execIPC runs command in the pod with the request on stdin and returns the first JSON value it writes,
+// the answer to the request. The command is stopped once answered, the socket may stay open.
+func (k *KubeClient) execIPC(ctx context.Context, pod *corev1.Pod, container string, command []string, request io.Reader) (json.RawMessage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stdout, stdoutWriter := io.Pipe()
+	var stderr bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		err := k.stream(ctx, pod, container, command, request, stdoutWriter, &stderr)
+		stdoutWriter.CloseWithError(err)
+		done <- err
+	}()
+
+	var answer json.RawMessage
+	err := json.NewDecoder(stdout).Decode(&answer)
+	cancel()
+	stdout.Close()
+	streamErr := <-done
+	if err == nil {
+		return answer, nil
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return nil, errors.New(msg)
+	}
+	if streamErr != nil {
+		return nil, streamErr
+	}
+	return nil, fmt.Errorf("no answer on the IPC socket: %v", err)
+}
@@ -0,0 +1,377 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	// portForwardTimeout bounds the time a port forward may take to become ready
+	portForwardTimeout = 30 * time.Second
+	// portForwardCloseTimeout bounds the time a port forward may take to release its listeners
+	portForwardCloseTimeout = 5 * time.Second
+)
+
+var (
+	activeForwardsMu sync.Mutex
+	activeForwards   = make(map[*PortForward]struct{})
+)
+
+// KubeClient talks to the Kubernetes API the nodes run behind
+type KubeClient struct {
+	// Context is the name of the kubeconfig context the client uses
+	Context string
+	// InCluster is set when the client uses the pod's service account, nodes are then reached through the cluster DNS
+	InCluster bool
+	config    *rest.Config
+	clientset kubernetes.Interface
+}
+
+// NewKubeClient creates a client for a kubeconfig context. An empty kubeconfig uses the default
+// loading rules (KUBECONFIG, ~/.kube/config), an empty context the current one.
+func NewKubeClient(kubeconfig string, contextName string) (*KubeClient, error) {
+	// Inside a pod the service account is used unless another cluster is configured explicitly
+	if kubeconfig == "" && contextName == "" {
+		if cfg, err := rest.InClusterConfig(); err == nil {
+			clientset, err := kubernetes.NewForConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+			return &KubeClient{InCluster: true, config: cfg, clientset: clientset}, nil
+		}
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = config.ExpandHome(kubeconfig)
+	}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: contextName})
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if contextName == "" {
+		if raw, err := loader.RawConfig(); err == nil {
+			contextName = raw.CurrentContext
+		}
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &KubeClient{Context: contextName, config: cfg, clientset: clientset}, nil
+}
+
+// KubeClients lazily creates one client per kubeconfig and context, so nodes of several clusters are checked in one run
+type KubeClients struct {
+	mu      sync.Mutex
+	clients map[[2]string]*KubeClient
+}
+
+// NewKubeClients creates an empty client pool
+func NewKubeClients() *KubeClients {
+	return &KubeClients{clients: make(map[[2]string]*KubeClient)}
+}
+
+// Get returns the client of the node's kubeconfig and context
+func (k *KubeClients) Get(node config.Node) (*KubeClient, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := [2]string{node.Kubeconfig, node.Context}
+	if client, ok := k.clients[key]; ok {
+		return client, nil
+	}
+	client, err := NewKubeClient(node.Kubeconfig, node.Context)
+	if err != nil {
+		return nil, err
+	}
+	k.clients[key] = client
+	return client, nil
+}
+
+// Forget drops the client of the node's kubeconfig and context, e.g. once its kubeconfig is gone
+func (k *KubeClients) Forget(node config.Node) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.clients, [2]string{node.Kubeconfig, node.Context})
+}
+
+// ClusterDNSNode points the node and its endpoints at their service.namespace.svc addresses
+func ClusterDNSNode(node config.Node) config.Node {
+	host := fmt.Sprintf("%s.%s.svc", node.Service, node.Namespace)
+	return directNode(node, host, func(port int) int { return port })
+}
+
+// directNode points the node and its endpoints without a URL at host, on the ports that port maps their ports to
+func directNode(node config.Node, host string, port func(int) int) config.Node {
+	scheme, path := "http", node.RPCPath
+	if node.WSPath != "" {
+		scheme, path = "ws", node.WSPath
+	}
+	if node.TLS != nil {
+		scheme += "s"
+	}
+	node.URL = fmt.Sprintf("%s://%s:%d%s", scheme, host, port(node.Port), path)
+
+	endpoints := make([]config.Endpoint, len(node.Endpoints))
+	for i, endpoint := range node.Endpoints {
+		if endpoint.URL == "" {
+			scheme := "http"
+			if endpoint.Type == config.EndpointWS {
+				scheme = "ws"
+			}
+			endpoint.URL = fmt.Sprintf("%s://%s:%d%s", scheme, host, port(endpoint.Port), endpoint.Path)
+		}
+		endpoints[i] = endpoint
+	}
+	node.Endpoints = endpoints
+	return node
+}
+
+// PortForward represents an established port forward to a service pod
+type PortForward struct {
+	stopChan chan struct{}
+	doneChan chan struct{}
+	stopOnce sync.Once
+	// errOut receives the errors of the forward, as given to ForwardService
+	errOut io.Writer
+}
+
+// Close stops the port forward and waits until its listeners are released.
+// A forward stuck on a dead connection is abandoned after portForwardCloseTimeout.
+func (pf *PortForward) Close() {
+	activeForwardsMu.Lock()
+	delete(activeForwards, pf)
+	activeForwardsMu.Unlock()
+
+	pf.stopOnce.Do(func() { close(pf.stopChan) })
+	select {
+	case <-pf.doneChan:
+	case <-time.After(portForwardCloseTimeout):
+		fmt.Fprintf(pf.errOut, "did not stop within %s, abandoning it\n", portForwardCloseTimeout)
+	}
+}
+
+// CloseAllForwards stops every port forward and tsh proxy created by this process
+func CloseAllForwards() {
+	activeForwardsMu.Lock()
+	forwards := make([]*PortForward, 0, len(activeForwards))
+	for pf := range activeForwards {
+		forwards = append(forwards, pf)
+	}
+	activeForwardsMu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, pf := range forwards {
+		wg.Add(1)
+		go func(pf *PortForward) {
+			defer wg.Done()
+			pf.Close()
+		}(pf)
+	}
+	wg.Wait()
+	closeTeleportProxies()
+}
+
+// ForwardService forwards local ports to a pod backing the service, like kubectl port-forward service/<name>.
+// ports are "local:servicePort" pairs, service ports are translated to the pod's target ports.
+// The call returns once the forward is ready to accept connections, ctx only bounds the setup.
+func (k *KubeClient) ForwardService(ctx context.Context, namespace string, service string, ports []string, errOut io.Writer) (*PortForward, error) {
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, portForwardTimeout)
+	defer cancel()
+
+	svc, err := k.clientset.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pod, err := k.servicePod(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	podPorts := make([]string, 0, len(ports))
+	for _, pair := range ports {
+		local, remote, _ := strings.Cut(pair, ":")
+		servicePort, err := strconv.Atoi(remote)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port pair %q", pair)
+		}
+		targetPort, err := podTargetPort(svc, pod, servicePort)
+		if err != nil {
+			return nil, err
+		}
+		podPorts = append(podPorts, fmt.Sprintf("%s:%d", local, targetPort))
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(k.config)
+	if err != nil {
+		return nil, err
+	}
+	url := k.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+
+	pf := &PortForward{stopChan: make(chan struct{}), doneChan: make(chan struct{}), errOut: errOut}
+	readyChan := make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, podPorts, pf.stopChan, readyChan, io.Discard, errOut)
+	if err != nil {
+		return nil, err
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- forwarder.ForwardPorts()
+		close(pf.doneChan)
+	}()
+
+	select {
+	case <-readyChan:
+		activeForwardsMu.Lock()
+		activeForwards[pf] = struct{}{}
+		activeForwardsMu.Unlock()
+		return pf, nil
+	case err := <-errChan:
+		if err == nil {
+			err = errors.New("port forward closed before it became ready")
+		}
+		return nil, err
+	case <-ctx.Done():
+		pf.stopOnce.Do(func() { close(pf.stopChan) })
+		if err := parent.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("port forward to %s/%s not ready after %s", namespace, service, portForwardTimeout)
+	}
+}
+
+// ServicePod returns a running pod backing the service
+func (k *KubeClient) ServicePod(ctx context.Context, namespace string, service string) (*corev1.Pod, error) {
+	ctx, cancel := context.WithTimeout(ctx, portForwardTimeout)
+	defer cancel()
+
+	svc, err := k.clientset.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return k.servicePod(ctx, svc)
+}
+
+func (k *KubeClient) servicePod(ctx context.Context, svc *corev1.Service) (*corev1.Pod, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return nil, fmt.Errorf("service %s has no selector", svc.Name)
+	}
+	pods, err := k.clientset.CoreV1().Pods(svc.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no running pod found for service %s", svc.Name)
+}
+
+// podTargetPort translates a service port into the container port of the pod
+func podTargetPort(svc *corev1.Service, pod *corev1.Pod, servicePort int) (int, error) {
+	for _, port := range svc.Spec.Ports {
+		if int(port.Port) != servicePort {
+			continue
+		}
+		switch {
+		case port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0:
+			return int(port.TargetPort.IntVal), nil
+		case port.TargetPort.Type == intstr.String && port.TargetPort.StrVal != "":
+			for _, container := range pod.Spec.Containers {
+				for _, containerPort := range container.Ports {
+					if containerPort.Name == port.TargetPort.StrVal {
+						return int(containerPort.ContainerPort), nil
+					}
+				}
+			}
+			return 0, fmt.Errorf("named port %s not found in pod %s", port.TargetPort.StrVal, pod.Name)
+		default:
+			return servicePort, nil
+		}
+	}
+	return 0, fmt.Errorf("service %s has no port %d", svc.Name, servicePort)
+}
+
+// Exec runs a command in the first container of a pod backing the service and returns its combined output
+func (k *KubeClient) Exec(ctx context.Context, namespace string, service string, command []string) (string, error) {
+	pod, err := k.ServicePod(ctx, namespace, service)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	err = k.stream(ctx, pod, pod.Spec.Containers[0].Name, command, nil, &out, &out)
+	return out.String(), err
+}
+
+// stream runs a command in a container of the pod until it exits or ctx is canceled,
+// with stdin, if not nil, piped to its standard input
+func (k *KubeClient) stream(ctx context.Context, pod *corev1.Pod, container string, command []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	req := k.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(k.config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+}
+
+// Logs returns the logs of all containers of a pod backing the service written during the last since duration
+func (k *KubeClient) Logs(ctx context.Context, namespace string, service string, since time.Duration) (string, error) {
+	pod, err := k.ServicePod(ctx, namespace, service)
+	if err != nil {
+		return "", err
+	}
+
+	sinceSeconds := int64(since.Seconds())
+	var out bytes.Buffer
+	for _, container := range pod.Spec.Containers {
+		data, err := k.clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container:    container.Name,
+			SinceSeconds: &sinceSeconds,
+		}).DoRaw(ctx)
+		if err != nil {
+			return "", err
+		}
+		out.Write(data)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,144 @@
+package forward
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultKnownHosts verifies the SSH host keys when no known_hosts file is configured
+const defaultKnownHosts = "~/.ssh/known_hosts"
+
+// SSHTunnel forwards local ports to ports reached from an SSH server, as ssh -L does
+type SSHTunnel struct {
+	client    *ssh.Client
+	listeners []net.Listener
+	closeOnce sync.Once
+}
+
+// Close stops listening on the local ports and closes the SSH connection with the connections through it
+func (t *SSHTunnel) Close() {
+	t.closeOnce.Do(func() {
+		for _, listener := range t.listeners {
+			listener.Close()
+		}
+		t.client.Close()
+	})
+}
+
+// ForwardSSH connects to the SSH server of conf and forwards ports, "local:remote" pairs, to the remote
+// ports of conf.Target on the server side. Connections that can't be forwarded are reported to errOut.
+// ctx bounds the connection and the handshake.
+func ForwardSSH(ctx context.Context, conf config.SSHConfig, ports []string, errOut io.Writer) (*SSHTunnel, error) {
+	clientConf, err := sshClientConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	host := conf.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+	dialer := net.Dialer{Timeout: clientConf.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	// ssh.NewClientConn takes no context, cancelling ctx interrupts the handshake through the deadline
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, host, clientConf)
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+
+	target := conf.Target
+	if target == "" {
+		target = "localhost"
+	}
+	tunnel := &SSHTunnel{client: client}
+	for _, pair := range ports {
+		local, remote, _ := strings.Cut(pair, ":")
+		listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", local))
+		if err != nil {
+			tunnel.Close()
+			return nil, err
+		}
+		tunnel.listeners = append(tunnel.listeners, listener)
+		go tunnel.serve(listener, net.JoinHostPort(target, remote), errOut)
+	}
+	return tunnel, nil
+}
+
+// serve forwards the connections accepted by listener to addr through the SSH connection until the tunnel is closed
+func (t *SSHTunnel) serve(listener net.Listener, addr string, errOut io.Writer) {
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer local.Close()
+			remote, err := t.client.Dial("tcp", addr)
+			if err != nil {
+				fmt.Fprintf(errOut, "dialing %s: %v\n", addr, err)
+				return
+			}
+			defer remote.Close()
+
+			done := make(chan struct{}, 2)
+			go func() {
+				io.Copy(remote, local)
+				done <- struct{}{}
+			}()
+			go func() {
+				io.Copy(local, remote)
+				done <- struct{}{}
+			}()
+			// Either side closing ends the forwarded connection
+			<-done
+		}()
+	}
+}
+
+// sshClientConfig authenticates with the key file of conf and verifies the host key against its known hosts
+func sshClientConfig(conf config.SSHConfig) (*ssh.ClientConfig, error) {
+	key, err := os.ReadFile(config.ExpandHome(conf.KeyFile))
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", conf.KeyFile, err)
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !conf.InsecureIgnoreHostKey {
+		knownHosts := conf.KnownHosts
+		if knownHosts == "" {
+			knownHosts = defaultKnownHosts
+		}
+		if hostKeyCallback, err = knownhosts.New(config.ExpandHome(knownHosts)); err != nil {
+			return nil, err
+		}
+	}
+	return &ssh.ClientConfig{
+		User:            conf.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         portForwardTimeout,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package forward
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// teleportProxy is a running tsh proxy kube process shared by the nodes of its Kubernetes cluster
+type teleportProxy struct {
+	cmd        *exec.Cmd
+	kubeconfig string
+	users      int
+}
+
+var (
+	teleportProxiesMu sync.Mutex
+	teleportProxies   = make(map[config.TeleportConfig]*teleportProxy)
+)
+
+// TeleportKubeconfig starts tsh proxy kube for the Kubernetes cluster of conf, unless nodes of the cluster
+// already use one, and returns the kubeconfig of the local proxy with the function releasing it.
+// The process stops once every node released it, ctx only bounds its start.
+func TeleportKubeconfig(ctx context.Context, conf config.TeleportConfig) (string, func(), error) {
+	teleportProxiesMu.Lock()
+	defer teleportProxiesMu.Unlock()
+
+	proxy, ok := teleportProxies[conf]
+	if !ok {
+		var err error
+		if proxy, err = startTeleportProxy(ctx, conf); err != nil {
+			return "", nil, err
+		}
+		teleportProxies[conf] = proxy
+	}
+	proxy.users++
+	var releaseOnce sync.Once
+	release := func() {
+		releaseOnce.Do(func() { releaseTeleportProxy(conf, proxy) })
+	}
+	return proxy.kubeconfig, release, nil
+}
+
+// startTeleportProxy runs tsh proxy kube and waits for the kubeconfig it prints
+func startTeleportProxy(ctx context.Context, conf config.TeleportConfig) (*teleportProxy, error) {
+	tsh := conf.Tsh
+	if tsh == "" {
+		tsh = "tsh"
+	}
+	args := []string{"proxy", "kube", conf.KubeCluster}
+	if conf.Proxy != "" {
+		args = append(args, "--proxy="+conf.Proxy)
+	}
+	if conf.Cluster != "" {
+		args = append(args, "--cluster="+conf.Cluster)
+	}
+	cmd := exec.Command(tsh, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	kubeconfig := make(chan string, 1)
+	exited := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if path, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "export KUBECONFIG="); ok {
+				kubeconfig <- path
+				break
+			}
+		}
+		// Keep reading so that tsh never blocks on a full pipe
+		io.Copy(io.Discard, stdout)
+		exited <- cmd.Wait()
+	}()
+
+	select {
+	case path := <-kubeconfig:
+		return &teleportProxy{cmd: cmd, kubeconfig: path}, nil
+	case err := <-exited:
+		// stderr is complete once the process was waited for
+		return nil, fmt.Errorf("tsh proxy kube %s exited: %v: %s", conf.KubeCluster, err, strings.TrimSpace(stderr.String()))
+	case <-time.After(portForwardTimeout):
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("tsh proxy kube %s printed no kubeconfig within %s", conf.KubeCluster, portForwardTimeout)
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		return nil, ctx.Err()
+	}
+}
+
+// releaseTeleportProxy stops the process of conf when proxy has no more users
+func releaseTeleportProxy(conf config.TeleportConfig, proxy *teleportProxy) {
+	teleportProxiesMu.Lock()
+	defer teleportProxiesMu.Unlock()
+
+	proxy.users--
+	if proxy.users > 0 {
+		return
+	}
+	if teleportProxies[conf] == proxy {
+		delete(teleportProxies, conf)
+	}
+	stopTeleportProxy(proxy)
+}
+
+// stopTeleportProxy interrupts tsh so that it removes its kubeconfig, killing it where interrupts aren't supported
+func stopTeleportProxy(proxy *teleportProxy) {
+	if err := proxy.cmd.Process.Signal(os.Interrupt); err != nil {
+		proxy.cmd.Process.Kill()
+	}
+}
+
+// closeTeleportProxies stops every tsh proxy kube process regardless of its users
+func closeTeleportProxies() {
+	teleportProxiesMu.Lock()
+	defer teleportProxiesMu.Unlock()
+
+	for conf, proxy := range teleportProxies {
+		delete(teleportProxies, conf)
+		stopTeleportProxy(proxy)
+	}
+}
@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BatchCall is a call of a JSON-RPC batch
+type BatchCall struct {
+	Method string
+	Params []interface{}
+}
+
+// BatchResult is the answer of a batched call, Error is set when the node answered it with an error
+type BatchResult struct {
+	Result json.RawMessage
+	Error  error
+}
+
+// batchAnswer is an entry of a batch response
+type batchAnswer struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Batch sends calls as a single JSON-RPC batch request, traced as one span.
+// The results are in the order of calls, an error is returned when the node doesn't answer the batch as a whole.
+func Batch(ctx context.Context, rpcURL string, auth *config.NodeAuth, calls []BatchCall) ([]BatchResult, error) {
+	methods := make([]string, len(calls))
+	requests := make([]map[string]interface{}, len(calls))
+	for i, call := range calls {
+		params := call.Params
+		if params == nil {
+			params = []interface{}{}
+		}
+		methods[i] = call.Method
+		requests[i] = map[string]interface{}{"jsonrpc": "2.0", "method": call.Method, "params": params, "id": i + 1}
+	}
+	label := "batch(" + strings.Join(methods, ",") + ")"
+
+	ctx, span := tracer.Start(ctx, label, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rpc.system", "jsonrpc"), attribute.StringSlice("rpc.methods", methods)))
+	defer span.End()
+
+	results, err := batch(ctx, rpcURL, auth, label, requests)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return results, err
+}
+
+func batch(ctx context.Context, rpcURL string, auth *config.NodeAuth, label string, requests []map[string]interface{}) ([]BatchResult, error) {
+	payload, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	body, err := post(ctx, rpcURL, auth, label, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes without batch support answer with a single error object
+	var answers []batchAnswer
+	if err := json.Unmarshal(body, &answers); err != nil {
+		return nil, fmt.Errorf("batch not supported: %s", strings.TrimSpace(string(body)))
+	}
+
+	// Answers may come in any order
+	results := make([]BatchResult, len(requests))
+	for i := range results {
+		results[i].Error = fmt.Errorf("no answer in batch")
+	}
+	for _, answer := range answers {
+		if answer.ID < 1 || answer.ID > len(results) {
+			continue
+		}
+		if answer.Error != nil {
+			results[answer.ID-1] = BatchResult{Error: answer.Error}
+			continue
+		}
+		results[answer.ID-1] = BatchResult{Result: answer.Result}
+	}
+	return results, nil
+}
+
+// prefetchKey is the context key of the prefetched answers
+type prefetchKey struct{}
+
+// prefetched holds the batched answers of a node not yet taken by a call
+type prefetched struct {
+	mu      sync.Mutex
+	url     string
+	results map[string]json.RawMessage
+}
+
+// Prefetch sends the methods, all without params, to the node as a single batch and returns a context
+// on which the first call of each method gets its prefetched answer instead of a round trip.
+// Later calls, e.g. to sample a method over time, reach the node again.
+// When the node rejects the batch, or answers a method with an error, the calls are sent as usual.
+func Prefetch(ctx context.Context, node config.Node, localPort int, methods ...string) context.Context {
+	if len(methods) == 0 {
+		return ctx
+	}
+	calls := make([]BatchCall, len(methods))
+	for i, method := range methods {
+		calls[i] = BatchCall{Method: method}
+	}
+
+	rpcURL := NodeURL(node, localPort)
+	results, err := Batch(ctx, rpcURL, node.Auth, calls)
+	if err != nil {
+		return ctx
+	}
+	store := &prefetched{url: rpcURL, results: make(map[string]json.RawMessage, len(methods))}
+	for i, res := range results {
+		if res.Error == nil {
+			store.results[methods[i]] = res.Result
+		}
+	}
+	return context.WithValue(ctx, prefetchKey{}, store)
+}
+
+// takePrefetched returns and forgets the prefetched answer of method from rpcURL, if any
+func takePrefetched(ctx context.Context, rpcURL string, method string) (json.RawMessage, bool) {
+	store, ok := ctx.Value(prefetchKey{}).(*prefetched)
+	if !ok || store.url != rpcURL {
+		return nil, false
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	result, ok := store.results[method]
+	delete(store.results, method)
+	return result, ok
+}
@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+func TestBatch(t *testing.T) {
+	calls := []BatchCall{{Method: "net_peerCount"}, {Method: "eth_blockNumber"}, {Method: "eth_syncing"}}
+	tests := []struct {
+		name   string
+		answer string
+		// want are the results by call, an empty string when the call fails
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "in order",
+			answer: `[{"id":1,"result":"0x19"},{"id":2,"result":"0x64"},{"id":3,"result":false}]`,
+			want:   []string{`"0x19"`, `"0x64"`, `false`},
+		},
+		{
+			name:   "reordered",
+			answer: `[{"id":3,"result":false},{"id":1,"result":"0x19"},{"id":2,"result":"0x64"}]`,
+			want:   []string{`"0x19"`, `"0x64"`, `false`},
+		},
+		{
+			name:   "error answer",
+			answer: `[{"id":2,"result":"0x64"},{"id":1,"error":{"code":-32601,"message":"not found"}},{"id":3,"result":false}]`,
+			want:   []string{"", `"0x64"`, `false`},
+		},
+		{
+			name:   "missing and unknown ids",
+			answer: `[{"id":2,"result":"0x64"},{"id":7,"result":"0x1"}]`,
+			want:   []string{"", `"0x64"`, ""},
+		},
+		{
+			name:    "batch not supported",
+			answer:  `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch not supported"}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var requests []map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&requests); err != nil || len(requests) != len(calls) {
+					t.Errorf("batch request = %v, %v", requests, err)
+				}
+				w.Write([]byte(tt.answer))
+			}))
+			defer srv.Close()
+
+			results, err := Batch(context.Background(), srv.URL, nil, calls)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Batch = %v, want an error", results)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Batch: %v", err)
+			}
+			for i, want := range tt.want {
+				got := results[i]
+				if want == "" {
+					if got.Error == nil {
+						t.Errorf("result %d = %s, want an error", i, got.Result)
+					}
+					continue
+				}
+				if got.Error != nil || string(got.Result) != want {
+					t.Errorf("result %d = %s, %v, want %s", i, got.Result, got.Error, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, isBatch := body.([]interface{}); isBatch {
+			w.Write([]byte(`[{"id":2,"error":{"code":-32601,"message":"not found"}},{"id":1,"result":"0x64"}]`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x65"}`))
+	}))
+	defer srv.Close()
+	node := config.Node{URL: srv.URL}
+
+	ctx := Prefetch(context.Background(), node, 0, "eth_blockNumber", "eth_syncing")
+	tests := []struct {
+		method       string
+		want         string
+		wantRequests int
+	}{
+		// The first call is answered by the batch, the next one reaches the node
+		{method: "eth_blockNumber", want: `"0x64"`, wantRequests: 1},
+		{method: "eth_blockNumber", want: `"0x65"`, wantRequests: 2},
+		// Answered with an error in the batch, so called again
+		{method: "eth_syncing", want: `"0x65"`, wantRequests: 3},
+	}
+	for _, tt := range tests {
+		got, err := CallRaw(ctx, node, 0, tt.method)
+		if err != nil {
+			t.Fatalf("CallRaw(%s): %v", tt.method, err)
+		}
+		if string(got) != tt.want || requests != tt.wantRequests {
+			t.Errorf("CallRaw(%s) = %s after %d requests, want %s after %d", tt.method, got, requests, tt.want, tt.wantRequests)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Client is shared by every call, so connections to nodes and APIs are pooled and kept alive
+// across calls and runs. Calls are bounded by the timeout of their Options through their context instead of a client timeout.
+var Client = &http.Client{
+	Transport: &http.Transport{
+		Proxy: proxyFromEnvironment(),
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2: true,
+		MaxIdleConns:      100,
+		// The checks of a node run concurrently, the default of 2 would reopen connections
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// proxyFromEnvironment returns the proxy of a request from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// (or their lowercase versions) as http.ProxyFromEnvironment does, falling back to ALL_PROXY.
+// Proxy URLs may be http, https or socks5. Requests to localhost, like port forwards, are never proxied.
+func proxyFromEnvironment() func(*http.Request) (*url.URL, error) {
+	conf := httpproxy.FromEnvironment()
+	all := os.Getenv("ALL_PROXY")
+	if all == "" {
+		all = os.Getenv("all_proxy")
+	}
+	if conf.HTTPProxy == "" {
+		conf.HTTPProxy = all
+	}
+	if conf.HTTPSProxy == "" {
+		conf.HTTPSProxy = all
+	}
+	proxy := conf.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// cancelBody releases the timeout of a call once its response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
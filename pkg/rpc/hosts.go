@@ -0,0 +1,108 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// HostSettings are the connection settings of a host differing from the shared Client
+type HostSettings struct {
+	// TLS configures the TLS connections to the host, the defaults when nil
+	TLS *config.TLSConfig
+	// Proxy is the URL of the HTTP or SOCKS5 proxy reaching the host, instead of the proxy environment variables
+	Proxy string
+}
+
+// hostClient is the client of a host with its own settings
+type hostClient struct {
+	tls    config.TLSConfig
+	proxy  string
+	client *http.Client
+}
+
+// hostClients are the clients of the hosts configured with ConfigureHost, by host:port
+var (
+	hostMu      sync.RWMutex
+	hostClients = make(map[string]hostClient)
+)
+
+// ConfigureHost makes the calls to the host of rpcURL use settings.
+// The client of the host is kept across runs as long as its settings don't change.
+func ConfigureHost(rpcURL string, settings HostSettings) error {
+	u, err := url.Parse(rpcURL)
+	if err != nil {
+		return err
+	}
+	entry := hostClient{proxy: settings.Proxy}
+	if settings.TLS != nil {
+		entry.tls = *settings.TLS
+	}
+	hostMu.RLock()
+	current, ok := hostClients[u.Host]
+	hostMu.RUnlock()
+	if ok && current.tls == entry.tls && current.proxy == entry.proxy {
+		return nil
+	}
+
+	transport := Client.Transport.(*http.Transport).Clone()
+	if settings.TLS != nil {
+		if transport.TLSClientConfig, err = buildTLSConfig(*settings.TLS); err != nil {
+			return err
+		}
+	}
+	if settings.Proxy != "" {
+		proxyURL, err := url.Parse(settings.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy %s: %v", settings.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	entry.client = &http.Client{Transport: transport}
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	hostClients[u.Host] = entry
+	return nil
+}
+
+// clientFor returns the client of the host of req, the shared Client unless the host has its own settings
+func clientFor(req *http.Request) *http.Client {
+	hostMu.RLock()
+	defer hostMu.RUnlock()
+	if c, ok := hostClients[req.URL.Host]; ok {
+		return c.client
+	}
+	return Client
+}
+
+func buildTLSConfig(conf config.TLSConfig) (*tls.Config, error) {
+	tlsConf := &tls.Config{ServerName: conf.ServerName, InsecureSkipVerify: conf.InsecureSkipVerify}
+	if conf.CAFile != "" {
+		pem, err := os.ReadFile(config.ExpandHome(conf.CAFile))
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in CA bundle %s", conf.CAFile)
+		}
+		tlsConf.RootCAs = pool
+	}
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.ExpandHome(conf.CertFile), config.ExpandHome(conf.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConf, nil
+}
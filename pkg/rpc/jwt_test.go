@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+func TestJWTToken(t *testing.T) {
+	secret := make([]byte, 32)
+	for i := range secret {
+		secret[i] = byte(i)
+	}
+	tests := []struct {
+		name   string
+		now    time.Time
+		claims string
+	}{
+		{name: "epoch", now: time.Unix(0, 0), claims: `{"iat":0}`},
+		{name: "now", now: time.Unix(1700000000, 0), claims: `{"iat":1700000000}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := strings.Split(jwtToken(secret, tt.now), ".")
+			if len(parts) != 3 {
+				t.Fatalf("token has %d parts, want 3", len(parts))
+			}
+			header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+			if string(header) != `{"alg":"HS256","typ":"JWT"}` {
+				t.Errorf("header = %s", header)
+			}
+			claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			if string(claims) != tt.claims {
+				t.Errorf("claims = %s, want %s", claims, tt.claims)
+			}
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(parts[0] + "." + parts[1]))
+			if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+				t.Errorf("signature = %s, want %s", parts[2], want)
+			}
+		})
+	}
+}
+
+func TestSetAuth(t *testing.T) {
+	secret := strings.Repeat("ab", 32)
+	secretPath := filepath.Join(t.TempDir(), "jwt.hex")
+	if err := os.WriteFile(secretPath, []byte("0x"+secret+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NODESTAT_TEST_TOKEN", "from-env")
+
+	tests := []struct {
+		name string
+		auth *config.NodeAuth
+		// want is the expected Authorization header, its prefix for JWT tokens
+		want    string
+		wantErr bool
+	}{
+		{name: "none", auth: nil, want: ""},
+		{name: "basic", auth: &config.NodeAuth{Username: "user", Password: "pass"}, want: "Basic dXNlcjpwYXNz"},
+		{name: "bearer", auth: &config.NodeAuth{Type: config.AuthBearer, Token: "secret"}, want: "Bearer secret"},
+		{name: "bearer from env", auth: &config.NodeAuth{Type: config.AuthBearer, TokenEnv: "NODESTAT_TEST_TOKEN"}, want: "Bearer from-env"},
+		{name: "jwt", auth: &config.NodeAuth{Type: config.AuthJWT, JWTSecret: secretPath}, want: "Bearer " + jwtHeader + "."},
+		{name: "missing jwt secret", auth: &config.NodeAuth{Type: config.AuthJWT, JWTSecret: filepath.Join(t.TempDir(), "missing")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "http://127.0.0.1:8551", nil)
+			err := setAuth(req, tt.auth)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("setAuth succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setAuth: %v", err)
+			}
+			got := req.Header.Get("Authorization")
+			if tt.auth != nil && tt.auth.Type == config.AuthJWT {
+				// The token is signed with the secret of the file
+				key, _ := hex.DecodeString(secret)
+				parts := strings.Split(strings.TrimPrefix(got, "Bearer "), ".")
+				if len(parts) != 3 {
+					t.Fatalf("Authorization = %q, want a bearer JWT", got)
+				}
+				mac := hmac.New(sha256.New, key)
+				mac.Write([]byte(parts[0] + "." + parts[1]))
+				if !strings.HasPrefix(got, tt.want) || parts[2] != base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) {
+					t.Errorf("Authorization = %q, want a token signed with the secret", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
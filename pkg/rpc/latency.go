@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MethodLatency summarizes the timed round trips of a JSON-RPC method
+type MethodLatency struct {
+	Calls  int           `json:"calls" yaml:"calls"`
+	Errors int           `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Mean   time.Duration `json:"mean" yaml:"mean"`
+	Max    time.Duration `json:"max" yaml:"max"`
+}
+
+// latencyKey is the context key of the latency recorder
+type latencyKey struct{}
+
+// Latencies records the duration of the calls to an endpoint by method
+type Latencies struct {
+	mu      sync.Mutex
+	url     string
+	methods map[string]*methodTimes
+}
+
+type methodTimes struct {
+	calls, errors int
+	total, max    time.Duration
+}
+
+// WithLatencies returns a context on which every call to rpcURL is timed into the returned recorder.
+// Batches are recorded under their batch(...) label, prefetched answers aren't round trips and aren't recorded.
+func WithLatencies(ctx context.Context, rpcURL string) (context.Context, *Latencies) {
+	l := &Latencies{url: rpcURL, methods: make(map[string]*methodTimes)}
+	return context.WithValue(ctx, latencyKey{}, l), l
+}
+
+// Methods returns the latency summary of every called method
+func (l *Latencies) Methods() map[string]MethodLatency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.methods) == 0 {
+		return nil
+	}
+	methods := make(map[string]MethodLatency, len(l.methods))
+	for method, times := range l.methods {
+		methods[method] = MethodLatency{
+			Calls:  times.calls,
+			Errors: times.errors,
+			Mean:   times.total / time.Duration(times.calls),
+			Max:    times.max,
+		}
+	}
+	return methods
+}
+
+// recordLatency times a call of label to rpcURL into the recorder of ctx, if any
+func recordLatency(ctx context.Context, rpcURL string, label string, duration time.Duration, failed bool) {
+	l, ok := ctx.Value(latencyKey{}).(*Latencies)
+	if !ok || l.url != rpcURL {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	times, ok := l.methods[label]
+	if !ok {
+		times = &methodTimes{}
+		l.methods[label] = times
+	}
+	times.calls++
+	if failed {
+		times.errors++
+	}
+	times.total += duration
+	times.max = max(times.max, duration)
+}
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// Options configure the JSON-RPC and HTTP API calls made with a context from WithOptions
+type Options struct {
+	// Timeout bounds every call including reading its response, so a hung node can't block its check forever.
+	// DefaultTimeout if 0.
+	Timeout time.Duration
+	// Retry is the retry policy of every call, DefaultRetry if nil
+	Retry *config.Retry
+	// Verbose logs the calls to Log: 1 logs each call with its status and duration and every retry,
+	// 2 also dumps the request and the raw response body
+	Verbose int
+	// Log receives the verbose logs, they are dropped if nil
+	Log io.Writer
+}
+
+// optionsKey is the context key of the call options
+type optionsKey struct{}
+
+// WithOptions returns a context whose calls follow opts, calls on other contexts use the defaults
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// OptionsFrom returns the call options of ctx with the defaults filled in
+func OptionsFrom(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options)
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Retry == nil {
+		retry := DefaultRetry
+		opts.Retry = &retry
+	}
+	if opts.Log == nil {
+		opts.Verbose = 0
+	}
+	return opts
+}
@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// DefaultRetry retries transient failures twice, after about 500ms and 1s
+var DefaultRetry = config.Retry{Count: 2, Backoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second, Jitter: 0.2}
+
+// errorBodyPeek bounds the part of a 5xx answer read to tell a JSON-RPC error from a failing server
+const errorBodyPeek = 64 << 10
+
+// noRetryKey marks the context of requests sent once
+type noRetryKey struct{}
+
+// WithoutRetry returns a context whose requests are never retried, for calls that aren't idempotent
+// like eth_sendRawTransaction or notifications
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// Do sends req with the shared Client, or the client of its host configured by ConfigureHost, every attempt bounded by the timeout
+// of the options of its context, retrying transport errors, timeouts and 429 or 5xx answers without a JSON-RPC error
+// according to their retry policy, unless the context of req
+// comes from WithoutRetry. A Retry-After header replaces the backoff, the answer is returned as is when it
+// asks for more than the maximum backoff or once the retries are exhausted.
+// A request body is replayed through GetBody, which http.NewRequest sets for in-memory bodies.
+func Do(req *http.Request) (*http.Response, error) {
+	opts := OptionsFrom(req.Context())
+	retry := *opts.Retry
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), opts.Timeout)
+		resp, err := clientFor(req).Do(req.WithContext(ctx))
+		if err != nil {
+			cancel()
+		} else {
+			resp.Body = cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		}
+		reason := transientFailure(req.Context(), resp, err)
+		if reason == "" || attempt >= retry.Count || req.Context().Value(noRetryKey{}) != nil {
+			return resp, err
+		}
+		delay := backoff(retry, attempt)
+		if wait := retryAfter(resp); wait > 0 {
+			// Rather fail over than wait longer than any backoff for a rate-limited provider
+			if wait > maxBackoff(retry) {
+				return resp, err
+			}
+			delay = wait
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if opts.Verbose > 0 {
+			// The query is left out, scanner APIs take their key there
+			fmt.Fprintf(opts.Log, "Retrying %s %s://%s%s in %s (retry %d of %d): %s\n", req.Method, req.URL.Scheme, req.URL.Host, req.URL.Path,
+				delay.Round(time.Millisecond), attempt+1, retry.Count, reason)
+		}
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// transientFailure returns why a call is worth retrying, empty when it succeeded or failed for good
+func transientFailure(ctx context.Context, resp *http.Response, err error) string {
+	if err != nil {
+		// The caller gave up on the call
+		if ctx.Err() != nil {
+			return ""
+		}
+		return err.Error()
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode >= 500 && !rpcErrorAnswer(resp)) {
+		return resp.Status
+	}
+	return ""
+}
+
+// rpcErrorAnswer reports whether resp carries a JSON-RPC error, which Bitcoin Core answers with status 500:
+// the node handled the call and would fail it again. The peeked body is put back for the caller.
+func rpcErrorAnswer(resp *http.Response) bool {
+	peek, err := io.ReadAll(io.LimitReader(resp.Body, errorBodyPeek))
+	resp.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(peek), resp.Body), Closer: resp.Body}
+	if err != nil {
+		return false
+	}
+	type answer struct {
+		Error json.RawMessage `json:"error"`
+	}
+	var single answer
+	if json.Unmarshal(peek, &single) == nil {
+		return len(single.Error) > 0 && string(single.Error) != "null"
+	}
+	var batch []answer
+	if json.Unmarshal(peek, &batch) == nil {
+		for _, item := range batch {
+			if len(item.Error) > 0 && string(item.Error) != "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// peekedBody reads the peeked start of a body before its rest
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// backoff returns the delay of retry before the retry following attempt
+func backoff(retry config.Retry, attempt int) time.Duration {
+	delay := retry.Backoff
+	if delay <= 0 {
+		delay = DefaultRetry.Backoff
+	}
+	maxDelay := maxBackoff(retry)
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if retry.Jitter > 0 {
+		delay = time.Duration(float64(delay) * (1 + retry.Jitter*(2*rand.Float64()-1)))
+	}
+	return delay
+}
+
+// maxBackoff returns the longest delay of retry before a retry
+func maxBackoff(retry config.Retry) time.Duration {
+	if retry.MaxBackoff <= 0 {
+		return DefaultRetry.MaxBackoff
+	}
+	return retry.MaxBackoff
+}
+
+// retryAfter returns the delay asked by the Retry-After header of resp, 0 if none
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		retry   config.Retry
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first retry", retry: config.Retry{Backoff: time.Second, MaxBackoff: time.Minute}, attempt: 0, want: time.Second},
+		{name: "doubles", retry: config.Retry{Backoff: time.Second, MaxBackoff: time.Minute}, attempt: 3, want: 8 * time.Second},
+		{name: "capped", retry: config.Retry{Backoff: time.Second, MaxBackoff: 5 * time.Second}, attempt: 3, want: 5 * time.Second},
+		{name: "default backoff", retry: config.Retry{MaxBackoff: time.Minute}, attempt: 1, want: 2 * DefaultRetry.Backoff},
+		{name: "default max backoff", retry: config.Retry{Backoff: time.Second}, attempt: 10, want: DefaultRetry.MaxBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backoff(tt.retry, tt.attempt); got != tt.want {
+				t.Errorf("backoff(%+v, %d) = %s, want %s", tt.retry, tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	retry := config.Retry{Backoff: time.Second, MaxBackoff: time.Minute, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if got := backoff(retry, 1); got < 1600*time.Millisecond || got > 2400*time.Millisecond {
+			t.Fatalf("backoff with 20%% jitter = %s, want 2s ± 400ms", got)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{name: "none", header: "", want: 0},
+		{name: "seconds", header: "3", want: 3 * time.Second},
+		{name: "invalid", header: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			if got := retryAfter(resp); got != tt.want {
+				t.Errorf("retryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+			}
+		})
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}}
+	if got := retryAfter(resp); got < 58*time.Second || got > time.Minute {
+		t.Errorf("retryAfter of a date a minute ahead = %s", got)
+	}
+	if got := retryAfter(nil); got != 0 {
+		t.Errorf("retryAfter(nil) = %s, want 0", got)
+	}
+}
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name string
+		// answers are the status codes of the attempts, the last one repeats
+		answers    []int
+		retryAfter string
+		body       string
+		noRetry    bool
+		wantStatus int
+		wantCalls  int32
+	}{
+		{name: "success", answers: []int{200}, wantStatus: 200, wantCalls: 1},
+		{name: "retries 5xx", answers: []int{502, 503, 200}, wantStatus: 200, wantCalls: 3},
+		{name: "retries 429", answers: []int{429, 200}, wantStatus: 200, wantCalls: 2},
+		{name: "gives up", answers: []int{500}, wantStatus: 500, wantCalls: 3},
+		{name: "no retry of 4xx", answers: []int{404}, wantStatus: 404, wantCalls: 1},
+		{name: "no retry of JSON-RPC errors", answers: []int{500}, body: `{"jsonrpc":"2.0","id":1,"error":{"code":-1,"message":"bad"}}`, wantStatus: 500, wantCalls: 1},
+		{name: "without retry", answers: []int{503, 200}, noRetry: true, wantStatus: 503, wantCalls: 1},
+		{name: "short Retry-After", answers: []int{429, 200}, retryAfter: "0", wantStatus: 200, wantCalls: 2},
+		{name: "Retry-After past max backoff", answers: []int{429, 200}, retryAfter: "120", wantStatus: 429, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := int(calls.Add(1)) - 1
+				status := tt.answers[min(call, len(tt.answers)-1)]
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			ctx := WithOptions(context.Background(), Options{
+				Timeout: time.Second,
+				Retry:   &config.Retry{Count: 2, Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+			})
+			if tt.noRetry {
+				ctx = WithoutRetry(ctx)
+			}
+			req, err := http.NewRequestWithContext(ctx, "POST", srv.URL, strings.NewReader(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := Do(req)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ctx = WithOptions(ctx, Options{Retry: &config.Retry{Count: 5, Backoff: time.Minute, MaxBackoff: time.Minute}})
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := Do(req); err != context.DeadlineExceeded {
+		t.Errorf("Do = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do waited %s for its backoff past the deadline", elapsed)
+	}
+}
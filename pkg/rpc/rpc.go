@@ -0,0 +1,221 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morzhanov/nodestat/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/morzhanov/nodestat/pkg/rpc")
+
+// DefaultTimeout is the default deadline of a single call
+const DefaultTimeout = 10 * time.Second
+
+// ErrRateLimited is returned when an endpoint answers that its rate limit is exceeded
+var ErrRateLimited = errors.New("rate limited")
+
+// MethodNotFound is the JSON-RPC error code of methods the node doesn't expose, e.g. a disabled namespace
+const MethodNotFound = -32601
+
+// RPCError is the error object of a JSON-RPC response
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// response is a JSON-RPC response, Error is nil on success
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// ParseHex parses a 0x-prefixed quantity
+func ParseHex(val string) (int64, error) {
+	if !strings.HasPrefix(val, "0x") {
+		return 0, fmt.Errorf("invalid hex quantity %q", val)
+	}
+	return strconv.ParseInt(val[2:], 16, 64)
+}
+
+func Call(ctx context.Context, node config.Node, localPort int, method string, params ...interface{}) (interface{}, error) {
+	raw, err := CallRaw(ctx, node, localPort, method, params...)
+	if err != nil {
+		return "", err
+	}
+
+	var result interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return "", err
+		}
+	}
+	return result, nil
+}
+
+// CallRaw performs a JSON-RPC call and returns the undecoded result field
+func CallRaw(ctx context.Context, node config.Node, localPort int, method string, params ...interface{}) (json.RawMessage, error) {
+	return CallAuth(ctx, NodeURL(node, localPort), node.Auth, method, params...)
+}
+
+// NodeURL returns the node's direct URL if configured, otherwise its forwarded local endpoint,
+// over https when the node has TLS settings and over WebSocket at its WSPath when set
+func NodeURL(node config.Node, localPort int) string {
+	if node.URL != "" {
+		return node.URL
+	}
+	scheme, path := "http", node.RPCPath
+	if node.WSPath != "" {
+		scheme, path = "ws", node.WSPath
+	}
+	if node.TLS != nil {
+		scheme += "s"
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%d%s", scheme, localPort, path)
+}
+
+// CallURL performs a JSON-RPC call against an arbitrary endpoint
+func CallURL(ctx context.Context, rpcURL string, method string, params ...interface{}) (json.RawMessage, error) {
+	return CallAuth(ctx, rpcURL, nil, method, params...)
+}
+
+// CallAuth performs a JSON-RPC call with optional basic auth credentials, traced as a span of the method.
+// An error answer of the node is returned as an *RPCError.
+// A call without params answered by a Prefetch batch on ctx returns the prefetched answer.
+func CallAuth(ctx context.Context, rpcURL string, auth *config.NodeAuth, method string, params ...interface{}) (json.RawMessage, error) {
+	if len(params) == 0 {
+		if result, ok := takePrefetched(ctx, rpcURL, method); ok {
+			return result, nil
+		}
+	}
+
+	ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rpc.system", "jsonrpc"), attribute.String("rpc.method", method)))
+	defer span.End()
+
+	result, err := callAuth(ctx, rpcURL, auth, method, params...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
+
+func callAuth(ctx context.Context, rpcURL string, auth *config.NodeAuth, method string, params ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := post(ctx, rpcURL, auth, method, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid JSON-RPC response: %v", err)
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Result, nil
+}
+
+// post sends a JSON-RPC payload and returns the raw response body, label names the call in the logs.
+// ws:// and wss:// endpoints are called over a WebSocket connection.
+func post(ctx context.Context, rpcURL string, auth *config.NodeAuth, label string, payload []byte) ([]byte, error) {
+	if IsWebSocket(rpcURL) {
+		return postWebSocket(ctx, rpcURL, auth, label, payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := setAuth(req, auth); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := Do(req)
+	if err != nil {
+		logCall(ctx, label, rpcURL, payload, err.Error(), nil, time.Since(start))
+		recordLatency(ctx, rpcURL, label, time.Since(start), true)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		logCall(ctx, label, rpcURL, payload, err.Error(), nil, time.Since(start))
+		recordLatency(ctx, rpcURL, label, time.Since(start), true)
+		return nil, err
+	}
+	logCall(ctx, label, rpcURL, payload, resp.Status, body, time.Since(start))
+	recordLatency(ctx, rpcURL, label, time.Since(start), resp.StatusCode >= http.StatusBadRequest)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w (%s)", ErrRateLimited, resp.Status)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("authentication failed (%s)", resp.Status)
+	}
+	return body, nil
+}
+
+// setAuth adds the credentials of auth, if any, to req
+func setAuth(req *http.Request, auth *config.NodeAuth) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case config.AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.BearerToken())
+	case config.AuthJWT:
+		secret, err := config.ReadJWTSecret(auth.JWTSecret)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+jwtToken(secret, time.Now()))
+	default:
+		req.SetBasicAuth(auth.User(), auth.Pass())
+	}
+	return nil
+}
+
+// logCall logs a JSON-RPC call according to the options of ctx, in a single write so concurrent checks don't interleave
+func logCall(ctx context.Context, method string, rpcURL string, payload []byte, status string, body []byte, duration time.Duration) {
+	opts := OptionsFrom(ctx)
+	if opts.Verbose == 0 {
+		return
+	}
+	msg := fmt.Sprintf("RPC %s %s: %s in %s\n", method, rpcURL, status, duration.Round(time.Millisecond))
+	if opts.Verbose > 1 {
+		msg += fmt.Sprintf("--> %s\n<-- %s\n", payload, bytes.TrimSpace(body))
+	}
+	fmt.Fprint(opts.Log, msg)
+}
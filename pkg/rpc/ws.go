@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// wsConn is a WebSocket connection to a JSON-RPC endpoint, carrying one call at a time
+type wsConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// wsConns are the connections kept open to WebSocket endpoints, by URL
+var (
+	wsMu    sync.Mutex
+	wsConns = make(map[string]*wsConn)
+)
+
+// IsWebSocket reports whether rpcURL is a ws:// or wss:// endpoint
+func IsWebSocket(rpcURL string) bool {
+	return strings.HasPrefix(rpcURL, "ws://") || strings.HasPrefix(rpcURL, "wss://")
+}
+
+// CloseWebSocket closes the connection kept open to rpcURL, if any
+func CloseWebSocket(rpcURL string) {
+	wsMu.Lock()
+	c, ok := wsConns[rpcURL]
+	delete(wsConns, rpcURL)
+	wsMu.Unlock()
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.conn.Close()
+}
+
+// postWebSocket sends a JSON-RPC payload over the connection to rpcURL and returns the answer, logged and
+// recorded like the calls over HTTP. Calls aren't retried, a failed call drops the connection and the next one redials.
+func postWebSocket(ctx context.Context, rpcURL string, auth *config.NodeAuth, label string, payload []byte) ([]byte, error) {
+	start := time.Now()
+	body, err := sendWebSocket(ctx, rpcURL, auth, payload)
+	if err != nil {
+		logCall(ctx, label, rpcURL, payload, err.Error(), nil, time.Since(start))
+		recordLatency(ctx, rpcURL, label, time.Since(start), true)
+		return nil, err
+	}
+	logCall(ctx, label, rpcURL, payload, "answered", body, time.Since(start))
+	recordLatency(ctx, rpcURL, label, time.Since(start), false)
+	return body, nil
+}
+
+func sendWebSocket(ctx context.Context, rpcURL string, auth *config.NodeAuth, payload []byte) ([]byte, error) {
+	c, err := wsConnection(ctx, rpcURL, auth)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := time.Now().Add(OptionsFrom(ctx).Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	c.conn.SetWriteDeadline(deadline)
+	c.conn.SetReadDeadline(deadline)
+	// Unblock the read when ctx is canceled before the answer
+	stop := context.AfterFunc(ctx, func() { c.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+		dropWebSocket(rpcURL, c)
+		return nil, err
+	}
+	// The calls are sent one at a time without subscriptions, so the next message is the answer
+	_, body, err := c.conn.ReadMessage()
+	if err != nil {
+		dropWebSocket(rpcURL, c)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// wsConnection returns the open connection to rpcURL, dialing it when there is none
+func wsConnection(ctx context.Context, rpcURL string, auth *config.NodeAuth) (*wsConn, error) {
+	wsMu.Lock()
+	c, ok := wsConns[rpcURL]
+	wsMu.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	// Dial without holding the lock, a hung endpoint must not delay the calls to the others
+	conn, err := DialWebSocket(ctx, rpcURL, auth)
+	if err != nil {
+		return nil, err
+	}
+	wsMu.Lock()
+	defer wsMu.Unlock()
+	if c, ok := wsConns[rpcURL]; ok {
+		// A concurrent call dialed first
+		conn.Close()
+		return c, nil
+	}
+	c = &wsConn{conn: conn}
+	wsConns[rpcURL] = c
+	return c, nil
+}
+
+// dropWebSocket closes c and forgets it unless it was already replaced
+func dropWebSocket(rpcURL string, c *wsConn) {
+	c.conn.Close()
+	wsMu.Lock()
+	defer wsMu.Unlock()
+	if wsConns[rpcURL] == c {
+		delete(wsConns, rpcURL)
+	}
+}
+
+// DialWebSocket opens a connection to rpcURL with the credentials of auth in the handshake,
+// through the TLS and proxy settings of its host
+func DialWebSocket(ctx context.Context, rpcURL string, auth *config.NodeAuth) (*websocket.Conn, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rpcURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := setAuth(req, auth); err != nil {
+		return nil, err
+	}
+	dialer := *websocket.DefaultDialer
+	if transport, ok := clientFor(req).Transport.(*http.Transport); ok {
+		dialer.Proxy = transport.Proxy
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, OptionsFrom(ctx).Timeout)
+	defer cancel()
+	conn, resp, err := dialer.DialContext(ctx, rpcURL, req.Header)
+	if err != nil && resp != nil {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+			return nil, fmt.Errorf("%w (%s)", ErrRateLimited, resp.Status)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return nil, fmt.Errorf("authentication failed (%s)", resp.Status)
+		}
+		return nil, fmt.Errorf("%v (%s)", err, resp.Status)
+	}
+	return conn, err
+}
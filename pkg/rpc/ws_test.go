@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/morzhanov/nodestat/pkg/config"
+)
+
+// wsServer serves JSON-RPC over WebSocket with answer, counting the connections
+func wsServer(t *testing.T, answer func(method string) string) (string, *atomic.Int32) {
+	var dials atomic.Int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		dials.Add(1)
+		for {
+			var req struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			result := answer(req.Method)
+			if result == "" {
+				// Hang without answering
+				continue
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":`+string(req.ID)+`,"result":`+result+`}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), &dials
+}
+
+func TestWebSocketCalls(t *testing.T) {
+	rpcURL, dials := wsServer(t, func(method string) string {
+		switch method {
+		case "eth_blockNumber":
+			return `"0x64"`
+		case "net_peerCount":
+			return `"0x19"`
+		}
+		return ""
+	})
+	defer CloseWebSocket(rpcURL)
+	auth := &config.NodeAuth{Type: config.AuthBearer, Token: "token"}
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{method: "eth_blockNumber", want: `"0x64"`},
+		{method: "net_peerCount", want: `"0x19"`},
+		{method: "eth_blockNumber", want: `"0x64"`},
+	}
+	for _, tt := range tests {
+		got, err := CallAuth(context.Background(), rpcURL, auth, tt.method)
+		if err != nil {
+			t.Fatalf("CallAuth(%s): %v", tt.method, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("CallAuth(%s) = %s, want %s", tt.method, got, tt.want)
+		}
+	}
+	// The calls share one connection
+	if got := dials.Load(); got != 1 {
+		t.Errorf("%d connections for %d calls, want 1", got, len(tests))
+	}
+
+	// A closed connection is dialed again by the next call
+	CloseWebSocket(rpcURL)
+	if _, err := CallAuth(context.Background(), rpcURL, auth, "eth_blockNumber"); err != nil {
+		t.Fatalf("CallAuth after CloseWebSocket: %v", err)
+	}
+	if got := dials.Load(); got != 2 {
+		t.Errorf("%d connections after CloseWebSocket, want 2", got)
+	}
+}
+
+func TestWebSocketErrors(t *testing.T) {
+	rpcURL, _ := wsServer(t, func(method string) string { return "" })
+	defer CloseWebSocket(rpcURL)
+
+	tests := []struct {
+		name    string
+		auth    *config.NodeAuth
+		timeout time.Duration
+		// wantMsg is a part of the expected error, any error within the timeout when empty
+		wantMsg string
+	}{
+		{name: "rejected handshake", auth: &config.NodeAuth{Type: config.AuthBearer, Token: "wrong"}, timeout: time.Second, wantMsg: "authentication failed"},
+		{name: "unanswered call", auth: &config.NodeAuth{Type: config.AuthBearer, Token: "token"}, timeout: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			_, err := CallAuth(ctx, rpcURL, tt.auth, "eth_blockNumber")
+			if err == nil {
+				t.Fatal("CallAuth succeeded, want an error")
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("CallAuth = %v, want %q in it", err, tt.wantMsg)
+			}
+			if elapsed := time.Since(start); elapsed > tt.timeout+time.Second {
+				t.Errorf("CallAuth returned after %s, past its %s deadline", elapsed, tt.timeout)
+			}
+		})
+	}
+}